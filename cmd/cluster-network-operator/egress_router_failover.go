@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+	"github.com/openshift/library-go/pkg/config/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+// newEgressRouterFailoverCommand returns a Command that runs in each replica of an
+// HA egress router. The replicas elect a leader with a Lease, and only the leader
+// answers ARP for the egress address of the router and carries the leader label.
+func newEgressRouterFailoverCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "egress-router-failover",
+		Short: "Fail the egress address of an HA egress router over between its replicas",
+	}
+
+	var kubeconfig string
+	var leaseName string
+	var iface string
+	var address string
+
+	flags := cmd.Flags()
+	flags.StringVar(&leaseName, "lease-name", "", "the name of the Lease the replicas elect their leader with")
+	flags.StringVar(&iface, "interface", "net1", "the interface of the egress address")
+	flags.StringVar(&address, "address", "", "the egress address, in CIDR notation")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		podName := os.Getenv("POD_NAME")
+		namespace := os.Getenv("POD_NAMESPACE")
+		if leaseName == "" || address == "" || podName == "" || namespace == "" {
+			return fmt.Errorf("--lease-name, --address, POD_NAME and POD_NAMESPACE are required")
+		}
+		ip, _, err := net.ParseCIDR(address)
+		if err != nil {
+			return err
+		}
+		if kubeconfig == "" {
+			kubeconfig = os.Getenv("KUBECONFIG")
+		}
+		cfg, err := client.GetKubeConfigOrInClusterConfig(kubeconfig, nil)
+		if err != nil {
+			return err
+		}
+		clientSet, err := kubernetes.NewForConfig(cfg)
+		if err != nil {
+			return err
+		}
+
+		if err := network.CheckEgressRouterCapabilities(); err != nil {
+			return err
+		}
+
+		// Start as a standby replica, whatever this replica was before a restart
+		if err := network.ReleaseEgressAddress(iface); err != nil {
+			return err
+		}
+		if err := setEgressRouterLeaderLabel(context.Background(), clientSet, namespace, podName, false); err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer cancel()
+
+		lock := &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: leaseName},
+			Client:     clientSet.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: podName},
+		}
+		// The lease is released when the replica is stopped, so that another
+		// replica takes over right away when a node is drained.
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			ReleaseOnCancel: true,
+			LeaseDuration:   15 * time.Second,
+			RenewDeadline:   10 * time.Second,
+			RetryPeriod:     2 * time.Second,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					klog.Infof("Became the leader of %s/%s, claiming %s on %s", namespace, leaseName, ip, iface)
+					if err := network.ClaimEgressAddress(iface, ip); err != nil {
+						klog.Errorf("Could not claim the egress address: %v", err)
+						cancel()
+						return
+					}
+					if err := setEgressRouterLeaderLabel(ctx, clientSet, namespace, podName, true); err != nil {
+						klog.Errorf("Could not label the leader: %v", err)
+						cancel()
+					}
+				},
+				OnStoppedLeading: func() {
+					klog.Infof("Stopped leading %s/%s, releasing %s", namespace, leaseName, ip)
+					if err := network.ReleaseEgressAddress(iface); err != nil {
+						klog.Errorf("Could not release the egress address: %v", err)
+					}
+					if err := setEgressRouterLeaderLabel(context.Background(), clientSet, namespace, podName, false); err != nil {
+						klog.Errorf("Could not remove the leader label: %v", err)
+					}
+				},
+			},
+		})
+
+		// Losing the lease without being stopped restarts the container, which
+		// then competes for the lease again.
+		if ctx.Err() == nil {
+			return fmt.Errorf("lost the lease %s/%s", namespace, leaseName)
+		}
+		return nil
+	}
+
+	return cmd
+}
+
+// setEgressRouterLeaderLabel adds or removes the leader label of a replica
+func setEgressRouterLeaderLabel(ctx context.Context, clientSet kubernetes.Interface, namespace, podName string, leader bool) error {
+	value := "null"
+	if leader {
+		value = `"true"`
+	}
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%s}}}`, names.EgressRouterLeaderLabel, value)
+	_, err := clientSet.CoreV1().Pods(namespace).Patch(ctx, podName, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
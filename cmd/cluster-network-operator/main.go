@@ -57,6 +57,8 @@ func newNetworkOperatorCommand() *cobra.Command {
 	cmd.AddCommand(cmd2)
 
 	cmd.AddCommand(newMTUProberCommand())
+	cmd.AddCommand(newEgressRouterFailoverCommand())
+	cmd.AddCommand(newProxyDiagnosticsCommand())
 
 	return cmd
 }
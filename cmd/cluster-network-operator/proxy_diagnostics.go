@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	configv1 "github.com/openshift/api/config/v1"
+	configclient "github.com/openshift/client-go/config/clientset/versioned"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/util/proxyconfig"
+	"github.com/openshift/cluster-network-operator/pkg/util/validation"
+	"github.com/openshift/library-go/pkg/config/client"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// newProxyDiagnosticsCommand returns a Command that explains how the requests of
+// the cluster components to a destination go through the cluster proxy: whether
+// the merged noProxy list bypasses the proxy, which proxy is used otherwise, and
+// whether the proxy accepts the credentials of its URL.
+func newProxyDiagnosticsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "proxy-diagnostics",
+		Short: "Explain how the requests to a destination go through the cluster proxy",
+	}
+
+	var kubeconfig string
+	var destination string
+	var skipAuth bool
+	var output string
+
+	flags := cmd.Flags()
+	flags.StringVar(&kubeconfig, "kubeconfig", "", "the kubeconfig of the cluster, KUBECONFIG or the in-cluster config by default")
+	flags.StringVar(&destination, "destination", "", "the URL or host to diagnose, reached with https when it has no scheme")
+	flags.BoolVar(&skipAuth, "skip-auth", false, "do not send a request through the proxy to check its credentials")
+	flags.StringVarP(&output, "output", "o", "", "the output format, json or empty for text")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if destination == "" {
+			return fmt.Errorf("--destination is required")
+		}
+		if output != "" && output != "json" {
+			return fmt.Errorf("unsupported output format %q", output)
+		}
+		if kubeconfig == "" {
+			kubeconfig = os.Getenv("KUBECONFIG")
+		}
+		cfg, err := client.GetKubeConfigOrInClusterConfig(kubeconfig, nil)
+		if err != nil {
+			return err
+		}
+		clientSet, err := kubernetes.NewForConfig(cfg)
+		if err != nil {
+			return err
+		}
+		configClient, err := configclient.NewForConfig(cfg)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		proxy, err := configClient.ConfigV1().Proxies().Get(ctx, names.PROXY_CONFIG, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get proxy '%s': %v", names.PROXY_CONFIG, err)
+		}
+		noProxy, err := mergedNoProxy(ctx, clientSet, configClient, proxy)
+		if err != nil {
+			return err
+		}
+		tlsConfig, err := trustedCATLSConfig(ctx, clientSet)
+		if err != nil {
+			return err
+		}
+
+		diagnosis, err := proxyconfig.DiagnoseProxy(ctx, &proxy.Spec, noProxy, destination, !skipAuth, tlsConfig)
+		if err != nil {
+			return err
+		}
+		if output == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(diagnosis)
+		}
+		fmt.Print(diagnosis.String())
+		return nil
+	}
+
+	return cmd
+}
+
+// mergedNoProxy returns the noProxy list of the cluster. Once the proxy
+// controller accepted the spec of the proxy, it is the one of the status, which
+// the components use. Otherwise it is computed from the spec the way the proxy
+// controller does. The computed list may differ from the one of the controller,
+// as the merge depends on the environment of the process, such as HYPERSHIFT.
+func mergedNoProxy(ctx context.Context, clientSet kubernetes.Interface, configClient configclient.Interface, proxy *configv1.Proxy) (string, error) {
+	sources, err := clientSet.CoreV1().ConfigMaps(names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS).Get(ctx, names.NO_PROXY_SOURCES_CONFIGMAP, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("failed to get configmap '%s/%s': %v", names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS, names.NO_PROXY_SOURCES_CONFIGMAP, err)
+	}
+	if err == nil {
+		explanation := &proxyconfig.NoProxyExplanation{}
+		if err := json.Unmarshal([]byte(sources.Data[names.NO_PROXY_SOURCES_CONFIGMAP_KEY]), explanation); err != nil {
+			return "", fmt.Errorf("failed to decode configmap '%s/%s': %v", names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS, names.NO_PROXY_SOURCES_CONFIGMAP, err)
+		}
+		if noProxy, ok := proxyconfig.AcceptedNoProxy(proxy, explanation); ok {
+			return noProxy, nil
+		}
+	}
+
+	if proxy.Spec.NoProxy == "*" {
+		return proxy.Spec.NoProxy, nil
+	}
+	if proxy.Spec.NoProxy == "" && proxy.Spec.HTTPProxy == "" && proxy.Spec.HTTPSProxy == "" {
+		return "", nil
+	}
+	infra, err := configClient.ConfigV1().Infrastructures().Get(ctx, names.INFRASTRUCTURE_CONFIG, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get infrastructure config '%s': %v", names.INFRASTRUCTURE_CONFIG, err)
+	}
+	network, err := configClient.ConfigV1().Networks().Get(ctx, names.CLUSTER_CONFIG, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get network config '%s': %v", names.CLUSTER_CONFIG, err)
+	}
+	cluster, err := clientSet.CoreV1().ConfigMaps("kube-system").Get(ctx, "cluster-config-v1", metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cluster = nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get configmap 'kube-system/cluster-config-v1': %v", err)
+	}
+	noProxy, err := proxyconfig.MergeUserSystemNoProxy(proxy, infra, network, cluster)
+	if err != nil {
+		return "", fmt.Errorf("failed to merge user/system noProxy settings: %v", err)
+	}
+	return noProxy, nil
+}
+
+// trustedCATLSConfig returns a TLS config that trusts the trusted CA bundle of
+// the cluster, or nil to trust the system CAs when the bundle does not exist.
+func trustedCATLSConfig(ctx context.Context, clientSet kubernetes.Interface) (*tls.Config, error) {
+	trustBundle, err := clientSet.CoreV1().ConfigMaps(names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS).Get(ctx, names.TRUSTED_CA_BUNDLE_CONFIGMAP, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trusted CA bundle configmap: %v", err)
+	}
+	certs, _, err := validation.TrustBundleConfigMap(trustBundle, names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate trusted CA bundle configmap: %v", err)
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
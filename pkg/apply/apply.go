@@ -36,6 +36,18 @@ func GetClusterName(obj Object) string {
 // For more information, see https://kubernetes.io/docs/reference/using-api/server-side-apply/
 // The subcontroller, if set, is used to assign field ownership.
 func ApplyObject(ctx context.Context, client cnoclient.Client, obj Object, subcontroller string, subresources ...string) error {
+	return applyObject(ctx, client, obj, subcontroller, false, subresources...)
+}
+
+// ValidateObject submits the same server-side apply patch as ApplyObject, but as a
+// dry-run with strict field validation. The apiserver checks the object against
+// its live OpenAPI schema, so unknown or duplicate fields, bad types and admission
+// failures are reported without anything being persisted.
+func ValidateObject(ctx context.Context, client cnoclient.Client, obj Object, subcontroller string, subresources ...string) error {
+	return applyObject(ctx, client, obj, subcontroller, true, subresources...)
+}
+
+func applyObject(ctx context.Context, client cnoclient.Client, obj Object, subcontroller string, dryRun bool, subresources ...string) error {
 	name := obj.GetName()
 	namespace := obj.GetNamespace()
 	clusterClient := client.ClientFor(GetClusterName(obj))
@@ -58,7 +70,15 @@ func ApplyObject(ctx context.Context, client cnoclient.Client, obj Object, subco
 	obj.GetObjectKind().SetGroupVersionKind(gvk)
 	// used for logging and errors
 	objDesc := fmt.Sprintf("(%s) %s/%s", gvk.String(), namespace, name)
-	log.Printf("reconciling %s", objDesc)
+	if !dryRun {
+		log.Printf("reconciling %s", objDesc)
+	}
+
+	// Work on a copy when validating, so that the object handed to
+	// ApplyObject afterwards is untouched.
+	if dryRun {
+		obj = obj.DeepCopyObject().(Object)
+	}
 
 	// It isn't allowed to send ManagedFields in a Patch.
 	obj.SetManagedFields(nil)
@@ -79,7 +99,9 @@ func ApplyObject(ctx context.Context, client cnoclient.Client, obj Object, subco
 
 	// If create-wait is specified, ignore creating the object
 	if _, ok := obj.GetAnnotations()[names.CreateWaitAnnotation]; ok {
-		log.Printf("Object %s has create-wait annotation, skipping apply.", objDesc)
+		if !dryRun {
+			log.Printf("Object %s has create-wait annotation, skipping apply.", objDesc)
+		}
 		return nil
 	}
 
@@ -87,7 +109,9 @@ func ApplyObject(ctx context.Context, client cnoclient.Client, obj Object, subco
 	if _, ok := obj.GetAnnotations()[names.CreateOnlyAnnotation]; ok {
 		_, err := clusterClient.Dynamic().Resource(rm.Resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
 		if err == nil {
-			log.Printf("Object %s has create-only annotation and already exists, skipping apply.", objDesc)
+			if !dryRun {
+				log.Printf("Object %s has create-only annotation and already exists, skipping apply.", objDesc)
+			}
 			return nil
 		}
 		if !apierrors.IsNotFound(err) {
@@ -119,6 +143,10 @@ func ApplyObject(ctx context.Context, client cnoclient.Client, obj Object, subco
 		Force:        utilpointer.To(true),
 		FieldManager: fieldManager,
 	}
+	if dryRun {
+		patchOptions.DryRun = []string{metav1.DryRunAll}
+		patchOptions.FieldValidation = metav1.FieldValidationStrict
+	}
 	// Send the full object to be applied on the server side.
 	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
 	if err != nil {
@@ -128,7 +156,7 @@ func ApplyObject(ctx context.Context, client cnoclient.Client, obj Object, subco
 	// consider removing in OCP 4.18 when we know field manager 'cluster-network-operator' no longer possibly
 	// exists in any object from all upgrade paths
 	// Retrieve the current state of the resource
-	if isDepFieldManagerCleanupNeeded(subcontroller) {
+	if !dryRun && isDepFieldManagerCleanupNeeded(subcontroller) {
 		us, err := clusterClient.Dynamic().Resource(rm.Resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
 		if err != nil && !apierrors.IsNotFound(err) {
 			return fmt.Errorf("failed to get current state of %s: %w", objDesc, err)
@@ -153,8 +181,14 @@ func ApplyObject(ctx context.Context, client cnoclient.Client, obj Object, subco
 
 	_, err = clusterClient.Dynamic().Resource(rm.Resource).Namespace(namespace).Patch(ctx, name, types.ApplyPatchType, data, patchOptions, subresources...)
 	if err != nil {
+		if dryRun {
+			return fmt.Errorf("failed to validate %s: %w", objDesc, err)
+		}
 		return fmt.Errorf("failed to apply / update %s: %w", objDesc, err)
 	}
+	if dryRun {
+		return nil
+	}
 
 	log.Printf("Apply / Create of %s was successful", objDesc)
 	return nil
@@ -0,0 +1,58 @@
+package apply
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ValidationCache remembers the objects that passed ValidateObject, so that an
+// object rendered on every reconcile is only validated again once it changes,
+// rather than costing a dry-run request each time it is applied.
+type ValidationCache struct {
+	lock sync.Mutex
+	// validated holds the hash of the last valid content of each object
+	validated map[string][sha256.Size]byte
+	// validate is ValidateObject, except in tests
+	validate func(ctx context.Context, client cnoclient.Client, obj Object, subcontroller string, subresources ...string) error
+}
+
+// NewValidationCache returns an empty ValidationCache
+func NewValidationCache() *ValidationCache {
+	return &ValidationCache{
+		validated: map[string][sha256.Size]byte{},
+		validate:  ValidateObject,
+	}
+}
+
+// ValidateObject validates an object with ValidateObject, unless the same content
+// of the object was already found valid.
+func (c *ValidationCache) ValidateObject(ctx context.Context, client cnoclient.Client, obj Object, subcontroller string, subresources ...string) error {
+	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	if err != nil {
+		return fmt.Errorf("could not encode %s/%s for validation: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	key := fmt.Sprintf("%s|%s|%s/%s|%v", GetClusterName(obj), obj.GetObjectKind().GroupVersionKind(), obj.GetNamespace(), obj.GetName(), subresources)
+	hash := sha256.Sum256(data)
+
+	c.lock.Lock()
+	validated, ok := c.validated[key]
+	c.lock.Unlock()
+	if ok && validated == hash {
+		return nil
+	}
+
+	if err := c.validate(ctx, client, obj, subcontroller, subresources...); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.validated[key] = hash
+	c.lock.Unlock()
+	return nil
+}
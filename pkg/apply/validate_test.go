@@ -0,0 +1,46 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidationCache(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	validated := []string{}
+	var validateErr error
+	c := NewValidationCache()
+	c.validate = func(ctx context.Context, client cnoclient.Client, obj Object, subcontroller string, subresources ...string) error {
+		validated = append(validated, obj.GetName())
+		return validateErr
+	}
+	configMap := func(name, value string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Data:       map[string]string{"key": value},
+		}
+	}
+
+	// An object is validated once, until it changes
+	g.Expect(c.ValidateObject(context.TODO(), nil, configMap("a", "1"), "test")).To(Succeed())
+	g.Expect(c.ValidateObject(context.TODO(), nil, configMap("a", "1"), "test")).To(Succeed())
+	g.Expect(c.ValidateObject(context.TODO(), nil, configMap("b", "1"), "test")).To(Succeed())
+	g.Expect(validated).To(Equal([]string{"a", "b"}))
+	g.Expect(c.ValidateObject(context.TODO(), nil, configMap("a", "2"), "test")).To(Succeed())
+	g.Expect(validated).To(Equal([]string{"a", "b", "a"}))
+
+	// An invalid object is validated again
+	validateErr = fmt.Errorf("invalid")
+	g.Expect(c.ValidateObject(context.TODO(), nil, configMap("a", "3"), "test")).To(MatchError("invalid"))
+	g.Expect(c.ValidateObject(context.TODO(), nil, configMap("a", "3"), "test")).To(MatchError("invalid"))
+	g.Expect(validated).To(Equal([]string{"a", "b", "a", "a", "a"}))
+}
@@ -0,0 +1,186 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// waitForKinds are the kinds that can be referenced by the wait-for annotation,
+// along with the function that decides whether an object of that kind is ready.
+var waitForKinds = map[string]struct {
+	gvk   schema.GroupVersionKind
+	ready func(ctx context.Context, client cnoclient.ClusterClient, obj *uns.Unstructured) (bool, error)
+}{
+	"CustomResourceDefinition": {
+		gvk:   schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"},
+		ready: crdEstablished,
+	},
+	"DaemonSet": {
+		gvk:   schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+		ready: daemonSetRolledOut,
+	},
+	"Deployment": {
+		gvk:   schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		ready: deploymentAvailable,
+	},
+	"ValidatingWebhookConfiguration": {
+		gvk:   schema.GroupVersionKind{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "ValidatingWebhookConfiguration"},
+		ready: webhookServing,
+	},
+	"MutatingWebhookConfiguration": {
+		gvk:   schema.GroupVersionKind{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "MutatingWebhookConfiguration"},
+		ready: webhookServing,
+	},
+}
+
+// WaitForReady checks the object referenced by the wait-for annotation of obj.
+// It returns true if obj has no such annotation or the referenced object is ready,
+// and false, together with a human-readable reason, if applying obj and every
+// object after it has to be postponed.
+func WaitForReady(ctx context.Context, client cnoclient.Client, obj Object) (bool, string, error) {
+	anno, exists := obj.GetAnnotations()[names.WaitForAnnotation]
+	if !exists {
+		return true, "", nil
+	}
+
+	// The referenced object is in the cluster of obj, unless the reference
+	// starts with the name of another cluster
+	clusterName := GetClusterName(obj)
+	ref := anno
+	if i := strings.Index(anno, ":"); i >= 0 {
+		clusterName, ref = anno[:i], anno[i+1:]
+	}
+	parts := strings.Split(ref, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return false, "", fmt.Errorf("'%s' annotation is invalid, expected: [Cluster:]Kind/Namespace/Name, got: %s", names.WaitForAnnotation, anno)
+	}
+	kind, namespace, name := parts[0], parts[1], parts[2]
+
+	wk, ok := waitForKinds[kind]
+	if !ok {
+		return false, "", fmt.Errorf("'%s' annotation references unsupported kind %s", names.WaitForAnnotation, kind)
+	}
+
+	clusterClient := client.ClientFor(clusterName)
+	if clusterClient == nil {
+		return false, "", fmt.Errorf("object %s/%s references unknown cluster %s", obj.GetNamespace(), obj.GetName(), clusterName)
+	}
+
+	target := &uns.Unstructured{}
+	target.SetGroupVersionKind(wk.gvk)
+	err := clusterClient.CRClient().Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, target)
+	if apierrors.IsNotFound(err) {
+		return false, fmt.Sprintf("%s %s does not exist", kind, anno), nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("could not get %s %s/%s: %w", kind, namespace, name, err)
+	}
+
+	ready, err := wk.ready(ctx, clusterClient, target)
+	if err != nil {
+		return false, "", err
+	}
+	if !ready {
+		return false, fmt.Sprintf("%s %s is not ready", kind, anno), nil
+	}
+	return true, "", nil
+}
+
+// hasTrueCondition returns true if the status of obj has a condition of the
+// given type with status True.
+func hasTrueCondition(obj *uns.Unstructured, condType string) (bool, error) {
+	conditions, _, err := uns.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if cond["type"] == condType && cond["status"] == "True" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// crdEstablished returns true once the CRD has been accepted and its
+// resources are being served.
+func crdEstablished(_ context.Context, _ cnoclient.ClusterClient, crd *uns.Unstructured) (bool, error) {
+	return hasTrueCondition(crd, "Established")
+}
+
+// deploymentAvailable returns true once the Deployment has reached its
+// minimum number of available replicas.
+func deploymentAvailable(_ context.Context, _ cnoclient.ClusterClient, dep *uns.Unstructured) (bool, error) {
+	return hasTrueCondition(dep, "Available")
+}
+
+// daemonSetRolledOut returns true once the DaemonSet runs an available pod of
+// its current generation on every node it is scheduled to.
+func daemonSetRolledOut(_ context.Context, _ cnoclient.ClusterClient, obj *uns.Unstructured) (bool, error) {
+	ds := &appsv1.DaemonSet{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ds); err != nil {
+		return false, err
+	}
+	status := ds.Status
+	return ds.Generation <= status.ObservedGeneration &&
+		status.UpdatedNumberScheduled >= status.DesiredNumberScheduled &&
+		status.NumberUnavailable == 0 && status.NumberAvailable > 0, nil
+}
+
+// webhookServing returns true if every webhook backed by a Service has at least
+// one ready endpoint behind it. Webhooks that are reached through a URL are
+// not checked.
+func webhookServing(ctx context.Context, client cnoclient.ClusterClient, webhookConfig *uns.Unstructured) (bool, error) {
+	webhooks, _, err := uns.NestedSlice(webhookConfig.Object, "webhooks")
+	if err != nil {
+		return false, err
+	}
+	for _, w := range webhooks {
+		webhook, ok := w.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		namespace, _, _ := uns.NestedString(webhook, "clientConfig", "service", "namespace")
+		name, found, _ := uns.NestedString(webhook, "clientConfig", "service", "name")
+		if !found {
+			continue
+		}
+
+		endpoints := &corev1.Endpoints{}
+		err := client.CRClient().Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, endpoints)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not get endpoints %s/%s: %w", namespace, name, err)
+		}
+		if !hasReadyAddress(endpoints) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func hasReadyAddress(endpoints *corev1.Endpoints) bool {
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package apply
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func waitingObject(waitFor string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "waiting",
+			Namespace:   "ns",
+			Annotations: map[string]string{names.WaitForAnnotation: waitFor},
+		},
+	}
+}
+
+func TestWaitForReady(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	availableDeployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "available", Namespace: "ns"},
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue}},
+		},
+	}
+	progressingDeployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "progressing", Namespace: "ns"},
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse}},
+		},
+	}
+	webhook := func(name, service string) *admissionregistrationv1.ValidatingWebhookConfiguration {
+		return &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+				Name: "webhook.example.com",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{Namespace: "ns", Name: service},
+				},
+			}},
+		}
+	}
+	servingEndpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "serving", Namespace: "ns"},
+		Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}},
+	}
+	notServingEndpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "not-serving", Namespace: "ns"},
+		Subsets:    []corev1.EndpointSubset{{NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}},
+	}
+
+	rolledOutDaemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "rolled-out", Namespace: "ns", Generation: 2},
+		Status: appsv1.DaemonSetStatus{
+			ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3,
+		},
+	}
+	rollingDaemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "rolling", Namespace: "ns", Generation: 2},
+		Status: appsv1.DaemonSetStatus{
+			ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 2, NumberAvailable: 3,
+		},
+	}
+	client := fake.NewFakeClient(availableDeployment, progressingDeployment, rolledOutDaemonSet, rollingDaemonSet,
+		webhook("serving", "serving"), webhook("not-serving", "not-serving"),
+		servingEndpoints, notServingEndpoints)
+
+	// no annotation, nothing to wait for
+	ready, _, err := WaitForReady(context.TODO(), client, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "x", Namespace: "ns"}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeTrue())
+
+	ready, _, err = WaitForReady(context.TODO(), client, waitingObject("Deployment/ns/available"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeTrue())
+
+	ready, reason, err := WaitForReady(context.TODO(), client, waitingObject("Deployment/ns/progressing"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeFalse())
+	g.Expect(reason).To(ContainSubstring("is not ready"))
+
+	ready, reason, err = WaitForReady(context.TODO(), client, waitingObject("Deployment/ns/missing"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeFalse())
+	g.Expect(reason).To(ContainSubstring("does not exist"))
+
+	ready, _, err = WaitForReady(context.TODO(), client, waitingObject("DaemonSet/ns/rolled-out"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeTrue())
+
+	ready, _, err = WaitForReady(context.TODO(), client, waitingObject("DaemonSet/ns/rolling"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeFalse())
+
+	// the referenced object may be in another cluster
+	ready, _, err = WaitForReady(context.TODO(), client, waitingObject(names.DefaultClusterName+":DaemonSet/ns/rolled-out"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeTrue())
+
+	ready, _, err = WaitForReady(context.TODO(), client, waitingObject("ValidatingWebhookConfiguration//serving"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeTrue())
+
+	ready, _, err = WaitForReady(context.TODO(), client, waitingObject("ValidatingWebhookConfiguration//not-serving"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeFalse())
+
+	_, _, err = WaitForReady(context.TODO(), client, waitingObject("Deployment/available"))
+	g.Expect(err).To(HaveOccurred())
+
+	_, _, err = WaitForReady(context.TODO(), client, waitingObject("Pod/ns/available"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestCRDEstablished(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := &uns.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "NamesAccepted", "status": "True"},
+				map[string]interface{}{"type": "Established", "status": "False"},
+			},
+		},
+	}}
+	ready, err := crdEstablished(context.TODO(), nil, crd)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeFalse())
+
+	g.Expect(uns.SetNestedSlice(crd.Object, []interface{}{
+		map[string]interface{}{"type": "Established", "status": "True"},
+	}, "status", "conditions")).To(Succeed())
+	ready, err = crdEstablished(context.TODO(), nil, crd)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ready).To(BeTrue())
+}
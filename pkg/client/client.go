@@ -28,6 +28,7 @@ import (
 	machineapi "github.com/openshift/api/machine/v1beta1"
 	op_netopv1 "github.com/openshift/api/networkoperator/v1"
 	operv1 "github.com/openshift/api/operator/v1"
+	operatorcontrolplanev1alpha1 "github.com/openshift/api/operatorcontrolplane/v1alpha1"
 	netopv1 "github.com/openshift/cluster-network-operator/pkg/apis/network/v1"
 	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
 
@@ -45,6 +46,7 @@ func init() {
 	utilruntime.Must(machineapi.AddToScheme(scheme.Scheme))
 	utilruntime.Must(op_netopv1.Install(scheme.Scheme))
 	utilruntime.Must(mcfgv1.Install(scheme.Scheme))
+	utilruntime.Must(operatorcontrolplanev1alpha1.Install(scheme.Scheme))
 }
 
 // OperatorClusterClient is a bag of holding for object clients & informers.
@@ -18,6 +18,7 @@ import (
 	"k8s.io/klog/v2"
 
 	configv1 "github.com/openshift/api/config/v1"
+	netopv1 "github.com/openshift/api/networkoperator/v1"
 	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
 	"github.com/openshift/cluster-network-operator/pkg/names"
 
@@ -100,7 +101,7 @@ func NewFakeClient(objs ...crclient.Object) cnoclient.Client {
 	fc := FakeClusterClient{
 		kClient:   faketyped.NewSimpleClientset(ooTyped...),
 		dynclient: fakedynamic.NewSimpleDynamicClient(scheme.Scheme, oo...),
-		crclient:  crfake.NewClientBuilder().WithStatusSubresource(co).WithObjects(objs...).Build(),
+		crclient:  crfake.NewClientBuilder().WithStatusSubresource(co, &configv1.Network{}, &netopv1.EgressRouter{}).WithObjects(objs...).Build(),
 	}
 
 	return &FakeClient{
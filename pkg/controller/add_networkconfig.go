@@ -5,13 +5,16 @@ import (
 	"github.com/openshift/cluster-network-operator/pkg/controller/clusterconfig"
 	configmapcainjector "github.com/openshift/cluster-network-operator/pkg/controller/configmap_ca_injector"
 	"github.com/openshift/cluster-network-operator/pkg/controller/dashboards"
+	"github.com/openshift/cluster-network-operator/pkg/controller/dhcp"
 	"github.com/openshift/cluster-network-operator/pkg/controller/egress_router"
 	"github.com/openshift/cluster-network-operator/pkg/controller/infrastructureconfig"
 	"github.com/openshift/cluster-network-operator/pkg/controller/ingressconfig"
+	"github.com/openshift/cluster-network-operator/pkg/controller/multinetworkpolicy"
 	"github.com/openshift/cluster-network-operator/pkg/controller/operconfig"
 	"github.com/openshift/cluster-network-operator/pkg/controller/pki"
 	"github.com/openshift/cluster-network-operator/pkg/controller/proxyconfig"
 	signer "github.com/openshift/cluster-network-operator/pkg/controller/signer"
+	"github.com/openshift/cluster-network-operator/pkg/controller/whereabouts"
 )
 
 func init() {
@@ -21,6 +24,7 @@ func init() {
 		egress_router.Add,
 		proxyconfig.Add,
 		operconfig.Add,
+		operconfig.AddMigrationReadiness,
 		clusterconfig.Add,
 		configmapcainjector.Add,
 		signer.Add,
@@ -28,5 +32,8 @@ func init() {
 		infrastructureconfig.Add,
 		allowlist.Add,
 		dashboards.Add,
+		whereabouts.Add,
+		dhcp.Add,
+		multinetworkpolicy.Add,
 	)
 }
@@ -21,9 +21,8 @@ import (
 
 func (r *ReconcileClusterConfig) processNetworkTypeLiveMigration(ctx context.Context, request reconcile.Request, clusterConfig *configv1.Network, operConfig *operv1.Network) error {
 	klog.Infof("process network type live migration to the target CNI: %s", clusterConfig.Spec.NetworkType)
-	var err error
 	currentOperConfig := &operv1.Network{}
-	err = r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, currentOperConfig)
+	err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, currentOperConfig)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			return nil
@@ -33,14 +32,62 @@ func (r *ReconcileClusterConfig) processNetworkTypeLiveMigration(ctx context.Con
 	// In live migration, preserve the network type change, we want to switch the DefaultNetwork.Type of network.operator when the prerequisite steps are completed.
 	operConfig.Spec.DefaultNetwork.Type = currentOperConfig.Spec.DefaultNetwork.Type
 
-	if meta.IsStatusConditionPresentAndEqual(clusterConfig.Status.Conditions, names.NetworkTypeMigrationInProgress, metav1.ConditionTrue) {
-		if v1helpers.IsOperatorConditionTrue(operConfig.Status.Conditions, operv1.OperatorStatusTypeProgressing) {
-			// Not update network.operator if the operator is processing
-			return nil
+	state, err := r.readMigrationState(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := clusterConfig.Annotations[names.NetworkTypeMigrationAbortAnnotation]; ok {
+		if err := r.abortNetworkTypeMigration(ctx, clusterConfig, state); err != nil {
+			return err
+		}
+	}
+
+	if !meta.IsStatusConditionPresentAndEqual(clusterConfig.Status.Conditions, names.NetworkTypeMigrationInProgress, metav1.ConditionTrue) {
+		if state != nil && state.CompletedAt == nil && meta.IsStatusConditionFalse(clusterConfig.Status.Conditions, names.NetworkTypeMigrationInProgress) {
+			state.complete(metav1.Now())
+			klog.Infof("network type live migration from %s to %s is completed", state.From, clusterConfig.Spec.NetworkType)
+			if err := r.writeMigrationState(ctx, state); err != nil {
+				return err
+			}
+			return r.setMigrationHeldCondition(ctx, nil)
 		}
-		operConfig.Spec.Migration = currentOperConfig.Spec.Migration
-		return r.prepareOperatorConfigForNetworkTypeMigration(ctx, clusterConfig, operConfig)
+		return nil
+	}
+	if v1helpers.IsOperatorConditionTrue(operConfig.Status.Conditions, operv1.OperatorStatusTypeProgressing) {
+		// Not update network.operator if the operator is processing
+		return nil
+	}
+	operConfig.Spec.Migration = currentOperConfig.Spec.Migration
+
+	// Prepare a copy, so that the operator config is only changed once the step is checkpointed
+	prepared := operConfig.DeepCopy()
+	step, err := r.prepareOperatorConfigForNetworkTypeMigration(ctx, clusterConfig, prepared)
+	if err != nil || step == "" {
+		return err
+	}
+	if state == nil || state.CompletedAt != nil {
+		state = newMigrationState(clusterConfig, metav1.Now())
 	}
+	proceed, changed := state.recordStep(step, clusterConfig.Spec.NetworkType, migrationStepObjects(step, &operConfig.Spec, &prepared.Spec), metav1.Now())
+	if changed {
+		if err := r.writeMigrationState(ctx, state); err != nil {
+			return err
+		}
+	}
+	if !proceed {
+		last := state.lastStep()
+		klog.Warningf("holding the network type live migration at step %s, the conditions point back to step %s", last.Name, step)
+		return r.setMigrationHeldCondition(ctx, &metav1.Condition{
+			Type:    names.NetworkTypeMigrationHeld,
+			Status:  metav1.ConditionTrue,
+			Reason:  "ConditionsPointToEarlierStep",
+			Message: fmt.Sprintf("The migration holds at step %s, as the conditions point back to step %s", last.Name, step),
+		})
+	}
+	if err := r.setMigrationHeldCondition(ctx, nil); err != nil {
+		return err
+	}
+	operConfig.Spec = prepared.Spec
 	return nil
 }
 
@@ -57,19 +104,26 @@ func (r *ReconcileClusterConfig) processNetworkTypeLiveMigration(ctx context.Con
 // 2. apply a MC with routable MTU to each MCP and switch the cluster default CNI to ovn-kubernetes, it will trigger MCP update
 // 3. remove routable MTU configuration from each MCP
 // 4. purge the openshift-sdn CNI pods
-func (r *ReconcileClusterConfig) prepareOperatorConfigForNetworkTypeMigration(ctx context.Context, clusterConfig *configv1.Network, operConfig *operv1.Network) error {
+//
+// An abort before step 3 sets spec.networkType back to the status one, and the
+// nodes are still configured for it. The rollback then takes the following steps:
+// 1. deploy the original CNI pods again, if the target CNI is not available yet
+// 2. if the routable MTU is applied, switch back to the original CNI, which removes
+// the routable MTU configuration from each MCP and triggers an MCP update
+// 3. purge the target CNI pods
+func (r *ReconcileClusterConfig) prepareOperatorConfigForNetworkTypeMigration(ctx context.Context, clusterConfig *configv1.Network, operConfig *operv1.Network) (migrationStepName, error) {
 	configConditions := clusterConfig.Status.Conditions
 	if configConditions == nil {
-		return fmt.Errorf("status.Conditions is not initialized")
+		return "", fmt.Errorf("status.Conditions is not initialized")
 	}
 
 	mcpCondition := meta.FindStatusCondition(configConditions, names.NetworkTypeMigrationMTUReady)
 	if mcpCondition == nil {
-		return fmt.Errorf("condition %q not found", names.NetworkTypeMigrationMTUReady)
+		return "", fmt.Errorf("condition %q not found", names.NetworkTypeMigrationMTUReady)
 	}
 	if mcpCondition.Reason == names.MachineConfigPoolsUpdating {
 		klog.Infof("MCP is updating, so we don't modify the operator config")
-		return nil
+		return "", nil
 	}
 
 	mtuApplied := meta.IsStatusConditionPresentAndEqual(configConditions, names.NetworkTypeMigrationMTUReady, metav1.ConditionTrue)
@@ -86,8 +140,9 @@ func (r *ReconcileClusterConfig) prepareOperatorConfigForNetworkTypeMigration(ct
 			klog.Infof("step-4: purge the original CNI")
 			operConfig.Spec.DefaultNetwork.Type = operv1.NetworkType(clusterConfig.Spec.NetworkType)
 			operConfig.Spec.Migration = nil
+			return migrationStepPurgeOriginalCNI, nil
 		}
-		return nil
+		return "", nil
 	}
 
 	if mtuApplied {
@@ -97,16 +152,20 @@ func (r *ReconcileClusterConfig) prepareOperatorConfigForNetworkTypeMigration(ct
 			Mode:        operv1.LiveNetworkMigrationMode,
 			NetworkType: clusterConfig.Spec.NetworkType,
 		}
-		return nil
+		return migrationStepSwitchCNI, nil
 	}
 
 	if !cniReady && clusterConfig.Status.Migration == nil {
 		klog.Infof("step-1: deploy target CNI: %s", clusterConfig.Spec.NetworkType)
+		if v1helpers.IsOperatorConditionFalse(operConfig.Status.Conditions, names.OVNKubernetesMigrationReady) {
+			readiness := v1helpers.FindOperatorCondition(operConfig.Status.Conditions, names.OVNKubernetesMigrationReady)
+			klog.Warningf("Starting the migration despite the readiness report: %s", readiness.Message)
+		}
 		operConfig.Spec.Migration = &operv1.NetworkMigration{
 			Mode:        operv1.LiveNetworkMigrationMode,
 			NetworkType: clusterConfig.Spec.NetworkType,
 		}
-		return nil
+		return migrationStepDeployTargetCNI, nil
 	}
 	if !mtuApplied && cniReady {
 		if isRollback {
@@ -115,7 +174,7 @@ func (r *ReconcileClusterConfig) prepareOperatorConfigForNetworkTypeMigration(ct
 		}
 		mtuMigration, err := r.calculateRoutableMTU(ctx, clusterConfig, operConfig, clusterConfig.Status.NetworkType)
 		if err != nil {
-			return err
+			return "", err
 		}
 		klog.Infof("step-2: apply routable MTU: %v", *mtuMigration.Network.To)
 		operConfig.Spec.Migration = &operv1.NetworkMigration{
@@ -123,8 +182,9 @@ func (r *ReconcileClusterConfig) prepareOperatorConfigForNetworkTypeMigration(ct
 			NetworkType: clusterConfig.Spec.NetworkType,
 			MTU:         mtuMigration,
 		}
+		return migrationStepApplyRoutableMTU, nil
 	}
-	return nil
+	return "", nil
 }
 
 func (r *ReconcileClusterConfig) calculateRoutableMTU(ctx context.Context, clusterConfig *configv1.Network, operConfig *operv1.Network, networkType string) (*operv1.MTUMigration, error) {
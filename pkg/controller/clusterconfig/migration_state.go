@@ -0,0 +1,242 @@
+package clusterconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// migrationStateConfigMap persists the progress of the network type live migration,
+	// so that it is resumed from the last recorded step after an operator restart
+	migrationStateConfigMap = "network-type-migration-state"
+	// migrationStateKey is the key of the JSON encoded migrationState
+	migrationStateKey = "state.json"
+)
+
+// migrationStepName is a step of prepareOperatorConfigForNetworkTypeMigration
+type migrationStepName string
+
+const (
+	migrationStepDeployTargetCNI  migrationStepName = "DeployTargetCNI"
+	migrationStepApplyRoutableMTU migrationStepName = "ApplyRoutableMTU"
+	migrationStepSwitchCNI        migrationStepName = "SwitchCNI"
+	migrationStepPurgeOriginalCNI migrationStepName = "PurgeOriginalCNI"
+)
+
+// migrationStepOrder is the position of each step in a migration, or in a rollback
+var migrationStepOrder = map[migrationStepName]int{
+	migrationStepDeployTargetCNI:  1,
+	migrationStepApplyRoutableMTU: 2,
+	migrationStepSwitchCNI:        3,
+	migrationStepPurgeOriginalCNI: 4,
+}
+
+// migrationState is the persisted record of a network type live migration
+type migrationState struct {
+	// From is the network type the migration started from, and the one an abort rolls back to
+	From string `json:"from"`
+	// To is the network type the migration was started for
+	To          string       `json:"to"`
+	StartedAt   metav1.Time  `json:"startedAt"`
+	AbortedAt   *metav1.Time `json:"abortedAt,omitempty"`
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+	// Steps are the steps taken so far, in order
+	Steps []migrationStepRecord `json:"steps"`
+}
+
+// migrationStepRecord is a step taken by the migration
+type migrationStepRecord struct {
+	Name migrationStepName `json:"name"`
+	// NetworkType is the network type the step migrates to. It is the From
+	// network type of the migration for the steps taken after an abort.
+	NetworkType string       `json:"networkType"`
+	StartedAt   metav1.Time  `json:"startedAt"`
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+	// Objects are the objects the step created or changed
+	Objects []string `json:"objects"`
+}
+
+func newMigrationState(clusterConfig *configv1.Network, now metav1.Time) *migrationState {
+	return &migrationState{
+		From:      clusterConfig.Status.NetworkType,
+		To:        clusterConfig.Spec.NetworkType,
+		StartedAt: now,
+		Steps:     []migrationStepRecord{},
+	}
+}
+
+// lastStep returns the step in progress, or nil if no step was taken yet
+func (s *migrationState) lastStep() *migrationStepRecord {
+	if len(s.Steps) == 0 {
+		return nil
+	}
+	return &s.Steps[len(s.Steps)-1]
+}
+
+// recordStep records that the migration to networkType is at step. It returns
+// whether the operator config may be prepared for the step, and whether the
+// state changed. A migration never goes back to an earlier step in the same
+// direction: the conditions may point back to an earlier step after a restart,
+// or while a MachineConfigPool is degraded, and the migration then holds at the
+// last recorded step. Going the other direction, after an abort or a rollback,
+// starts over from the first step.
+func (s *migrationState) recordStep(step migrationStepName, networkType string, objects []string, now metav1.Time) (bool, bool) {
+	last := s.lastStep()
+	if last != nil && last.NetworkType == networkType {
+		if last.Name == step {
+			return true, false
+		}
+		if migrationStepOrder[step] < migrationStepOrder[last.Name] {
+			return false, false
+		}
+		last.CompletedAt = &now
+	}
+	s.Steps = append(s.Steps, migrationStepRecord{
+		Name:        step,
+		NetworkType: networkType,
+		StartedAt:   now,
+		Objects:     objects,
+	})
+	return true, true
+}
+
+// complete marks the migration, and its last step, as completed
+func (s *migrationState) complete(now metav1.Time) {
+	if last := s.lastStep(); last != nil && last.CompletedAt == nil {
+		last.CompletedAt = &now
+	}
+	s.CompletedAt = &now
+}
+
+// migrationStepObjects describes the objects a step creates or changes, from the
+// changes to the operator config and the objects the operator renders from them.
+func migrationStepObjects(step migrationStepName, before, after *operv1.NetworkSpec) []string {
+	objects := []string{}
+	if before.DefaultNetwork.Type != after.DefaultNetwork.Type {
+		objects = append(objects, fmt.Sprintf("network.operator.openshift.io/%s: spec.defaultNetwork.type %s -> %s",
+			names.OPERATOR_CONFIG, before.DefaultNetwork.Type, after.DefaultNetwork.Type))
+	}
+	if after.Migration == nil {
+		objects = append(objects, fmt.Sprintf("network.operator.openshift.io/%s: spec.migration removed", names.OPERATOR_CONFIG))
+	} else if migration, err := json.Marshal(after.Migration); err == nil {
+		objects = append(objects, fmt.Sprintf("network.operator.openshift.io/%s: spec.migration %s", names.OPERATOR_CONFIG, migration))
+	}
+
+	switch step {
+	case migrationStepDeployTargetCNI:
+		objects = append(objects, fmt.Sprintf("the %s DaemonSets, next to the ones of %s", after.Migration.NetworkType, after.DefaultNetwork.Type))
+	case migrationStepApplyRoutableMTU:
+		objects = append(objects, "a MachineConfig per MachineConfigPool with the routable MTU")
+	case migrationStepSwitchCNI:
+		objects = append(objects, fmt.Sprintf("a MachineConfig per MachineConfigPool that configures the nodes for %s", after.DefaultNetwork.Type))
+	case migrationStepPurgeOriginalCNI:
+		objects = append(objects, fmt.Sprintf("only the %s DaemonSets are kept", after.DefaultNetwork.Type))
+	}
+	return objects
+}
+
+// readMigrationState returns the persisted migration state, or nil if there is none
+func (r *ReconcileClusterConfig) readMigrationState(ctx context.Context) (*migrationState, error) {
+	cm := &corev1.ConfigMap{}
+	err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: migrationStateConfigMap}, cm)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get the network type migration state: %w", err)
+	}
+	state := &migrationState{}
+	if err := json.Unmarshal([]byte(cm.Data[migrationStateKey]), state); err != nil {
+		return nil, fmt.Errorf("failed to decode the network type migration state in ConfigMap %s/%s: %w",
+			names.APPLIED_NAMESPACE, migrationStateConfigMap, err)
+	}
+	return state, nil
+}
+
+// writeMigrationState persists the migration state
+func (r *ReconcileClusterConfig) writeMigrationState(ctx context.Context, state *migrationState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	cm := &corev1.ConfigMap{}
+	err = r.client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: migrationStateConfigMap}, cm)
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: names.APPLIED_NAMESPACE, Name: migrationStateConfigMap},
+			Data:       map[string]string{migrationStateKey: string(data)},
+		}
+		return r.client.Default().CRClient().Create(ctx, cm)
+	}
+	if err != nil {
+		return err
+	}
+	cm.Data = map[string]string{migrationStateKey: string(data)}
+	return r.client.Default().CRClient().Update(ctx, cm)
+}
+
+// abortNetworkTypeMigration handles the NetworkTypeMigrationAbortAnnotation. It
+// records the abort and sets the network type of the cluster config back to the
+// one the migration started from, so that the migration is rolled back from the
+// step it is at. The annotation is removed in any case.
+func (r *ReconcileClusterConfig) abortNetworkTypeMigration(ctx context.Context, clusterConfig *configv1.Network, state *migrationState) error {
+	switch {
+	case state == nil || state.CompletedAt != nil:
+		klog.Warningf("Ignoring the %s annotation, no network type live migration is in progress", names.NetworkTypeMigrationAbortAnnotation)
+	case state.AbortedAt != nil:
+		klog.Infof("The network type live migration to %s is already aborted", state.To)
+	default:
+		now := metav1.Now()
+		state.AbortedAt = &now
+		if err := r.writeMigrationState(ctx, state); err != nil {
+			return err
+		}
+		klog.Infof("Aborting the network type live migration to %s at step %s, rolling back to %s",
+			state.To, lastStepName(state), state.From)
+		clusterConfig.Spec.NetworkType = state.From
+	}
+	delete(clusterConfig.Annotations, names.NetworkTypeMigrationAbortAnnotation)
+	return r.client.Default().CRClient().Update(ctx, clusterConfig)
+}
+
+func lastStepName(state *migrationState) migrationStepName {
+	if last := state.lastStep(); last != nil {
+		return last.Name
+	}
+	return "none"
+}
+
+// setMigrationHeldCondition sets the NetworkTypeMigrationHeld condition of the
+// cluster config, or removes it when held is nil.
+func (r *ReconcileClusterConfig) setMigrationHeldCondition(ctx context.Context, held *metav1.Condition) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		clusterConfig := &configv1.Network{}
+		if err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.CLUSTER_CONFIG}, clusterConfig); err != nil {
+			return err
+		}
+		updated := clusterConfig.DeepCopy()
+		if held == nil {
+			meta.RemoveStatusCondition(&updated.Status.Conditions, names.NetworkTypeMigrationHeld)
+		} else {
+			meta.SetStatusCondition(&updated.Status.Conditions, *held)
+		}
+		if reflect.DeepEqual(clusterConfig.Status.Conditions, updated.Status.Conditions) {
+			return nil
+		}
+		return r.client.Default().CRClient().Status().Update(ctx, updated)
+	})
+}
@@ -0,0 +1,288 @@
+package clusterconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+)
+
+func stepNames(state *migrationState) []string {
+	out := []string{}
+	for _, step := range state.Steps {
+		out = append(out, step.NetworkType+"/"+string(step.Name))
+	}
+	return out
+}
+
+func TestMigrationStateRecordStep(t *testing.T) {
+	now := metav1.Now()
+	ovn := string(operv1.NetworkTypeOVNKubernetes)
+	sdn := string(operv1.NetworkTypeOpenShiftSDN)
+	state := &migrationState{From: sdn, To: ovn}
+
+	proceed, changed := state.recordStep(migrationStepDeployTargetCNI, ovn, nil, now)
+	assert.True(t, proceed)
+	assert.True(t, changed)
+
+	// Preparing the same step again is a no-op
+	proceed, changed = state.recordStep(migrationStepDeployTargetCNI, ovn, nil, now)
+	assert.True(t, proceed)
+	assert.False(t, changed)
+
+	proceed, changed = state.recordStep(migrationStepApplyRoutableMTU, ovn, nil, now)
+	assert.True(t, proceed)
+	assert.True(t, changed)
+	assert.NotNil(t, state.Steps[0].CompletedAt)
+	assert.Nil(t, state.Steps[1].CompletedAt)
+
+	// The migration holds instead of going back
+	proceed, changed = state.recordStep(migrationStepDeployTargetCNI, ovn, nil, now)
+	assert.False(t, proceed)
+	assert.False(t, changed)
+
+	// A rollback starts over from the first step
+	proceed, changed = state.recordStep(migrationStepDeployTargetCNI, sdn, nil, now)
+	assert.True(t, proceed)
+	assert.True(t, changed)
+	assert.Nil(t, state.Steps[1].CompletedAt)
+	assert.Equal(t, []string{
+		ovn + "/DeployTargetCNI",
+		ovn + "/ApplyRoutableMTU",
+		sdn + "/DeployTargetCNI",
+	}, stepNames(state))
+
+	state.complete(now)
+	assert.NotNil(t, state.Steps[2].CompletedAt)
+	assert.NotNil(t, state.CompletedAt)
+}
+
+func TestProcessNetworkTypeLiveMigrationCheckpointAndAbort(t *testing.T) {
+	ctx := context.TODO()
+	clusterConfig := &configv1.Network{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        names.CLUSTER_CONFIG,
+			Annotations: map[string]string{names.NetworkTypeMigrationAnnotation: ""},
+		},
+		Spec: configv1.NetworkSpec{NetworkType: string(operv1.NetworkTypeOVNKubernetes)},
+		Status: configv1.NetworkStatus{
+			NetworkType: string(operv1.NetworkTypeOpenShiftSDN),
+			Conditions:  generateStatusConditions(metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse),
+		},
+	}
+	current := &operv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG},
+		Spec: operv1.NetworkSpec{
+			DefaultNetwork: operv1.DefaultNetworkDefinition{Type: operv1.NetworkTypeOpenShiftSDN},
+		},
+	}
+	r := &ReconcileClusterConfig{client: fake.NewFakeClient(clusterConfig, current)}
+	process := func() *operv1.Network {
+		cc := &configv1.Network{}
+		assert.NoError(t, r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.CLUSTER_CONFIG}, cc))
+		cc.Status = clusterConfig.Status
+		operConfig := &operv1.Network{ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG}}
+		assert.NoError(t, r.processNetworkTypeLiveMigration(ctx, reconcile.Request{}, cc, operConfig))
+		clusterConfig = cc
+		return operConfig
+	}
+	readState := func() *migrationState {
+		state, err := r.readMigrationState(ctx)
+		assert.NoError(t, err)
+		return state
+	}
+
+	operConfig := process()
+	assert.Equal(t, &operv1.NetworkMigration{
+		Mode:        operv1.LiveNetworkMigrationMode,
+		NetworkType: string(operv1.NetworkTypeOVNKubernetes),
+	}, operConfig.Spec.Migration)
+	state := readState()
+	assert.Equal(t, string(operv1.NetworkTypeOpenShiftSDN), state.From)
+	assert.Equal(t, []string{"OVNKubernetes/DeployTargetCNI"}, stepNames(state))
+	assert.NotEmpty(t, state.Steps[0].Objects)
+
+	// After a restart, the recorded step is resumed without being recorded again
+	process()
+	assert.Len(t, readState().Steps, 1)
+
+	// Abort the migration
+	cc := &configv1.Network{}
+	assert.NoError(t, r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.CLUSTER_CONFIG}, cc))
+	cc.Annotations[names.NetworkTypeMigrationAbortAnnotation] = ""
+	assert.NoError(t, r.client.Default().CRClient().Update(ctx, cc))
+	operConfig = process()
+	assert.Equal(t, string(operv1.NetworkTypeOpenShiftSDN), clusterConfig.Spec.NetworkType)
+	assert.NotContains(t, clusterConfig.Annotations, names.NetworkTypeMigrationAbortAnnotation)
+	assert.Equal(t, string(operv1.NetworkTypeOpenShiftSDN), operConfig.Spec.Migration.NetworkType)
+	state = readState()
+	assert.NotNil(t, state.AbortedAt)
+	assert.Equal(t, []string{"OVNKubernetes/DeployTargetCNI", "OpenShiftSDN/DeployTargetCNI"}, stepNames(state))
+
+	// The state is completed with the migration
+	meta.SetStatusCondition(&clusterConfig.Status.Conditions, metav1.Condition{
+		Type:   names.NetworkTypeMigrationInProgress,
+		Status: metav1.ConditionFalse,
+	})
+	process()
+	assert.NotNil(t, readState().CompletedAt)
+}
+
+// migrationTest drives processNetworkTypeLiveMigration, with the conditions the
+// operconfig controller would set on the cluster config
+type migrationTest struct {
+	t *testing.T
+	r *ReconcileClusterConfig
+}
+
+func newMigrationTest(t *testing.T, clusterConfig *configv1.Network) *migrationTest {
+	current := &operv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG},
+		Spec: operv1.NetworkSpec{
+			DefaultNetwork: operv1.DefaultNetworkDefinition{Type: operv1.NetworkType(clusterConfig.Status.NetworkType)},
+		},
+	}
+	mtu := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: names.APPLIED_NAMESPACE, Name: "mtu"},
+		Data:       map[string]string{"mtu": "1500"},
+	}
+	return &migrationTest{t: t, r: &ReconcileClusterConfig{client: fake.NewFakeClient(clusterConfig, current, mtu)}}
+}
+
+func (m *migrationTest) clusterConfig() *configv1.Network {
+	cc := &configv1.Network{}
+	assert.NoError(m.t, m.r.client.Default().CRClient().Get(context.TODO(), types.NamespacedName{Name: names.CLUSTER_CONFIG}, cc))
+	return cc
+}
+
+// process runs the migration with the given conditions, and applies the
+// resulting operator config as the clusterconfig controller does
+func (m *migrationTest) process(conditions []metav1.Condition) *operv1.Network {
+	ctx := context.TODO()
+	cc := m.clusterConfig()
+	for _, cond := range conditions {
+		meta.SetStatusCondition(&cc.Status.Conditions, cond)
+	}
+	assert.NoError(m.t, m.r.client.Default().CRClient().Status().Update(ctx, cc))
+
+	operConfig := &operv1.Network{ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG}}
+	assert.NoError(m.t, m.r.processNetworkTypeLiveMigration(ctx, reconcile.Request{}, cc, operConfig))
+	current := &operv1.Network{}
+	assert.NoError(m.t, m.r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, current))
+	current.Spec.DefaultNetwork.Type = operConfig.Spec.DefaultNetwork.Type
+	current.Spec.Migration = operConfig.Spec.Migration
+	assert.NoError(m.t, m.r.client.Default().CRClient().Update(ctx, current))
+	return current
+}
+
+func (m *migrationTest) state() *migrationState {
+	state, err := m.r.readMigrationState(context.TODO())
+	assert.NoError(m.t, err)
+	return state
+}
+
+func TestProcessNetworkTypeLiveMigrationHold(t *testing.T) {
+	m := newMigrationTest(t, &configv1.Network{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        names.CLUSTER_CONFIG,
+			Annotations: map[string]string{names.NetworkTypeMigrationAnnotation: ""},
+		},
+		Spec: configv1.NetworkSpec{NetworkType: string(operv1.NetworkTypeOVNKubernetes)},
+		Status: configv1.NetworkStatus{
+			NetworkType:       string(operv1.NetworkTypeOpenShiftSDN),
+			ClusterNetworkMTU: 1450,
+		},
+	})
+
+	m.process(generateStatusConditions(metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse))
+	m.process(generateStatusConditions(metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse))
+	switched := m.process(generateStatusConditions(metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse))
+	assert.Equal(t, operv1.NetworkTypeOVNKubernetes, switched.Spec.DefaultNetwork.Type)
+
+	// A degraded MachineConfigPool makes the conditions point back to ApplyRoutableMTU
+	operConfig := m.process([]metav1.Condition{{
+		Type:   names.NetworkTypeMigrationMTUReady,
+		Status: metav1.ConditionFalse,
+		Reason: "MachineConfigPoolDegraded",
+	}})
+	assert.Equal(t, switched.Spec, operConfig.Spec)
+	assert.Equal(t, []string{"OVNKubernetes/DeployTargetCNI", "OVNKubernetes/ApplyRoutableMTU", "OVNKubernetes/SwitchCNI"}, stepNames(m.state()))
+	held := meta.FindStatusCondition(m.clusterConfig().Status.Conditions, names.NetworkTypeMigrationHeld)
+	if assert.NotNil(t, held) {
+		assert.Equal(t, metav1.ConditionTrue, held.Status)
+		assert.Equal(t, "ConditionsPointToEarlierStep", held.Reason)
+		assert.Equal(t, "The migration holds at step SwitchCNI, as the conditions point back to step ApplyRoutableMTU", held.Message)
+	}
+
+	// The condition is removed once the migration proceeds
+	m.process(generateStatusConditions(metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionFalse))
+	assert.Nil(t, meta.FindStatusCondition(m.clusterConfig().Status.Conditions, names.NetworkTypeMigrationHeld))
+	assert.Equal(t, "PurgeOriginalCNI", string(m.state().lastStep().Name))
+}
+
+func TestProcessNetworkTypeLiveMigrationAbortBeforeSwitchCNI(t *testing.T) {
+	ctx := context.TODO()
+	sdn := string(operv1.NetworkTypeOpenShiftSDN)
+	m := newMigrationTest(t, &configv1.Network{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        names.CLUSTER_CONFIG,
+			Annotations: map[string]string{names.NetworkTypeMigrationAnnotation: ""},
+		},
+		Spec: configv1.NetworkSpec{NetworkType: string(operv1.NetworkTypeOVNKubernetes)},
+		Status: configv1.NetworkStatus{
+			NetworkType:       sdn,
+			ClusterNetworkMTU: 1450,
+		},
+	})
+
+	m.process(generateStatusConditions(metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse))
+	operConfig := m.process(generateStatusConditions(metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse))
+	assert.NotNil(t, operConfig.Spec.Migration.MTU)
+
+	// Abort once the routable MTU is applied, before the CNI is switched
+	cc := m.clusterConfig()
+	cc.Annotations[names.NetworkTypeMigrationAbortAnnotation] = ""
+	assert.NoError(t, m.r.client.Default().CRClient().Update(ctx, cc))
+	operConfig = m.process(generateStatusConditions(metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse))
+	cc = m.clusterConfig()
+	assert.Equal(t, sdn, cc.Spec.NetworkType)
+	assert.Equal(t, cc.Status.NetworkType, cc.Spec.NetworkType)
+
+	// The original CNI is switched back to, which removes the routable MTU
+	assert.Equal(t, operv1.NetworkTypeOpenShiftSDN, operConfig.Spec.DefaultNetwork.Type)
+	assert.Equal(t, &operv1.NetworkMigration{Mode: operv1.LiveNetworkMigrationMode, NetworkType: sdn}, operConfig.Spec.Migration)
+
+	// Nothing changes while the MachineConfigPools update
+	updating := generateStatusConditions(metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse)
+	updating[2].Reason = names.MachineConfigPoolsUpdating
+	assert.Equal(t, operConfig.Spec, m.process(updating).Spec)
+
+	// The nodes are back on the original CNI, the target CNI is purged
+	operConfig = m.process(generateStatusConditions(metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionFalse))
+	assert.Equal(t, operv1.NetworkTypeOpenShiftSDN, operConfig.Spec.DefaultNetwork.Type)
+	assert.Nil(t, operConfig.Spec.Migration)
+
+	// The operconfig controller completes the migration once the target CNI is gone
+	m.process([]metav1.Condition{{Type: names.NetworkTypeMigrationInProgress, Status: metav1.ConditionFalse}})
+	state := m.state()
+	assert.NotNil(t, state.AbortedAt)
+	assert.NotNil(t, state.CompletedAt)
+	assert.Equal(t, []string{
+		"OVNKubernetes/DeployTargetCNI",
+		"OVNKubernetes/ApplyRoutableMTU",
+		"OpenShiftSDN/SwitchCNI",
+		"OpenShiftSDN/PurgeOriginalCNI",
+	}, stepNames(state))
+	assert.Nil(t, meta.FindStatusCondition(m.clusterConfig().Status.Conditions, names.NetworkTypeMigrationHeld))
+}
@@ -456,7 +456,7 @@ func TestPrepareOperatorConfigForNetworkTypeMigration(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			err := r.prepareOperatorConfigForNetworkTypeMigration(ctx, tt.clusterConfig, tt.operConfig)
+			_, err := r.prepareOperatorConfigForNetworkTypeMigration(ctx, tt.clusterConfig, tt.operConfig)
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
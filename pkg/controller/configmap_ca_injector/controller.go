@@ -2,26 +2,23 @@ package configmapcainjector
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"log"
 
-	"github.com/openshift/cluster-network-operator/pkg/apply"
 	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
 	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
 	"github.com/openshift/cluster-network-operator/pkg/names"
 	"github.com/openshift/cluster-network-operator/pkg/util/validation"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 
-	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	v1coreinformers "k8s.io/client-go/informers/core/v1"
 	v1corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/util/retry"
 
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -67,6 +64,7 @@ func newReconciler(mgr manager.Manager, status *statusmanager.StatusManager, c c
 		nsInformer:    ni,
 		nsLister:      v1corelisters.NewConfigMapLister(ni.GetIndexer()),
 	}
+	r.injector = newBundleInjector(c, status, r.labelLister, r.nsLister)
 
 	c.Default().AddCustomInformer(r.labelInformer)
 	c.Default().AddCustomInformer(r.nsInformer)
@@ -94,7 +92,8 @@ func add(mgr manager.Manager, r *ReconcileConfigMapInjector) error {
 		return err
 	}
 
-	return nil
+	// Inject the bundle from a rate-limited work queue
+	return mgr.Add(r.injector)
 }
 
 var _ reconcile.Reconciler = &ReconcileConfigMapInjector{}
@@ -108,6 +107,10 @@ type ReconcileConfigMapInjector struct {
 	labelLister   v1corelisters.ConfigMapLister
 	nsInformer    cache.SharedIndexInformer
 	nsLister      v1corelisters.ConfigMapLister
+
+	injector *bundleInjector
+	// systemTrustBundle caches the certificates of the system trust bundle
+	systemTrustBundle []*x509.Certificate
 }
 
 // Reconcile expects requests to refers to configmaps of two different types.
@@ -128,7 +131,7 @@ func (r *ReconcileConfigMapInjector) Reconcile(ctx context.Context, request reco
 		log.Println(err)
 		return reconcile.Result{}, err
 	}
-	_, trustedCAbundleData, err := validation.TrustBundleConfigMap(trustedCAbundleConfigMap, names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY)
+	trustedCAbundleCerts, _, err := validation.TrustBundleConfigMap(trustedCAbundleConfigMap, names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY)
 
 	if err != nil {
 		log.Println(err)
@@ -136,8 +139,14 @@ func (r *ReconcileConfigMapInjector) Reconcile(ctx context.Context, request reco
 			fmt.Sprintf("Failed to validate trusted CA certificates in %s", trustedCAbundleConfigMap.Name))
 		return reconcile.Result{}, err
 	}
-	// Build a list of configMaps.
-	configMapsToChange := []*corev1.ConfigMap{}
+
+	// Both a new bundle and a new or removed injection target change the
+	// inventory. It is informational, so failing to write it only requeues.
+	inventoryErr := r.syncTrustBundleInventory(ctx, trustedCAbundleCerts)
+	if inventoryErr != nil {
+		log.Printf("Failed to sync trusted CA bundle inventory configmap '%s/%s': %v",
+			names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS, names.TRUSTED_CA_BUNDLE_INVENTORY_CONFIGMAP, inventoryErr)
+	}
 
 	// The trusted-ca-bundle changed.
 	if request.Name == names.TRUSTED_CA_BUNDLE_CONFIGMAP && request.Namespace == names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS {
@@ -149,80 +158,15 @@ func (r *ReconcileConfigMapInjector) Reconcile(ctx context.Context, request reco
 			return reconcile.Result{}, err
 
 		}
-		configMapsToChange = cms
-		log.Printf("%s changed, updating %d configMaps", names.TRUSTED_CA_BUNDLE_CONFIGMAP, len(configMapsToChange))
+		r.injector.injectAll(cms)
 	} else {
-		// Changing a single labeled configmap.
-
-		// Get the requested object.
-		cm, err := r.labelLister.ConfigMaps(request.Namespace).Get(request.Name)
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				log.Printf("ConfigMap '%s/%s' not found; reconciliation will be skipped", request.Namespace, request.Name)
-				return reconcile.Result{}, nil
-			}
-			// Unlikely -- this is an informer
-			r.status.SetDegraded(statusmanager.InjectorConfig, "ClusterConfigError",
-				fmt.Sprintf("failed to get configmap '%s/%s': %v", request.Namespace, request.Name, err))
-			log.Println(err)
-			return reconcile.Result{}, err
-		}
-		configMapsToChange = append(configMapsToChange, cm)
+		// Changing a single labeled configmap. The injector skips it if it
+		// was deleted or unlabeled meanwhile.
+		r.injector.enqueue(request.Namespace, request.Name)
 	}
 
-	errs := []error{}
-
-	for _, configMap := range configMapsToChange {
-		err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-			needsOwner := len(configMap.Annotations[names.OpenShiftComponent]) == 0
-			if existing, ok := configMap.Data[names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY]; !needsOwner && ok && existing == string(trustedCAbundleData) {
-				// Nothing to update the new and old configmap object would be the same.
-				log.Printf("ConfigMap %s/%s %s unchanged, skipping", configMap.Namespace, configMap.Name, names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY)
-				return nil
-			}
-
-			// create sparse object with only the keys we care about.
-			// so that server-side-apply will DTRT.
-			configMapToUpdate := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: configMap.Namespace,
-					Name:      configMap.Name,
-					Annotations: map[string]string{
-						names.OpenShiftComponent: configMap.Annotations[names.OpenShiftComponent],
-					},
-				},
-				Data: map[string]string{
-					names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY: string(trustedCAbundleData),
-				},
-			}
-			// this lets a configmap writer to claim ownership
-			if needsOwner {
-				configMapToUpdate.Annotations[names.OpenShiftComponent] = trustedCAbundleConfigMap.Annotations[names.OpenShiftComponent]
-			}
-
-			err = apply.ApplyObject(ctx, r.client, configMapToUpdate, "configmap_ca")
-			if err != nil {
-				log.Println(err)
-				return err
-			}
-			return nil
-		})
-		if err != nil {
-			errs = append(errs, err)
-			if len(errs) > 5 {
-				r.status.SetDegraded(statusmanager.InjectorConfig, "ConfigMapUpdateFailure",
-					"Too many errors seen when updating trusted CA configmaps")
-				return reconcile.Result{}, fmt.Errorf("Too many errors attempting to update configmaps with CA cert. data")
-			}
-		}
-	}
-	if len(errs) > 0 {
-		r.status.SetDegraded(statusmanager.InjectorConfig, "ConfigmapUpdateFailure",
-			"some configmaps didn't fully update with CA cert. data")
-		return reconcile.Result{}, fmt.Errorf("some configmaps didn't fully update with CA cert. data")
-	}
 	r.status.SetNotDegraded(statusmanager.InjectorConfig)
-	return reconcile.Result{}, nil
+	return reconcile.Result{}, inventoryErr
 }
 
 func isCABundle(meta crclient.Object) bool {
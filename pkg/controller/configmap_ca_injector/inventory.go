@@ -0,0 +1,181 @@
+package configmapcainjector
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-network-operator/pkg/apply"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/crypto"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// sourceSystem is the source of the certificates of the system trust bundle
+	sourceSystem = "system"
+	// sourceUnknown is the source of the certificates that are neither in the
+	// system trust bundle nor in a proxy trustedCA ConfigMap
+	sourceUnknown = "unknown"
+)
+
+// trustBundleInventory is the content of the inventory ConfigMap
+type trustBundleInventory struct {
+	// Certificates are the certificates of the trusted CA bundle, in its order
+	Certificates []inventoryCertificate `json:"certificates"`
+	// Warnings are the expired and duplicate certificates of the bundle
+	Warnings []string `json:"warnings,omitempty"`
+	// InjectionTargets is the number of ConfigMaps the bundle is injected
+	// into, per namespace
+	InjectionTargets map[string]int `json:"injectionTargets"`
+}
+
+// inventoryCertificate describes one certificate of the trusted CA bundle
+type inventoryCertificate struct {
+	Subject           string `json:"subject"`
+	Issuer            string `json:"issuer"`
+	NotAfter          string `json:"notAfter"`
+	Expired           bool   `json:"expired,omitempty"`
+	SHA256Fingerprint string `json:"sha256Fingerprint"`
+	// Source is "system" for the system trust bundle, or the proxy trustedCA
+	// ConfigMap the certificate comes from
+	Source string `json:"source"`
+}
+
+// fingerprint returns the SHA-256 fingerprint of a certificate, the way openssl prints it
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(hex, ":")
+}
+
+// buildTrustBundleInventory describes the certificates of the merged trusted CA
+// bundle. The proxy controller merges the bundle as the certificates of the user
+// trustedCA ConfigMap followed by the system trust bundle, so the last occurrences
+// of the system certificates come from the system trust bundle, and the others from
+// userSource.
+func buildTrustBundleInventory(bundle, system []*x509.Certificate, userSource string, targets []*corev1.ConfigMap, now time.Time) *trustBundleInventory {
+	inventory := &trustBundleInventory{
+		Certificates:     make([]inventoryCertificate, len(bundle)),
+		InjectionTargets: map[string]int{},
+	}
+
+	systemCount := map[string]int{}
+	for _, cert := range system {
+		systemCount[fingerprint(cert)]++
+	}
+	for i := len(bundle) - 1; i >= 0; i-- {
+		cert := bundle[i]
+		fp := fingerprint(cert)
+		source := userSource
+		if systemCount[fp] > 0 {
+			systemCount[fp]--
+			source = sourceSystem
+		}
+		inventory.Certificates[i] = inventoryCertificate{
+			Subject:           cert.Subject.String(),
+			Issuer:            cert.Issuer.String(),
+			NotAfter:          cert.NotAfter.UTC().Format(time.RFC3339),
+			Expired:           now.After(cert.NotAfter),
+			SHA256Fingerprint: fp,
+			Source:            source,
+		}
+	}
+
+	sources := map[string][]string{}
+	for _, cert := range inventory.Certificates {
+		if cert.Expired {
+			inventory.Warnings = append(inventory.Warnings, fmt.Sprintf("certificate %q (%s) from %s expired on %s",
+				cert.Subject, cert.SHA256Fingerprint, cert.Source, cert.NotAfter))
+		}
+		sources[cert.SHA256Fingerprint] = append(sources[cert.SHA256Fingerprint], cert.Source)
+	}
+	for _, cert := range inventory.Certificates {
+		if certSources := sources[cert.SHA256Fingerprint]; len(certSources) > 1 {
+			inventory.Warnings = append(inventory.Warnings, fmt.Sprintf("certificate %q (%s) appears %d times, from %s",
+				cert.Subject, cert.SHA256Fingerprint, len(certSources), strings.Join(certSources, ", ")))
+			// warn once per certificate
+			delete(sources, cert.SHA256Fingerprint)
+		}
+	}
+
+	for _, target := range targets {
+		inventory.InjectionTargets[target.Namespace]++
+	}
+	return inventory
+}
+
+// syncTrustBundleInventory writes the inventory of the trusted CA bundle to the
+// inventory ConfigMap, unless it is unchanged. The injection targets are counted
+// from the informer of the labeled ConfigMaps.
+func (r *ReconcileConfigMapInjector) syncTrustBundleInventory(ctx context.Context, bundle []*x509.Certificate) error {
+	// The system trust bundle is part of the image, so it is only read once
+	if r.systemTrustBundle == nil {
+		systemData, err := os.ReadFile(names.SYSTEM_TRUST_BUNDLE)
+		if err != nil {
+			return fmt.Errorf("failed to read system trust bundle %s: %v", names.SYSTEM_TRUST_BUNDLE, err)
+		}
+		if r.systemTrustBundle, err = crypto.CertsFromPEM(systemData); err != nil {
+			return fmt.Errorf("failed to parse system trust bundle %s: %v", names.SYSTEM_TRUST_BUNDLE, err)
+		}
+	}
+
+	userSource := sourceUnknown
+	proxy := &configv1.Proxy{}
+	if err := r.client.Default().CRClient().Get(ctx, names.Proxy(), proxy); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get proxy '%s': %v", names.PROXY_CONFIG, err)
+		}
+	} else if proxy.Spec.TrustedCA.Name != "" {
+		ns := names.ADDL_TRUST_BUNDLE_CONFIGMAP_NS
+		if proxy.Spec.TrustedCA.Name == names.TRUSTED_CA_BUNDLE_CONFIGMAP {
+			ns = names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS
+		}
+		userSource = fmt.Sprintf("ConfigMap %s/%s", ns, proxy.Spec.TrustedCA.Name)
+	}
+
+	targets, err := r.labelLister.List(labelSelector.AsSelector())
+	if err != nil {
+		return fmt.Errorf("failed to list the injection targets: %v", err)
+	}
+
+	inventory := buildTrustBundleInventory(bundle, r.systemTrustBundle, userSource, targets, time.Now())
+	data, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return err
+	}
+	existing, err := r.nsLister.ConfigMaps(names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS).Get(names.TRUSTED_CA_BUNDLE_INVENTORY_CONFIGMAP)
+	if err == nil && existing.Data[names.TRUSTED_CA_BUNDLE_INVENTORY_CONFIGMAP_KEY] == string(data) {
+		return nil
+	}
+	for _, warning := range inventory.Warnings {
+		log.Printf("Warning: trusted CA bundle %s/%s: %s", names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS, names.TRUSTED_CA_BUNDLE_CONFIGMAP, warning)
+	}
+
+	inventoryConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS,
+			Name:      names.TRUSTED_CA_BUNDLE_INVENTORY_CONFIGMAP,
+			Annotations: map[string]string{
+				names.OpenShiftComponent: names.ClusterNetworkOperatorJiraComponent,
+			},
+		},
+		Data: map[string]string{
+			names.TRUSTED_CA_BUNDLE_INVENTORY_CONFIGMAP_KEY: string(data),
+		},
+	}
+	return apply.ApplyObject(ctx, r.client, inventoryConfigMap, "configmap_ca")
+}
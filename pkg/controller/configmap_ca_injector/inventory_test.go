@@ -0,0 +1,95 @@
+package configmapcainjector
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testCA returns a self-signed CA certificate that expires at notAfter
+func testCA(t *testing.T, name string, notAfter time.Time) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestBuildTrustBundleInventory(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	userCA := testCA(t, "user-ca", now.Add(24*time.Hour))
+	expiredCA := testCA(t, "expired-ca", now.Add(-24*time.Hour))
+	systemCA := testCA(t, "system-ca", now.Add(24*time.Hour))
+	otherSystemCA := testCA(t, "other-system-ca", now.Add(24*time.Hour))
+
+	// The user bundle also holds a system certificate
+	bundle := []*x509.Certificate{userCA, expiredCA, systemCA, systemCA, otherSystemCA}
+	system := []*x509.Certificate{systemCA, otherSystemCA}
+	targets := []*corev1.ConfigMap{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-ingress", Name: "trusted-ca"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-ingress", Name: "router-ca"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-console", Name: "trusted-ca-bundle"}},
+	}
+
+	inventory := buildTrustBundleInventory(bundle, system, "ConfigMap openshift-config/user-ca-bundle", targets, now)
+
+	sources := []string{}
+	for _, cert := range inventory.Certificates {
+		sources = append(sources, cert.Source)
+	}
+	g.Expect(sources).To(Equal([]string{
+		"ConfigMap openshift-config/user-ca-bundle",
+		"ConfigMap openshift-config/user-ca-bundle",
+		"ConfigMap openshift-config/user-ca-bundle",
+		"system",
+		"system",
+	}))
+
+	g.Expect(inventory.Certificates[0]).To(Equal(inventoryCertificate{
+		Subject:           "CN=user-ca",
+		Issuer:            "CN=user-ca",
+		NotAfter:          "2024-06-02T00:00:00Z",
+		SHA256Fingerprint: fingerprint(userCA),
+		Source:            "ConfigMap openshift-config/user-ca-bundle",
+	}))
+	g.Expect(inventory.Certificates[0].SHA256Fingerprint).To(MatchRegexp(`^([0-9A-F]{2}:){31}[0-9A-F]{2}$`))
+	g.Expect(inventory.Certificates[1].Expired).To(BeTrue())
+
+	g.Expect(inventory.Warnings).To(ConsistOf(
+		ContainSubstring(`certificate "CN=expired-ca" (%s) from ConfigMap openshift-config/user-ca-bundle expired on 2024-05-31T00:00:00Z`, fingerprint(expiredCA)),
+		ContainSubstring(`certificate "CN=system-ca" (%s) appears 2 times, from ConfigMap openshift-config/user-ca-bundle, system`, fingerprint(systemCA)),
+	))
+
+	g.Expect(inventory.InjectionTargets).To(Equal(map[string]int{
+		"openshift-ingress": 2,
+		"openshift-console": 1,
+	}))
+}
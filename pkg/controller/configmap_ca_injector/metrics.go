@@ -0,0 +1,50 @@
+package configmapcainjector
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	injectionDuration = metrics.NewHistogram(
+		&metrics.HistogramOpts{
+			Namespace:      "network_operator",
+			Subsystem:      "ca_injection",
+			Name:           "duration_seconds",
+			Help:           "The duration of the updates of the ConfigMaps the trusted CA bundle is injected into.",
+			Buckets:        []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+	injectionFailures = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Namespace:      "network_operator",
+			Subsystem:      "ca_injection",
+			Name:           "failures_total",
+			Help:           "The number of failed updates of the ConfigMaps the trusted CA bundle is injected into.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+	injectionSkipped = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Namespace:      "network_operator",
+			Subsystem:      "ca_injection",
+			Name:           "skipped_total",
+			Help:           "The number of ConfigMaps that already held the trusted CA bundle when it was injected.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+	injectionPending = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace:      "network_operator",
+			Subsystem:      "ca_injection",
+			Name:           "pending",
+			Help:           "The number of ConfigMaps the current trusted CA bundle still has to be injected into.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(injectionDuration, injectionFailures, injectionSkipped, injectionPending)
+}
@@ -0,0 +1,269 @@
+package configmapcainjector
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/cluster-network-operator/pkg/apply"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	v1corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// injectionWorkers is how many ConfigMaps are injected at the same time
+	injectionWorkers = 5
+	// injectionQPS and injectionBurst limit the updates of the ConfigMaps, so
+	// that a new bundle does not flood the apiserver on large clusters. The
+	// ConfigMaps that already hold the bundle are skipped without a request.
+	injectionQPS   = 20
+	injectionBurst = 40
+	// injectionMaxRetries is how many times the update of a ConfigMap is retried,
+	// with an exponential backoff, before the operator is Degraded
+	injectionMaxRetries = 5
+)
+
+// bundleInjector injects the trusted CA bundle into the labeled ConfigMaps from a
+// work queue, rather than one by one in Reconcile.
+type bundleInjector struct {
+	status      *statusmanager.StatusManager
+	labelLister v1corelisters.ConfigMapLister
+	nsLister    v1corelisters.ConfigMapLister
+
+	queue   workqueue.RateLimitingInterface
+	limiter flowcontrol.RateLimiter
+	// apply writes a ConfigMap
+	apply func(ctx context.Context, configMap *corev1.ConfigMap) error
+
+	lock sync.Mutex
+	// failing are the ConfigMaps that could not be updated after injectionMaxRetries
+	failing map[string]error
+	// rollout is the injection of a new bundle into all the ConfigMaps, if any
+	rollout *injectionRollout
+}
+
+// injectionRollout tracks the progress of the injection of a new bundle
+type injectionRollout struct {
+	total   int
+	pending sets.String
+	started time.Time
+	// reported is the last reported progress, in tenths of total
+	reported int
+}
+
+func newBundleInjector(c cnoclient.Client, status *statusmanager.StatusManager, labelLister, nsLister v1corelisters.ConfigMapLister) *bundleInjector {
+	return &bundleInjector{
+		status:      status,
+		labelLister: labelLister,
+		nsLister:    nsLister,
+		queue:       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "trusted-ca-bundle-injection"),
+		limiter:     flowcontrol.NewTokenBucketRateLimiter(injectionQPS, injectionBurst),
+		apply: func(ctx context.Context, configMap *corev1.ConfigMap) error {
+			return apply.ApplyObject(ctx, c, configMap, "configmap_ca")
+		},
+		failing: map[string]error{},
+	}
+}
+
+// Start runs the injection workers until ctx is done. It implements manager.Runnable.
+func (b *bundleInjector) Start(ctx context.Context) error {
+	defer b.queue.ShutDown()
+	for i := 0; i < injectionWorkers; i++ {
+		go wait.UntilWithContext(ctx, func(ctx context.Context) {
+			for b.processNextItem(ctx) {
+			}
+		}, time.Second)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// enqueue injects the bundle into a single ConfigMap
+func (b *bundleInjector) enqueue(namespace, name string) {
+	b.queue.Add(namespace + "/" + name)
+}
+
+// injectAll injects a new bundle into all the ConfigMaps, tracking its progress
+func (b *bundleInjector) injectAll(configMaps []*corev1.ConfigMap) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	log.Printf("%s changed, injecting it into %d configMaps", names.TRUSTED_CA_BUNDLE_CONFIGMAP, len(configMaps))
+	b.rollout = nil
+	if len(configMaps) > 0 {
+		b.rollout = &injectionRollout{total: len(configMaps), pending: sets.NewString(), started: time.Now()}
+		for _, configMap := range configMaps {
+			key := configMap.Namespace + "/" + configMap.Name
+			b.rollout.pending.Insert(key)
+			b.queue.Add(key)
+		}
+	}
+	b.updateStatus()
+}
+
+func (b *bundleInjector) processNextItem(ctx context.Context) bool {
+	item, quit := b.queue.Get()
+	if quit {
+		return false
+	}
+	defer b.queue.Done(item)
+
+	key := item.(string)
+	err := b.inject(ctx, key)
+	if err != nil && b.queue.NumRequeues(key) < injectionMaxRetries {
+		log.Printf("Failed to inject %s into configmap %s, retrying: %v", names.TRUSTED_CA_BUNDLE_CONFIGMAP, key, err)
+		b.queue.AddRateLimited(key)
+		return true
+	}
+	b.queue.Forget(key)
+	b.done(key, err)
+	return true
+}
+
+// bundleHash hashes the content of a ca-bundle.crt key
+func bundleHash(data string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(data))
+}
+
+// inject ensures that a ConfigMap holds the current bundle. Only the updates of
+// the ConfigMaps are rate limited.
+func (b *bundleInjector) inject(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	trustedCAbundleConfigMap, err := b.nsLister.ConfigMaps(names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS).Get(names.TRUSTED_CA_BUNDLE_CONFIGMAP)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	configMap, err := b.labelLister.ConfigMaps(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	trustedCAbundleData := trustedCAbundleConfigMap.Data[names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY]
+	needsOwner := len(configMap.Annotations[names.OpenShiftComponent]) == 0
+	if existing, ok := configMap.Data[names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY]; !needsOwner && ok && bundleHash(existing) == bundleHash(trustedCAbundleData) {
+		// Nothing to update the new and old configmap object would be the same.
+		injectionSkipped.Inc()
+		return nil
+	}
+
+	// create sparse object with only the keys we care about.
+	// so that server-side-apply will DTRT.
+	configMapToUpdate := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: configMap.Namespace,
+			Name:      configMap.Name,
+			Annotations: map[string]string{
+				names.OpenShiftComponent: configMap.Annotations[names.OpenShiftComponent],
+			},
+		},
+		Data: map[string]string{
+			names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY: trustedCAbundleData,
+		},
+	}
+	// this lets a configmap writer to claim ownership
+	if needsOwner {
+		configMapToUpdate.Annotations[names.OpenShiftComponent] = trustedCAbundleConfigMap.Annotations[names.OpenShiftComponent]
+	}
+
+	if err := b.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	start := time.Now()
+	err = b.apply(ctx, configMapToUpdate)
+	injectionDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		injectionFailures.Inc()
+	}
+	return err
+}
+
+// done records the result of the injection into a ConfigMap, once it succeeded
+// or ran out of retries, and reports the progress of the rollout.
+func (b *bundleInjector) done(key string, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	changed := false
+	if _, failing := b.failing[key]; failing != (err != nil) {
+		changed = true
+	}
+	if err != nil {
+		log.Printf("Failed to inject %s into configmap %s: %v", names.TRUSTED_CA_BUNDLE_CONFIGMAP, key, err)
+		b.failing[key] = err
+	} else {
+		delete(b.failing, key)
+	}
+
+	if rollout := b.rollout; rollout != nil && rollout.pending.Has(key) {
+		rollout.pending.Delete(key)
+		injected := rollout.total - rollout.pending.Len()
+		if rollout.pending.Len() == 0 {
+			log.Printf("Injected %s into %d configMaps in %s", names.TRUSTED_CA_BUNDLE_CONFIGMAP, rollout.total, time.Since(rollout.started).Round(time.Second))
+			b.rollout = nil
+			changed = true
+		} else if progress := injected * 10 / rollout.total; progress > rollout.reported {
+			log.Printf("Injected %s into %d/%d configMaps", names.TRUSTED_CA_BUNDLE_CONFIGMAP, injected, rollout.total)
+			rollout.reported = progress
+			changed = true
+		}
+	}
+	if changed {
+		b.updateStatus()
+	}
+}
+
+// updateStatus reports the ConfigMaps that could not be updated as Degraded, and
+// the progress of a rollout as Progressing. The lock must be held.
+func (b *bundleInjector) updateStatus() {
+	pending := 0
+	if b.rollout != nil {
+		pending = b.rollout.pending.Len()
+	}
+	injectionPending.Set(float64(pending))
+
+	switch {
+	case len(b.failing) > 0:
+		keys := make([]string, 0, len(b.failing))
+		for key := range b.failing {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if len(keys) > 5 {
+			keys = append(keys[:5], "...")
+		}
+		b.status.SetDegradedClearingProgressing(statusmanager.InjectorRollout, "ConfigMapUpdateFailure",
+			fmt.Sprintf("%d configmaps didn't fully update with CA cert. data: %s", len(b.failing), strings.Join(keys, ", ")))
+	case b.rollout != nil:
+		b.status.SetNotDegraded(statusmanager.InjectorRollout)
+		b.status.SetProgressing(statusmanager.InjectorRollout, "InjectingTrustedCABundle",
+			fmt.Sprintf("Injected %s into %d of %d configmaps", names.TRUSTED_CA_BUNDLE_CONFIGMAP, b.rollout.total-pending, b.rollout.total))
+	default:
+		b.status.UnsetProgressing(statusmanager.InjectorRollout)
+		b.status.SetNotDegraded(statusmanager.InjectorRollout)
+	}
+}
@@ -0,0 +1,125 @@
+package configmapcainjector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	v1corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func injectionTarget(name, bundle, owner string) *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "openshift-ingress",
+			Name:      name,
+			Labels:    map[string]string{names.TRUSTED_CA_BUNDLE_CONFIGMAP_LABEL: "true"},
+		},
+		Data: map[string]string{names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY: bundle},
+	}
+	if owner != "" {
+		configMap.Annotations = map[string]string{names.OpenShiftComponent: owner}
+	}
+	return configMap
+}
+
+func TestBundleInjector(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	nsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	labelIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	g.Expect(nsIndexer.Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS,
+			Name:        names.TRUSTED_CA_BUNDLE_CONFIGMAP,
+			Annotations: map[string]string{names.OpenShiftComponent: names.ClusterNetworkOperatorJiraComponent},
+		},
+		Data: map[string]string{names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY: "new bundle"},
+	})).To(Succeed())
+	targets := []*corev1.ConfigMap{
+		injectionTarget("up-to-date", "new bundle", "Networking"),
+		injectionTarget("stale", "old bundle", "Networking"),
+		injectionTarget("unowned", "new bundle", ""),
+	}
+	for _, target := range targets {
+		g.Expect(labelIndexer.Add(target)).To(Succeed())
+	}
+
+	client := fake.NewFakeClient(&operv1.Network{ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG}})
+	status := statusmanager.New(client, "testing", names.StandAloneClusterName)
+	condition := func(conditionType string) *operv1.OperatorCondition {
+		oc := &operv1.Network{}
+		g.Expect(client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, oc)).To(Succeed())
+		return v1helpers.FindOperatorCondition(oc.Status.Conditions, conditionType)
+	}
+
+	var lock sync.Mutex
+	var applied []*corev1.ConfigMap
+	var applyErr error
+	b := &bundleInjector{
+		status:      status,
+		labelLister: v1corelisters.NewConfigMapLister(labelIndexer),
+		nsLister:    v1corelisters.NewConfigMapLister(nsIndexer),
+		queue:       workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		limiter:     flowcontrol.NewFakeAlwaysRateLimiter(),
+		apply: func(ctx context.Context, configMap *corev1.ConfigMap) error {
+			lock.Lock()
+			defer lock.Unlock()
+			if applyErr != nil {
+				return applyErr
+			}
+			applied = append(applied, configMap)
+			return nil
+		},
+		failing: map[string]error{},
+	}
+	defer b.queue.ShutDown()
+
+	// A new bundle is injected into the targets that do not hold it yet
+	b.injectAll(targets)
+	g.Expect(condition(operv1.OperatorStatusTypeProgressing)).To(HaveField("Reason", "InjectingTrustedCABundle"))
+	g.Expect(condition(operv1.OperatorStatusTypeProgressing).Message).To(Equal("Injected trusted-ca-bundle into 0 of 3 configmaps"))
+	for i := 0; i < len(targets); i++ {
+		g.Expect(b.processNextItem(ctx)).To(BeTrue())
+	}
+	g.Expect(b.queue.Len()).To(Equal(0))
+	g.Expect(applied).To(HaveLen(2))
+	g.Expect(applied[0].Name).To(Equal("stale"))
+	g.Expect(applied[0].Data[names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY]).To(Equal("new bundle"))
+	g.Expect(applied[1].Name).To(Equal("unowned"))
+	g.Expect(applied[1].Annotations[names.OpenShiftComponent]).To(Equal(names.ClusterNetworkOperatorJiraComponent))
+	g.Expect(b.rollout).To(BeNil())
+	g.Expect(condition(operv1.OperatorStatusTypeProgressing)).To(HaveField("Status", operv1.ConditionFalse))
+
+	// A target that keeps failing is retried, then reported
+	applyErr = fmt.Errorf("apiserver unavailable")
+	b.enqueue("openshift-ingress", "stale")
+	for i := 0; i <= injectionMaxRetries; i++ {
+		g.Expect(b.processNextItem(ctx)).To(BeTrue())
+	}
+	g.Expect(b.queue.Len()).To(Equal(0))
+	g.Expect(condition(operv1.OperatorStatusTypeDegraded)).To(HaveField("Reason", "ConfigMapUpdateFailure"))
+	g.Expect(condition(operv1.OperatorStatusTypeDegraded).Message).To(ContainSubstring("openshift-ingress/stale"))
+
+	// A deleted target is no longer reported
+	g.Expect(labelIndexer.Delete(targets[1])).To(Succeed())
+	b.enqueue("openshift-ingress", "stale")
+	g.Expect(b.processNextItem(ctx)).To(BeTrue())
+	g.Expect(condition(operv1.OperatorStatusTypeDegraded)).To(HaveField("Status", operv1.ConditionFalse))
+}
@@ -0,0 +1,328 @@
+package dhcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	v1coreinformers "k8s.io/client-go/informers/core/v1"
+	v1corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// networksAnnotation is the multus annotation with which pods request
+	// additional networks
+	networksAnnotation = "k8s.v1.cni.cncf.io/networks"
+	// networkStatusAnnotation is the multus annotation that reports the
+	// interfaces and addresses of the networks a pod is attached to
+	networkStatusAnnotation = "k8s.v1.cni.cncf.io/network-status"
+
+	// daemonAppLabel is the app label of the dhcp-daemon pods
+	daemonAppLabel = "dhcp-daemon"
+)
+
+// resyncPeriod is how often the DHCP daemon and leases are checked, since
+// nothing triggers a reconcile when pods or daemon pods come and go
+var resyncPeriod = time.Minute
+
+// maxNodesInMessage caps the number of nodes listed in the Degraded message
+const maxNodesInMessage = 10
+
+// Add creates a new DHCP daemon controller and adds it to the manager.
+func Add(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client) error {
+	return add(mgr, newReconciler(status, c))
+}
+
+func newReconciler(status *statusmanager.StatusManager, c cnoclient.Client) *ReconcileDHCP {
+	// The pods of the whole cluster are inspected on each reconcile, so they
+	// are read from an informer rather than listed from the apiserver. Only
+	// the running pods are cached, trimmed down to what inspectPods reads.
+	podInformer := v1coreinformers.NewFilteredPodInformer(
+		c.Default().Kubernetes(),
+		metav1.NamespaceAll,
+		0, // don't resync
+		cache.Indexers{},
+		func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.AndSelectors(
+				fields.OneTermNotEqualSelector("status.phase", string(corev1.PodSucceeded)),
+				fields.OneTermNotEqualSelector("status.phase", string(corev1.PodFailed)),
+			).String()
+		})
+	if err := podInformer.SetTransform(trimPod); err != nil {
+		klog.Errorf("Failed to set the transform of the pod informer: %v", err)
+	}
+	c.Default().AddCustomInformer(podInformer)
+
+	return &ReconcileDHCP{
+		client:      c,
+		status:      status,
+		podInformer: podInformer,
+		podLister:   v1corelisters.NewPodLister(podInformer.GetIndexer()),
+		unavailable: sets.New[string](),
+	}
+}
+
+// trimPod drops what inspectPods does not read from the pods kept by the
+// informer: all the annotations but the multus ones, the containers and the
+// status but the phase.
+func trimPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	trimmed := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		},
+		Spec: corev1.PodSpec{
+			NodeName:    pod.Spec.NodeName,
+			HostNetwork: pod.Spec.HostNetwork,
+		},
+		Status: corev1.PodStatus{Phase: pod.Status.Phase},
+	}
+	for _, annotation := range []string{networksAnnotation, networkStatusAnnotation} {
+		if value, ok := pod.Annotations[annotation]; ok {
+			if trimmed.Annotations == nil {
+				trimmed.Annotations = map[string]string{}
+			}
+			trimmed.Annotations[annotation] = value
+		}
+	}
+	return trimmed, nil
+}
+
+func add(mgr manager.Manager, r *ReconcileDHCP) error {
+	c, err := controller.New("dhcp-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Reconcile when the operator configuration changes, which is when networks
+	// that use DHCP may be added or removed. From then on, the reconciler
+	// requeues itself.
+	return c.Watch(source.Kind(mgr.GetCache(), &operv1.Network{}), &handler.EnqueueRequestForObject{},
+		predicate.GenerationChangedPredicate{})
+}
+
+var _ reconcile.Reconciler = &ReconcileDHCP{}
+
+// ReconcileDHCP checks that the DHCP CNI daemon is ready on the nodes that run
+// pods attached to additional networks that use DHCP, and reports the leases
+// held on each node.
+type ReconcileDHCP struct {
+	client      cnoclient.Client
+	status      *statusmanager.StatusManager
+	podInformer cache.SharedIndexInformer
+	podLister   v1corelisters.PodLister
+
+	// unavailable are the nodes on which the daemon was found unavailable at
+	// the previous reconcile. A node is only reported once the daemon has been
+	// unavailable on two consecutive reconciles, so that a rolling update of
+	// the daemon does not flap the Degraded condition.
+	unavailable sets.Set[string]
+}
+
+func (r *ReconcileDHCP) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	if request.Name != names.OPERATOR_CONFIG {
+		return reconcile.Result{}, nil
+	}
+
+	operConfig := &operv1.Network{}
+	err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig)
+	if apierrors.IsNotFound(err) {
+		return reconcile.Result{}, nil
+	} else if err != nil {
+		klog.Errorf("Unable to retrieve Network.operator.openshift.io object: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	dhcpNetworks := sets.New[string](network.DHCPNetworks(&operConfig.Spec)...)
+	if dhcpNetworks.Len() == 0 {
+		resetMetrics()
+		r.unavailable = sets.New[string]()
+		r.status.SetNotDegraded(statusmanager.DHCPDaemon)
+		return reconcile.Result{}, nil
+	}
+
+	pods, err := r.podLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list pods: %v", err)
+		return reconcile.Result{}, err
+	}
+	daemonPods := &corev1.PodList{}
+	if err := r.client.Default().CRClient().List(ctx, daemonPods,
+		crclient.InNamespace(names.MULTUS_NAMESPACE), crclient.MatchingLabels{"app": daemonAppLabel}); err != nil {
+		klog.Errorf("Failed to list DHCP daemon pods: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	nodeLeases, needed := inspectPods(dhcpNetworks, pods)
+	ready := readyNodes(daemonPods.Items)
+
+	resetMetrics()
+	for node, count := range nodeLeases {
+		leases.WithLabelValues(node).Set(float64(count))
+	}
+	for _, pod := range daemonPods.Items {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		value := 0.0
+		if ready.Has(pod.Spec.NodeName) {
+			value = 1
+		}
+		daemonReady.WithLabelValues(pod.Spec.NodeName).Set(value)
+	}
+
+	unavailable := needed.Difference(ready)
+	persistent := sets.List(unavailable.Intersection(r.unavailable))
+	r.unavailable = unavailable
+	if len(persistent) == 0 {
+		r.status.SetNotDegraded(statusmanager.DHCPDaemon)
+		return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+	}
+
+	nodes := strings.Join(persistent, ", ")
+	if len(persistent) > maxNodesInMessage {
+		nodes = strings.Join(persistent[:maxNodesInMessage], ", ") + fmt.Sprintf(" and %d more", len(persistent)-maxNodesInMessage)
+	}
+	r.status.SetDegraded(statusmanager.DHCPDaemon, "DHCPDaemonUnavailable",
+		fmt.Sprintf("The DHCP CNI daemon is not ready on nodes that run pods attached to networks that use DHCP (%s): %s",
+			strings.Join(sets.List(dhcpNetworks), ", "), nodes))
+	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// inspectPods returns, for each node, the number of pod interfaces with an
+// address on a network that uses DHCP, and the nodes that run pods requesting
+// such a network, whether or not they got an address.
+func inspectPods(dhcpNetworks sets.Set[string], pods []*corev1.Pod) (map[string]int, sets.Set[string]) {
+	nodeLeases := map[string]int{}
+	needed := sets.New[string]()
+	for _, pod := range pods {
+		node := pod.Spec.NodeName
+		if node == "" || pod.Spec.HostNetwork || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, name := range requestedNetworks(pod) {
+			if dhcpNetworks.Has(name) {
+				needed.Insert(node)
+				break
+			}
+		}
+		for _, status := range networkStatus(pod) {
+			if dhcpNetworks.Has(status.Name) && len(status.IPs) > 0 {
+				nodeLeases[node]++
+			}
+		}
+	}
+	return nodeLeases, needed
+}
+
+// readyNodes returns the nodes on which a dhcp-daemon pod is ready
+func readyNodes(daemonPods []corev1.Pod) sets.Set[string] {
+	nodes := sets.New[string]()
+	for _, pod := range daemonPods {
+		if pod.Spec.NodeName == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				nodes.Insert(pod.Spec.NodeName)
+			}
+		}
+	}
+	return nodes
+}
+
+// networkSelection is an element of the JSON form of the networks annotation
+type networkSelection struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// requestedNetworks returns the networks, as "namespace/name", that a pod
+// requests with the networks annotation. The annotation is either a JSON list
+// of network selections, or a comma separated list of [namespace/]name[@interface].
+func requestedNetworks(pod *corev1.Pod) []string {
+	annotation := strings.TrimSpace(pod.Annotations[networksAnnotation])
+	if annotation == "" {
+		return nil
+	}
+
+	requested := []string{}
+	if strings.HasPrefix(annotation, "[") {
+		selections := []networkSelection{}
+		if err := json.Unmarshal([]byte(annotation), &selections); err != nil {
+			klog.V(5).Infof("Ignoring invalid %s annotation of pod %s/%s: %v", networksAnnotation, pod.Namespace, pod.Name, err)
+			return nil
+		}
+		for _, selection := range selections {
+			namespace := selection.Namespace
+			if namespace == "" {
+				namespace = pod.Namespace
+			}
+			requested = append(requested, namespace+"/"+selection.Name)
+		}
+		return requested
+	}
+
+	for _, item := range strings.Split(annotation, ",") {
+		item = strings.TrimSpace(item)
+		item, _, _ = strings.Cut(item, "@")
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			item = pod.Namespace + "/" + item
+		}
+		requested = append(requested, item)
+	}
+	return requested
+}
+
+// attachmentStatus is an element of the network status annotation
+type attachmentStatus struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips,omitempty"`
+}
+
+// networkStatus returns the network attachments reported by multus for a pod
+func networkStatus(pod *corev1.Pod) []attachmentStatus {
+	annotation := pod.Annotations[networkStatusAnnotation]
+	if annotation == "" {
+		return nil
+	}
+	statuses := []attachmentStatus{}
+	if err := json.Unmarshal([]byte(annotation), &statuses); err != nil {
+		klog.V(5).Infof("Ignoring invalid %s annotation of pod %s/%s: %v", networkStatusAnnotation, pod.Namespace, pod.Name, err)
+		return nil
+	}
+	return statuses
+}
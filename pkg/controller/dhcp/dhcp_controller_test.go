@@ -0,0 +1,135 @@
+package dhcp
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func pod(namespace, name, node string, annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func daemonPod(node string, ready bool) *corev1.Pod {
+	p := pod(names.MULTUS_NAMESPACE, "dhcp-daemon-"+node, node, nil)
+	p.Labels = map[string]string{"app": daemonAppLabel}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+	return p
+}
+
+func TestRequestedNetworks(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(requestedNetworks(pod("ns", "p", "", nil))).To(BeEmpty())
+	g.Expect(requestedNetworks(pod("ns", "p", "", map[string]string{
+		networksAnnotation: "net1, other/net2@eth1,net3@eth2",
+	}))).To(Equal([]string{"ns/net1", "other/net2", "ns/net3"}))
+	g.Expect(requestedNetworks(pod("ns", "p", "", map[string]string{
+		networksAnnotation: `[{"name": "net1"}, {"name": "net2", "namespace": "other", "interface": "eth1"}]`,
+	}))).To(Equal([]string{"ns/net1", "other/net2"}))
+	g.Expect(requestedNetworks(pod("ns", "p", "", map[string]string{
+		networksAnnotation: `[{"name": `,
+	}))).To(BeEmpty())
+}
+
+func TestInspectPods(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dhcpNetworks := sets.New[string]("ns/dhcp", "default/dhcp")
+	attached := map[string]string{
+		networksAnnotation:      "dhcp,default/dhcp,static",
+		networkStatusAnnotation: `[{"name": "ovn-kubernetes", "ips": ["10.128.0.5"]}, {"name": "ns/dhcp", "ips": ["192.168.1.5"]}, {"name": "default/dhcp", "ips": ["192.168.2.5"]}, {"name": "ns/static", "ips": ["192.168.3.5"]}]`,
+	}
+	pending := map[string]string{networksAnnotation: "dhcp"}
+	completed := pod("ns", "completed", "node-c", attached)
+	completed.Status.Phase = corev1.PodSucceeded
+
+	// The pods are inspected as the informer keeps them
+	pods := []*corev1.Pod{}
+	for _, p := range []*corev1.Pod{
+		pod("ns", "attached", "node-a", attached),
+		pod("ns", "pending", "node-b", pending),
+		pod("ns", "unscheduled", "", pending),
+		pod("ns", "static", "node-d", map[string]string{networksAnnotation: "static"}),
+		completed,
+	} {
+		p.Annotations["unrelated"] = "dropped"
+		p.Spec.Containers = []corev1.Container{{Name: "dropped"}}
+		trimmed, err := trimPod(p)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(trimmed).To(HaveField("Spec.Containers", BeEmpty()))
+		g.Expect(trimmed).To(HaveField("Annotations", Not(HaveKey("unrelated"))))
+		pods = append(pods, trimmed.(*corev1.Pod))
+	}
+
+	nodeLeases, needed := inspectPods(dhcpNetworks, pods)
+	g.Expect(nodeLeases).To(Equal(map[string]int{"node-a": 2}))
+	g.Expect(sets.List(needed)).To(Equal([]string{"node-a", "node-b"}))
+}
+
+func TestReconcile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	operConfig := &operv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG},
+		Spec: operv1.NetworkSpec{
+			AdditionalNetworks: []operv1.AdditionalNetworkDefinition{
+				{Type: operv1.NetworkTypeSimpleMacvlan, Name: "dhcp", Namespace: "ns"},
+			},
+		},
+	}
+	pending := map[string]string{networksAnnotation: "dhcp"}
+	client := fake.NewFakeClient(operConfig, daemonPod("node-a", true), daemonPod("node-b", false))
+	status := statusmanager.New(client, "testing", names.StandAloneClusterName)
+	r := newReconciler(status, client)
+	for _, p := range []*corev1.Pod{pod("ns", "a", "node-a", pending), pod("ns", "b", "node-b", pending)} {
+		g.Expect(r.podInformer.GetIndexer().Add(p)).To(Succeed())
+	}
+
+	degraded := func() *operv1.OperatorCondition {
+		oc := &operv1.Network{}
+		g.Expect(client.Default().CRClient().Get(context.TODO(), types.NamespacedName{Name: names.OPERATOR_CONFIG}, oc)).To(Succeed())
+		return v1helpers.FindOperatorCondition(oc.Status.Conditions, operv1.OperatorStatusTypeDegraded)
+	}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: names.OPERATOR_CONFIG}}
+
+	// the daemon must be unavailable for two reconciles in a row to be reported
+	_, err := r.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(degraded()).To(HaveField("Status", operv1.ConditionFalse))
+
+	_, err = r.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(degraded()).To(HaveField("Status", operv1.ConditionTrue))
+	g.Expect(degraded()).To(HaveField("Reason", "DHCPDaemonUnavailable"))
+	g.Expect(degraded().Message).To(ContainSubstring("(ns/dhcp): node-b"))
+
+	// networks that use DHCP are removed
+	g.Expect(client.Default().CRClient().Get(context.TODO(), types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig)).To(Succeed())
+	operConfig.Spec.AdditionalNetworks = nil
+	g.Expect(client.Default().CRClient().Update(context.TODO(), operConfig)).To(Succeed())
+	_, err = r.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(degraded()).To(HaveField("Status", operv1.ConditionFalse))
+}
@@ -0,0 +1,40 @@
+package dhcp
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	leases = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "network_operator",
+			Subsystem:      "dhcp",
+			Name:           "leases",
+			Help:           "The number of pod interfaces on a node with an address leased through the DHCP CNI daemon.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"node"},
+	)
+	daemonReady = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      "network_operator",
+			Subsystem:      "dhcp",
+			Name:           "daemon_ready",
+			Help:           "Whether the DHCP CNI daemon is ready on a node, that is whether it listens on its socket.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"node"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(leases, daemonReady)
+}
+
+// resetMetrics clears all DHCP metrics, so that nodes that were removed since
+// the last reconcile, or a DHCP daemon that is not in use, are not reported.
+func resetMetrics() {
+	leases.Reset()
+	daemonReady.Reset()
+}
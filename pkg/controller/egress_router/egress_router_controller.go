@@ -11,23 +11,23 @@ import (
 
 	"github.com/openshift/cluster-network-operator/pkg/apply"
 	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/names"
 	"github.com/openshift/cluster-network-operator/pkg/render"
 	"github.com/pkg/errors"
 
 	"path/filepath"
-	"reflect"
-	"strings"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 
 	netopv1 "github.com/openshift/api/networkoperator/v1"
 	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -49,8 +49,9 @@ func Add(mgr manager.Manager, status *statusmanager.StatusManager, cli cnoclient
 		return err
 	}
 
-	// Watch for changes to primary resource EgressRouter.network.operator.openshift.io/v1
-	err = c.Watch(source.Kind(mgr.GetCache(), &netopv1.EgressRouter{}), &handler.EnqueueRequestForObject{})
+	// Watch for changes to primary resource EgressRouter.network.operator.openshift.io/v1,
+	// ignoring the updates of its status
+	err = c.Watch(source.Kind(mgr.GetCache(), &netopv1.EgressRouter{}), &handler.EnqueueRequestForObject{}, egressRouterChangedPredicate)
 	if err != nil {
 		return err
 	}
@@ -58,42 +59,47 @@ func Add(mgr manager.Manager, status *statusmanager.StatusManager, cli cnoclient
 	return nil
 }
 
+// egressRouterChangedPredicate filters out the updates of the status of the routers.
+// The proxy and HA settings are annotations, which do not change the generation.
+var egressRouterChangedPredicate = predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})
+
 var _ reconcile.Reconciler = &EgressRouterReconciler{}
 var manifestDir = "bindata/"
 
-type egressrouter struct {
-	spec netopv1.EgressRouterSpec
-}
-
 type EgressRouterReconciler struct {
-	mgr    manager.Manager
-	client cnoclient.Client
-	status *statusmanager.StatusManager
-
-	egressrouters    map[types.NamespacedName]*egressrouter
-	egressrouterErrs map[types.NamespacedName]error
+	mgr      manager.Manager
+	client   cnoclient.Client
+	status   *statusmanager.StatusManager
+	recorder record.EventRecorder
 }
 
 var ResyncPeriod = 5 * time.Minute
 
+// ProgressingResyncPeriod is how often the status of a router that is not
+// available yet, or still rolling out, is refreshed
+var ProgressingResyncPeriod = 30 * time.Second
+
 func newEgressRouterReconciler(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client) (reconcile.Reconciler, error) {
 
 	return &EgressRouterReconciler{
-		mgr:    mgr,
-		status: status,
-		client: c,
-
-		egressrouters:    map[types.NamespacedName]*egressrouter{},
-		egressrouterErrs: map[types.NamespacedName]error{},
+		mgr:      mgr,
+		status:   status,
+		client:   c,
+		recorder: mgr.GetEventRecorderFor("egress-router-controller"),
 	}, nil
 }
 
-func (r EgressRouterReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+// Reconcile renders the objects of an Egress Router, and reports their state in the
+// conditions of its status. The state of each router is only reported on the router,
+// so that a broken router does not degrade the whole network operator.
+func (r *EgressRouterReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
 	klog.Infof("Reconciling egressrouter.network.operator.openshift.io %s\n", request.NamespacedName)
+	// Clear the Degraded state reported for the routers by earlier versions
+	r.status.SetNotDegraded(statusmanager.EgressRouterConfig)
 
 	obj := &netopv1.EgressRouter{}
-	err := r.mgr.GetClient().Get(ctx, request.NamespacedName, obj)
+	err := r.client.Default().CRClient().Get(ctx, request.NamespacedName, obj)
 
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -104,68 +110,41 @@ func (r EgressRouterReconciler) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, err
 	}
 
-	// Check to see if we already know this object
-	existing := r.egressrouters[request.NamespacedName]
-	if existing != nil {
-		if !reflect.DeepEqual(obj.Spec, existing.spec) {
-			klog.Infof("Egress Router %s has changed, refreshing\n", request.NamespacedName)
-			delete(r.egressrouters, request.NamespacedName)
-			existing = nil
-		}
+	// Set owner reference to the controller
+	boolTrue := bool(true)
+	EgressRouterOwnerReferences := []metav1.OwnerReference{
+		{
+			APIVersion: "network.operator.openshift.io/v1",
+			Kind:       "EgressRouter",
+			Name:       obj.Name,
+			UID:        obj.UID,
+			Controller: &boolTrue,
+		},
 	}
-
-	if existing == nil {
-		klog.Infof("Creating a new Egress Router")
-		// Set owner reference to the controller
-		boolTrue := bool(true)
-		EgressRouterOwnerReferences := []metav1.OwnerReference{
-			{
-				APIVersion: "network.operator.openshift.io/v1",
-				Kind:       "EgressRouter",
-				Name:       obj.Name,
-				UID:        obj.UID,
-				Controller: &boolTrue,
-			},
-		}
-		err := r.ensureEgressRouter(ctx, manifestDir, request.Namespace, obj, EgressRouterOwnerReferences)
-
-		if err != nil {
-			klog.Error(err)
-			r.egressrouterErrs[request.NamespacedName] =
-				errors.Wrapf(err, "could not reconcile Egress Router %s", request.NamespacedName)
-			r.setStatus()
-			return reconcile.Result{}, err
-		}
-
-		r.egressrouters[request.NamespacedName] = existing
+	// A router that does not fit in the cluster is not rendered, so that no broken
+	// pod is created for it
+	applyErr := r.validateEgressRouter(ctx, obj)
+	if applyErr == nil {
+		applyErr = r.ensureEgressRouter(ctx, manifestDir, request.Namespace, obj, EgressRouterOwnerReferences)
+	}
+	if applyErr != nil {
+		klog.Errorf("could not reconcile Egress Router %s: %v", request.NamespacedName, applyErr)
 	}
 
+	settled, err := r.syncEgressRouterStatus(ctx, obj, applyErr)
 	if err != nil {
 		klog.Error(err)
-		r.egressrouterErrs[request.NamespacedName] =
-			errors.Wrapf(err, "could not reconcile Egress Router %s", request.NamespacedName)
-		r.setStatus()
 		return reconcile.Result{}, err
 	}
+	if applyErr != nil {
+		return reconcile.Result{}, errors.Wrapf(applyErr, "could not reconcile Egress Router %s", request.NamespacedName)
+	}
 
 	klog.Infof("successful reconciliation")
-	delete(r.egressrouterErrs, request.NamespacedName)
-	r.setStatus()
-	return reconcile.Result{RequeueAfter: ResyncPeriod}, nil
-}
-
-// setStatus summarizes the status of all Egress Router objects and updates the statusmanager
-// as appropriate.
-func (r *EgressRouterReconciler) setStatus() {
-	if len(r.egressrouterErrs) == 0 {
-		r.status.SetNotDegraded(statusmanager.EgressRouterConfig)
-	} else {
-		msgs := []string{}
-		for _, e := range r.egressrouterErrs {
-			msgs = append(msgs, e.Error())
-		}
-		r.status.SetDegraded(statusmanager.EgressRouterConfig, "EgressRouterError", strings.Join(msgs, ", "))
+	if !settled {
+		return reconcile.Result{RequeueAfter: ProgressingResyncPeriod}, nil
 	}
+	return reconcile.Result{RequeueAfter: ResyncPeriod}, nil
 }
 
 // getAllowedDestinationsConfigJSONi generates AllowedDestinations json config
@@ -194,45 +173,99 @@ func getAllowedDestinationsConfigJSON(RedirectRules []netopv1.L4RedirectRule) (s
 }
 
 func (r *EgressRouterReconciler) ensureEgressRouter(ctx context.Context, manifestDir string, namespace string, router *netopv1.EgressRouter, EgressRouterOwnerReferences []metav1.OwnerReference) error {
+	out, err := renderEgressRouter(manifestDir, namespace, router)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range out {
+		klog.Infof("Assigning owner references")
+		obj.SetOwnerReferences(EgressRouterOwnerReferences)
+		// The rollout of the router is tracked like the one of the other network
+		// workloads, as a non-critical one
+		if obj.GetAPIVersion() == "apps/v1" && obj.GetKind() == "Deployment" {
+			l := obj.GetLabels()
+			l[names.GenerateStatusLabel] = r.status.Cluster()
+			obj.SetLabels(l)
+		}
+		klog.Infof("Applying manifest")
+		if err := apply.ApplyObject(ctx, r.client, obj, "egress_router"); err != nil {
+			klog.Infof("could not apply egress router object: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderEgressRouter renders the NetworkAttachmentDefinition and the Deployment of a router
+func renderEgressRouter(manifestDir string, namespace string, router *netopv1.EgressRouter) ([]*uns.Unstructured, error) {
 	var err error
 	if len(router.Spec.Addresses) == 0 {
-		return fmt.Errorf("Error: router without addresses")
+		return nil, fmt.Errorf("Error: router without addresses")
 	}
-	out := []*uns.Unstructured{}
 	data := render.MakeRenderData()
 	data.Data["ReleaseVersion"] = os.Getenv("RELEASE_VERSION")
 	data.Data["EgressRouterNamespace"] = namespace
 	if isItValidCidr(router.Spec.Addresses[0].IP) {
 		data.Data["Addresses"] = router.Spec.Addresses[0].IP
 	}
+	// The gateway is optional, the plugin determines it when it is not set
+	data.Data["Gateway"] = ""
 	if isItValidIPAddress(router.Spec.Addresses[0].Gateway) {
 		data.Data["Gateway"] = router.Spec.Addresses[0].Gateway
 	}
-	data.Data["AllowedDestinations"], err = getAllowedDestinationsConfigJSON(router.Spec.Redirect.RedirectRules)
+	replicas, err := egressRouterReplicas(router)
+	if err != nil {
+		return nil, err
+	}
+	data.Data["Replicas"] = replicas
+	data.Data["HA"] = replicas > 1
+	data.Data["NetworkOperatorImage"] = os.Getenv("NETWORK_OPERATOR_IMAGE")
+	proxy, err := egressRouterProxyConfig(router)
 	if err != nil {
-		return errors.Wrap(err, "failed to render AllowedDestinations config")
+		return nil, err
+	}
+	// A proxy router sends its own traffic, nothing is redirected by the CNI plugin
+	data.Data["ProxyMode"] = ""
+	data.Data["AllowedDestinations"] = "[]"
+	data.Data["FallbackIP"] = ""
+	data.Data["ProbeTargets"] = []string{}
+	if proxy != nil {
+		data.Data["ProxyMode"] = proxy.Mode
+		data.Data["ProxyDestination"] = proxy.destinationConfig()
+		data.Data["EgressHTTPProxyImage"] = os.Getenv("EGRESS_HTTP_PROXY_IMAGE")
+		data.Data["EgressDNSProxyImage"] = os.Getenv("EGRESS_DNS_PROXY_IMAGE")
+	} else if router.Spec.Redirect != nil {
+		data.Data["AllowedDestinations"], err = getAllowedDestinationsConfigJSON(router.Spec.Redirect.RedirectRules)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render AllowedDestinations config")
+		}
+		data.Data["FallbackIP"] = router.Spec.Redirect.FallbackIP
+		data.Data["ProbeTargets"] = redirectProbeTargets(router.Spec.Redirect.RedirectRules)
 	}
-	data.Data["FallbackIP"] = router.Spec.Redirect.FallbackIP
 	data.Data["mode"] = router.Spec.Mode
 	data.Data["network_interfaces"] = router.Spec.NetworkInterface
 	data.Data["EgressRouterPodImage"] = os.Getenv("EGRESS_ROUTER_CNI_IMAGE")
-	manifests, err := render.RenderDir(filepath.Join(manifestDir, "egress-router"), &data)
-	if err != nil {
-		return err
-	}
-	out = append(out, manifests...)
+	return render.RenderDir(filepath.Join(manifestDir, "egress-router"), &data)
+}
 
-	for _, obj := range out {
-		klog.Infof("Assigning owner references")
-		obj.SetOwnerReferences(EgressRouterOwnerReferences)
-		klog.Infof("Applying manifest")
-		if err := apply.ApplyObject(ctx, r.client, obj, "egress_router"); err != nil {
-			klog.Infof("could not apply egress router object: %v", err)
-			return err
+// redirectProbeTargets returns the <ip>/<port> targets of the TCP redirect rules,
+// which the readiness probe of the router pod dials. The UDP and SCTP targets, and
+// the ones of the rules without a port, cannot be probed by a connection.
+func redirectProbeTargets(rules []netopv1.L4RedirectRule) []string {
+	targets := []string{}
+	for _, rule := range rules {
+		if rule.Protocol != netopv1.ProtocolTypeTCP || rule.Port == 0 || net.ParseIP(rule.DestinationIP) == nil {
+			continue
 		}
+		port := rule.Port
+		if rule.TargetPort != 0 {
+			port = rule.TargetPort
+		}
+		targets = append(targets, fmt.Sprintf("%s/%d", rule.DestinationIP, port))
 	}
-
-	return nil
+	return targets
 }
 
 func isItValidCidr(cidr string) bool {
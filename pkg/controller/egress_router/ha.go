@@ -0,0 +1,104 @@
+package egress_router
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// egressRouterReplicas returns the number of replicas of a router, from the
+// EgressRouterReplicasAnnotation. A router with more than one replica runs in HA
+// mode, where the failover helper of the replicas only lets the leader answer ARP
+// for the egress address, which therefore has to be an IPv4 address.
+func egressRouterReplicas(router *netopv1.EgressRouter) (int, error) {
+	value, ok := router.Annotations[names.EgressRouterReplicasAnnotation]
+	if !ok {
+		return 1, nil
+	}
+	replicas, err := strconv.Atoi(value)
+	if err != nil || replicas < 1 {
+		return 0, fmt.Errorf("invalid %s annotation %q, must be a positive number", names.EgressRouterReplicasAnnotation, value)
+	}
+	if replicas > 1 {
+		for _, address := range router.Spec.Addresses {
+			ip, _, err := net.ParseCIDR(address.IP)
+			if err == nil && ip.To4() == nil {
+				return 0, fmt.Errorf("an HA egress router only supports IPv4 addresses, %s is not one", address.IP)
+			}
+		}
+	}
+	return replicas, nil
+}
+
+// egressRouterIPs returns the IPs of the addresses of a router
+func egressRouterIPs(router *netopv1.EgressRouter) []net.IP {
+	ips := []net.IP{}
+	for _, address := range router.Spec.Addresses {
+		if ip, _, err := net.ParseCIDR(address.IP); err == nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// claimsAddressesFirst tells whether a router was created before another one, and
+// therefore keeps the addresses they share. Routers created at the same time are
+// ordered by namespace.
+func claimsAddressesFirst(router, other *netopv1.EgressRouter) bool {
+	if !router.CreationTimestamp.Equal(&other.CreationTimestamp) {
+		return router.CreationTimestamp.Before(&other.CreationTimestamp)
+	}
+	return router.Namespace+"/"+router.Name < other.Namespace+"/"+other.Name
+}
+
+// egressRouterAddressConflicts checks that the addresses of a router are not
+// the addresses of a node, nor of an EgressRouter created before it.
+func egressRouterAddressConflicts(router *netopv1.EgressRouter, routers []netopv1.EgressRouter, nodes []corev1.Node) error {
+	for _, ip := range egressRouterIPs(router) {
+		for i := range routers {
+			other := &routers[i]
+			if other.UID == router.UID || !claimsAddressesFirst(other, router) {
+				continue
+			}
+			for _, otherIP := range egressRouterIPs(other) {
+				if ip.Equal(otherIP) {
+					return fmt.Errorf("address %s is already used by EgressRouter %s/%s", ip, other.Namespace, other.Name)
+				}
+			}
+		}
+		for _, node := range nodes {
+			for _, address := range node.Status.Addresses {
+				if ip.Equal(net.ParseIP(address.Address)) {
+					return fmt.Errorf("address %s is already used by node %s", ip, node.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateEgressRouterAddresses checks that the addresses of a router are not used
+// elsewhere in the cluster, and returns an invalidEgressRouterError when they are.
+// The replicas of an HA router share its addresses, which would otherwise move
+// between the router and whatever else uses them.
+func (r *EgressRouterReconciler) validateEgressRouterAddresses(ctx context.Context, router *netopv1.EgressRouter) error {
+	crclient := r.client.Default().CRClient()
+	routers := &netopv1.EgressRouterList{}
+	if err := crclient.List(ctx, routers); err != nil {
+		return fmt.Errorf("could not list the egress routers: %w", err)
+	}
+	nodes := &corev1.NodeList{}
+	if err := crclient.List(ctx, nodes); err != nil {
+		return fmt.Errorf("could not list the nodes: %w", err)
+	}
+	if err := egressRouterAddressConflicts(router, routers.Items, nodes.Items); err != nil {
+		return &invalidEgressRouterError{problems: []string{err.Error()}}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package egress_router
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	securityv1 "github.com/openshift/api/security/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+func TestEgressRouterReplicas(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter()
+	replicas, err := egressRouterReplicas(router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(replicas).To(Equal(1))
+
+	router.Annotations = map[string]string{names.EgressRouterReplicasAnnotation: "3"}
+	replicas, err = egressRouterReplicas(router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(replicas).To(Equal(3))
+
+	for _, invalid := range []string{"0", "-1", "two"} {
+		router.Annotations[names.EgressRouterReplicasAnnotation] = invalid
+		_, err = egressRouterReplicas(router)
+		g.Expect(err).To(MatchError(ContainSubstring("must be a positive number")), invalid)
+	}
+
+	router.Annotations[names.EgressRouterReplicasAnnotation] = "2"
+	router.Spec.Addresses[0].IP = "fd00::99/64"
+	_, err = egressRouterReplicas(router)
+	g.Expect(err).To(MatchError(ContainSubstring("only supports IPv4 addresses")))
+}
+
+func TestEgressRouterAddressConflicts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now()
+	router := egressRouter()
+	router.UID = "router"
+	router.CreationTimestamp = metav1.NewTime(now)
+
+	older := egressRouter()
+	older.Namespace = "older"
+	older.UID = "older"
+	older.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+	older.Spec.Addresses[0].IP = "192.168.12.99/32"
+
+	newer := older.DeepCopy()
+	newer.Namespace = "newer"
+	newer.UID = "newer"
+	newer.CreationTimestamp = metav1.NewTime(now.Add(time.Hour))
+
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-0"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "192.168.12.10"},
+		}},
+	}
+
+	// The router created first keeps the address
+	g.Expect(egressRouterAddressConflicts(router, []netopv1.EgressRouter{*router, *newer}, []corev1.Node{node})).To(Succeed())
+	g.Expect(egressRouterAddressConflicts(router, []netopv1.EgressRouter{*router, *older}, nil)).To(
+		MatchError("address 192.168.12.99 is already used by EgressRouter older/router"))
+
+	node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "192.168.12.99"})
+	g.Expect(egressRouterAddressConflicts(router, nil, []corev1.Node{node})).To(
+		MatchError("address 192.168.12.99 is already used by node worker-0"))
+
+	client := fake.NewFakeClient(router, older, &node)
+	r := &EgressRouterReconciler{client: client}
+	g.Expect(r.validateEgressRouterAddresses(context.TODO(), router)).To(
+		MatchError(ContainSubstring("already used by")))
+}
+
+func TestRenderEgressRouterHA(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter()
+	router.Annotations = map[string]string{names.EgressRouterReplicasAnnotation: "2"}
+	objs, err := renderEgressRouter("../../../bindata", "egress", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	kinds := []string{}
+	for _, obj := range objs {
+		kinds = append(kinds, obj.GetKind())
+	}
+	g.Expect(kinds).To(Equal([]string{"ServiceAccount", "Role", "RoleBinding", "NetworkAttachmentDefinition", "Deployment", "PodDisruptionBudget"}))
+
+	deployment := objs[4]
+	// The rollout is tracked by the operator, but cannot degrade it
+	g.Expect(deployment.GetAnnotations()).To(HaveKey(names.NonCriticalAnnotation))
+	replicas, _, _ := uns.NestedInt64(deployment.Object, "spec", "replicas")
+	g.Expect(replicas).To(BeEquivalentTo(2))
+	topologyKey, _, _ := uns.NestedSlice(deployment.Object, "spec", "template", "spec", "affinity", "podAntiAffinity", "requiredDuringSchedulingIgnoredDuringExecution")
+	g.Expect(topologyKey).To(HaveLen(1))
+	g.Expect(topologyKey[0]).To(HaveKeyWithValue("topologyKey", "kubernetes.io/hostname"))
+
+	containers, _, _ := uns.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	g.Expect(containers).To(HaveLen(2))
+	failover := containers[1].(map[string]interface{})
+	g.Expect(failover["name"]).To(Equal("egress-router-failover"))
+	g.Expect(failover["command"]).To(ContainElement("--address=192.168.12.99/24"))
+
+	// A single replica router has no failover
+	router.Annotations = nil
+	objs, err = renderEgressRouter("../../../bindata", "egress", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+	_, found, _ := uns.NestedFieldNoCopy(objs[1].Object, "spec", "template", "spec", "affinity")
+	g.Expect(found).To(BeFalse())
+}
+
+// sccViolations checks a pod against the settings of an SCC that the pods of the
+// egress routers could request, as the SCC admission plugin would.
+func sccViolations(scc *securityv1.SecurityContextConstraints, pod *corev1.PodSpec) []string {
+	violations := []string{}
+	if pod.HostNetwork && !scc.AllowHostNetwork {
+		violations = append(violations, "host network")
+	}
+	if (pod.HostPID && !scc.AllowHostPID) || (pod.HostIPC && !scc.AllowHostIPC) {
+		violations = append(violations, "host PID or IPC")
+	}
+	volumes := sets.New[securityv1.FSType](scc.Volumes...)
+	for _, volume := range pod.Volumes {
+		if volume.HostPath != nil && !scc.AllowHostDirVolumePlugin {
+			violations = append(violations, "hostPath volume "+volume.Name)
+		}
+		if volume.ConfigMap != nil && !volumes.Has(securityv1.FSTypeConfigMap) {
+			violations = append(violations, "configMap volume "+volume.Name)
+		}
+	}
+	allowed := sets.New[corev1.Capability](scc.AllowedCapabilities...)
+	for _, container := range pod.Containers {
+		sc := container.SecurityContext
+		if sc == nil {
+			continue
+		}
+		if sc.Privileged != nil && *sc.Privileged && !scc.AllowPrivilegedContainer {
+			violations = append(violations, container.Name+": privileged")
+		}
+		if sc.AllowPrivilegeEscalation != nil && *sc.AllowPrivilegeEscalation &&
+			scc.AllowPrivilegeEscalation != nil && !*scc.AllowPrivilegeEscalation {
+			violations = append(violations, container.Name+": privilege escalation")
+		}
+		if scc.RunAsUser.Type != securityv1.RunAsUserStrategyRunAsAny &&
+			(sc.RunAsNonRoot == nil || !*sc.RunAsNonRoot) && (sc.RunAsUser == nil || *sc.RunAsUser == 0) {
+			violations = append(violations, container.Name+": root user")
+		}
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				if !allowed.Has(capability) {
+					violations = append(violations, fmt.Sprintf("%s: capability %s", container.Name, capability))
+				}
+			}
+		}
+	}
+	return violations
+}
+
+func TestEgressRouterFailoverAdmission(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	data, err := os.ReadFile("../../../manifests/0000_70_cluster-network-operator_02_egress-router-scc.yaml")
+	g.Expect(err).NotTo(HaveOccurred())
+	scc := &securityv1.SecurityContextConstraints{}
+	g.Expect(yaml.UnmarshalStrict(data, scc)).To(Succeed())
+
+	router := egressRouter()
+	router.Annotations = map[string]string{names.EgressRouterReplicasAnnotation: "2"}
+	objs, err := renderEgressRouter("../../../bindata", "egress", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	role := &rbacv1.Role{}
+	binding := &rbacv1.RoleBinding{}
+	deployment := &appsv1.Deployment{}
+	for obj, typed := range map[*uns.Unstructured]interface{}{objs[1]: role, objs[2]: binding, objs[4]: deployment} {
+		g.Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed)).To(Succeed())
+	}
+	pod := &deployment.Spec.Template.Spec
+
+	// The ServiceAccount of the pods may use the SCC...
+	g.Expect(binding.RoleRef).To(Equal(rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: role.Name}))
+	g.Expect(binding.Subjects).To(ContainElement(rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: pod.ServiceAccountName, Namespace: "egress"}))
+	g.Expect(role.Rules).To(ContainElement(rbacv1.PolicyRule{
+		APIGroups:     []string{securityv1.GroupName},
+		Resources:     []string{"securitycontextconstraints"},
+		ResourceNames: []string{scc.Name},
+		Verbs:         []string{"use"},
+	}))
+
+	// ... which admits them, unlike restricted-v2
+	g.Expect(sccViolations(scc, pod)).To(BeEmpty())
+	restricted := scc.DeepCopy()
+	restricted.AllowedCapabilities = []corev1.Capability{"NET_BIND_SERVICE"}
+	g.Expect(sccViolations(restricted, pod)).To(ConsistOf(
+		"egress-router-failover: capability NET_ADMIN", "egress-router-failover: capability NET_RAW"))
+
+	// As the ServiceAccount can be reused by any pod of the namespace, the SCC
+	// must not admit root pods
+	g.Expect(scc.RunAsUser.Type).To(Equal(securityv1.RunAsUserStrategyMustRunAsRange))
+	g.Expect(scc.AllowPrivilegeEscalation).To(HaveValue(BeFalse()))
+	root := pod.DeepCopy()
+	root.Containers[len(root.Containers)-1].SecurityContext.RunAsNonRoot = nil
+	g.Expect(sccViolations(scc, root)).To(ConsistOf("egress-router-failover: root user"))
+}
@@ -0,0 +1,163 @@
+package egress_router
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// The proxy modes of an EgressRouter, set with the EgressRouterProxyModeAnnotation.
+// The API only knows the Redirect mode, that proxy routers keep in their spec.
+const (
+	// egressRouterModeHTTPProxy runs an HTTP proxy that only lets the allowed destinations through
+	egressRouterModeHTTPProxy = "HTTPProxy"
+	// egressRouterModeDNSProxy forwards TCP ports of the router to hosts resolved by name
+	egressRouterModeDNSProxy = "DNSProxy"
+)
+
+// egressRouterProxy is the configuration of the proxy sidecar of an EgressRouter
+type egressRouterProxy struct {
+	Mode  string
+	Allow []string
+	Deny  []string
+}
+
+// proxyList splits an annotation value on newlines and commas
+func proxyList(value string) []string {
+	out := []string{}
+	for _, line := range strings.FieldsFunc(value, func(r rune) bool { return r == '\n' || r == ',' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// egressRouterProxyConfig returns the validated proxy configuration of a router,
+// or nil if it is a Redirect router.
+func egressRouterProxyConfig(router *netopv1.EgressRouter) (*egressRouterProxy, error) {
+	mode, ok := router.Annotations[names.EgressRouterProxyModeAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	proxy := &egressRouterProxy{
+		Mode:  mode,
+		Allow: proxyList(router.Annotations[names.EgressRouterProxyAllowAnnotation]),
+		Deny:  proxyList(router.Annotations[names.EgressRouterProxyDenyAnnotation]),
+	}
+	if router.Spec.Redirect != nil && (len(router.Spec.Redirect.RedirectRules) > 0 || router.Spec.Redirect.FallbackIP != "") {
+		return nil, fmt.Errorf("a %s egress router cannot have redirect rules or a fallback IP", mode)
+	}
+	if len(proxy.Allow) == 0 {
+		return nil, fmt.Errorf("a %s egress router needs destinations in the %s annotation", mode, names.EgressRouterProxyAllowAnnotation)
+	}
+
+	switch mode {
+	case egressRouterModeHTTPProxy:
+		for _, destination := range append(append([]string{}, proxy.Allow...), proxy.Deny...) {
+			if err := validateHTTPProxyDestination(destination); err != nil {
+				return nil, err
+			}
+		}
+	case egressRouterModeDNSProxy:
+		if len(proxy.Deny) > 0 {
+			return nil, fmt.Errorf("a %s egress router only forwards the allowed destinations, remove the %s annotation",
+				mode, names.EgressRouterProxyDenyAnnotation)
+		}
+		for _, destination := range proxy.Allow {
+			if err := validateDNSProxyDestination(destination); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s annotation %q, must be one of %s or %s",
+			names.EgressRouterProxyModeAnnotation, mode, egressRouterModeHTTPProxy, egressRouterModeDNSProxy)
+	}
+	return proxy, nil
+}
+
+// validateHost checks that a host is an IP address or a DNS name
+func validateHost(host string) error {
+	if isItValidIPAddress(host) {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(strings.ToLower(host)); len(errs) > 0 {
+		return fmt.Errorf("invalid host %q: %s", host, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// validatePort checks that a port is between 1 and 65535, like the ports of the redirect rules
+func validatePort(port string) error {
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port %q, must be between 1 and 65535", port)
+	}
+	return nil
+}
+
+// validateHTTPProxyDestination checks an allowed or denied destination of an HTTP proxy:
+// "*" for all, a CIDR, an IP, a domain, or the subdomains of a domain as "*.<domain>".
+func validateHTTPProxyDestination(destination string) error {
+	switch {
+	case destination == "*":
+		return nil
+	case strings.Contains(destination, "/"):
+		if _, _, err := net.ParseCIDR(destination); err != nil {
+			return fmt.Errorf("invalid HTTP proxy destination %q: %v", destination, err)
+		}
+		return nil
+	}
+	if err := validateHost(strings.TrimPrefix(destination, "*.")); err != nil {
+		return fmt.Errorf("invalid HTTP proxy destination: %v", err)
+	}
+	return nil
+}
+
+// validateDNSProxyDestination checks a destination of a DNS proxy, "<port> <host> [<target port>]"
+func validateDNSProxyDestination(destination string) error {
+	fields := strings.Fields(destination)
+	if len(fields) < 2 || len(fields) > 3 {
+		return fmt.Errorf("invalid DNS proxy destination %q, must be \"<port> <host> [<target port>]\"", destination)
+	}
+	for i, field := range fields {
+		var err error
+		if i == 1 {
+			err = validateHost(field)
+		} else {
+			err = validatePort(field)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid DNS proxy destination %q: %v", destination, err)
+		}
+	}
+	return nil
+}
+
+// destinationConfig renders the destinations in the format of the proxy image.
+// The first matching line applies, so the denied destinations of an HTTP proxy
+// are listed first with a "!", and "*" last.
+func (p *egressRouterProxy) destinationConfig() string {
+	lines := []string{}
+	for _, destination := range p.Deny {
+		lines = append(lines, "!"+destination)
+	}
+	all := false
+	for _, destination := range p.Allow {
+		if destination == "*" {
+			all = true
+			continue
+		}
+		lines = append(lines, destination)
+	}
+	if all {
+		lines = append(lines, "*")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,112 @@
+package egress_router
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestEgressRouterProxyConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter()
+	proxy, err := egressRouterProxyConfig(router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(proxy).To(BeNil())
+
+	router.Annotations = map[string]string{
+		names.EgressRouterProxyModeAnnotation:  egressRouterModeHTTPProxy,
+		names.EgressRouterProxyAllowAnnotation: "*\n*.example.com, 10.0.0.0/8",
+		names.EgressRouterProxyDenyAnnotation:  "blocked.example.com",
+	}
+	proxy, err = egressRouterProxyConfig(router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(proxy.destinationConfig()).To(Equal("!blocked.example.com\n*.example.com\n10.0.0.0/8\n*\n"))
+
+	for _, invalid := range []string{"10.0.0.0/33", "under_score.example.com", "*.-bad-"} {
+		router.Annotations[names.EgressRouterProxyDenyAnnotation] = invalid
+		_, err = egressRouterProxyConfig(router)
+		g.Expect(err).To(HaveOccurred(), invalid)
+	}
+
+	router.Annotations = map[string]string{
+		names.EgressRouterProxyModeAnnotation:  egressRouterModeDNSProxy,
+		names.EgressRouterProxyAllowAnnotation: "80 172.16.12.11\n8080 www.example.com 80",
+	}
+	proxy, err = egressRouterProxyConfig(router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(proxy.destinationConfig()).To(Equal("80 172.16.12.11\n8080 www.example.com 80\n"))
+
+	for _, invalid := range []string{"www.example.com", "0 www.example.com", "80 www.example.com 70000", "80 -bad-"} {
+		router.Annotations[names.EgressRouterProxyAllowAnnotation] = invalid
+		_, err = egressRouterProxyConfig(router)
+		g.Expect(err).To(HaveOccurred(), invalid)
+	}
+
+	router.Annotations[names.EgressRouterProxyAllowAnnotation] = "80 www.example.com"
+	router.Annotations[names.EgressRouterProxyDenyAnnotation] = "other.example.com"
+	_, err = egressRouterProxyConfig(router)
+	g.Expect(err).To(MatchError(ContainSubstring("only forwards the allowed destinations")))
+
+	router.Annotations = map[string]string{names.EgressRouterProxyModeAnnotation: "SOCKSProxy", names.EgressRouterProxyAllowAnnotation: "*"}
+	_, err = egressRouterProxyConfig(router)
+	g.Expect(err).To(MatchError(ContainSubstring("must be one of HTTPProxy or DNSProxy")))
+
+	router.Annotations = map[string]string{names.EgressRouterProxyModeAnnotation: egressRouterModeHTTPProxy}
+	_, err = egressRouterProxyConfig(router)
+	g.Expect(err).To(MatchError(ContainSubstring("needs destinations")))
+}
+
+func TestRenderEgressRouterProxy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter()
+	router.Annotations = map[string]string{
+		names.EgressRouterProxyModeAnnotation:  egressRouterModeHTTPProxy,
+		names.EgressRouterProxyAllowAnnotation: "www.example.com",
+	}
+	objs, err := renderEgressRouter("../../../bindata", "egress", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+
+	config, _, _ := uns.NestedString(objs[0].Object, "spec", "config")
+	g.Expect(config).To(ContainSubstring(`"destinations": []`))
+
+	containers, _, _ := uns.NestedSlice(objs[1].Object, "spec", "template", "spec", "containers")
+	g.Expect(containers).To(HaveLen(2))
+	sidecar := containers[1].(map[string]interface{})
+	g.Expect(sidecar["name"]).To(Equal("egress-router-http-proxy"))
+	g.Expect(sidecar["env"]).To(Equal([]interface{}{map[string]interface{}{
+		"name":  "EGRESS_HTTP_PROXY_DESTINATION",
+		"value": "www.example.com\n",
+	}}))
+
+	// A Redirect router has no sidecar
+	router.Annotations = nil
+	objs, err = renderEgressRouter("../../../bindata", "egress", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	containers, _, _ = uns.NestedSlice(objs[1].Object, "spec", "template", "spec", "containers")
+	g.Expect(containers).To(HaveLen(1))
+}
+
+func TestEgressRouterChangedPredicate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter()
+	router.Generation = 1
+	updated := router.DeepCopy()
+	updated.Status.Conditions = []netopv1.EgressRouterStatusCondition{{Type: netopv1.EgressRouterAvailable, Status: netopv1.ConditionTrue}}
+	g.Expect(egressRouterChangedPredicate.Update(event.UpdateEvent{ObjectOld: router, ObjectNew: updated})).To(BeFalse())
+
+	updated.Annotations = map[string]string{names.EgressRouterProxyModeAnnotation: egressRouterModeDNSProxy}
+	g.Expect(egressRouterChangedPredicate.Update(event.UpdateEvent{ObjectOld: router, ObjectNew: updated})).To(BeTrue())
+
+	updated = router.DeepCopy()
+	updated.Generation = 2
+	g.Expect(egressRouterChangedPredicate.Update(event.UpdateEvent{ObjectOld: router, ObjectNew: updated})).To(BeTrue())
+}
@@ -0,0 +1,194 @@
+package egress_router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// egressRouterDeploymentName and egressRouterNADName are the names of the
+	// objects rendered from bindata/egress-router
+	egressRouterDeploymentName = "egress-router-cni-deployment"
+	egressRouterNADName        = "egress-router-cni-nad"
+	// egressRouterContainerName is the container of the router pods whose readiness
+	// probe dials the redirect targets
+	egressRouterContainerName = "egress-router-cni-pod"
+	// egressRouterProbeGrace is how long a router container may run before its
+	// readiness probe is expected to pass
+	egressRouterProbeGrace = 2 * time.Minute
+)
+
+var nadGVK = schema.GroupVersionKind{Group: "k8s.cni.cncf.io", Version: "v1", Kind: "NetworkAttachmentDefinition"}
+
+// egressRouterConditions derives the conditions of a router from its rendered
+// Deployment and NetworkAttachmentDefinition, either of which is nil when
+// missing, from its pods and from the error of the last apply of its manifests.
+// Only the router pods reach the redirect targets through the egress interface,
+// so they probe them themselves, and a pod that has been running for a while
+// without being ready cannot reach them.
+func egressRouterConditions(deployment *appsv1.Deployment, pods []corev1.Pod, nad *uns.Unstructured, applyErr error, now time.Time) []netopv1.EgressRouterStatusCondition {
+	available := netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterAvailable, Status: netopv1.ConditionTrue, Reason: "RouterAvailable"}
+	progressing := netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterProgressing, Status: netopv1.ConditionFalse, Reason: "RouterDeployed"}
+	degraded := netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterDegraded, Status: netopv1.ConditionFalse, Reason: "AsExpected"}
+
+	switch {
+	case nad == nil:
+		available.Status = netopv1.ConditionFalse
+		available.Reason = "NetworkAttachmentDefinitionMissing"
+		available.Message = fmt.Sprintf("NetworkAttachmentDefinition %s does not exist", egressRouterNADName)
+	case deployment == nil:
+		available.Status = netopv1.ConditionFalse
+		available.Reason = "DeploymentMissing"
+		available.Message = fmt.Sprintf("Deployment %s does not exist", egressRouterDeploymentName)
+	case deployment.Status.AvailableReplicas == 0:
+		available.Status = netopv1.ConditionFalse
+		available.Reason = "PodNotAvailable"
+		available.Message = fmt.Sprintf("Deployment %s has no available pod", egressRouterDeploymentName)
+	}
+
+	if deployment != nil {
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		if deployment.Status.ObservedGeneration < deployment.Generation || deployment.Status.UpdatedReplicas < replicas ||
+			deployment.Status.Replicas > deployment.Status.UpdatedReplicas {
+			progressing.Status = netopv1.ConditionTrue
+			progressing.Reason = "Deploying"
+			progressing.Message = fmt.Sprintf("Deployment %s is rolling out, %d of %d pods updated",
+				egressRouterDeploymentName, deployment.Status.UpdatedReplicas, replicas)
+		}
+	}
+
+	var problems []string
+	degradedReason := ""
+	var invalid *invalidEgressRouterError
+	if errors.As(applyErr, &invalid) {
+		degradedReason = "InvalidSpec"
+		problems = append(problems, invalid.problems...)
+	} else if applyErr != nil {
+		degradedReason = "ApplyFailed"
+		problems = append(problems, fmt.Sprintf("could not apply the router manifests: %v", applyErr))
+	}
+	if deployment != nil {
+		for _, cond := range deployment.Status.Conditions {
+			failed := (cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == corev1.ConditionTrue) ||
+				(cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse)
+			if failed {
+				if degradedReason == "" {
+					degradedReason = "DeploymentFailed"
+				}
+				problems = append(problems, fmt.Sprintf("Deployment %s: %s", egressRouterDeploymentName, cond.Message))
+			}
+		}
+	}
+	for _, pod := range pods {
+		for _, container := range pod.Status.ContainerStatuses {
+			if container.Name != egressRouterContainerName || container.Ready || container.State.Running == nil ||
+				now.Sub(container.State.Running.StartedAt.Time) < egressRouterProbeGrace {
+				continue
+			}
+			if degradedReason == "" {
+				degradedReason = "RedirectTargetUnreachable"
+			}
+			problems = append(problems, fmt.Sprintf("pod %s cannot reach its redirect targets", pod.Name))
+		}
+	}
+	if len(problems) > 0 {
+		degraded.Status = netopv1.ConditionTrue
+		degraded.Reason = degradedReason
+		degraded.Message = strings.Join(problems, "; ")
+	}
+
+	return []netopv1.EgressRouterStatusCondition{available, progressing, degraded}
+}
+
+// setEgressRouterConditions merges the conditions into the current ones,
+// keeping the transition time of the conditions whose status did not change.
+func setEgressRouterConditions(current []netopv1.EgressRouterStatusCondition, conditions []netopv1.EgressRouterStatusCondition, now metav1.Time) []netopv1.EgressRouterStatusCondition {
+	out := []netopv1.EgressRouterStatusCondition{}
+	for _, cond := range conditions {
+		cond.LastTransitionTime = now
+		for _, existing := range current {
+			if existing.Type == cond.Type && existing.Status == cond.Status {
+				cond.LastTransitionTime = existing.LastTransitionTime
+			}
+		}
+		out = append(out, cond)
+	}
+	return out
+}
+
+// syncEgressRouterStatus writes the conditions of a router to its status, from
+// the objects rendered for it. It returns whether the router is settled, that
+// is available and not progressing.
+func (r *EgressRouterReconciler) syncEgressRouterStatus(ctx context.Context, router *netopv1.EgressRouter, applyErr error) (bool, error) {
+	crclient := r.client.Default().CRClient()
+
+	var deployment *appsv1.Deployment
+	d := &appsv1.Deployment{}
+	err := crclient.Get(ctx, types.NamespacedName{Namespace: router.Namespace, Name: egressRouterDeploymentName}, d)
+	if err == nil {
+		deployment = d
+	} else if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	var nad *uns.Unstructured
+	n := &uns.Unstructured{}
+	n.SetGroupVersionKind(nadGVK)
+	err = crclient.Get(ctx, types.NamespacedName{Namespace: router.Namespace, Name: egressRouterNADName}, n)
+	if err == nil {
+		nad = n
+	} else if !apierrors.IsNotFound(err) && !apierrors.IsGone(err) {
+		return false, err
+	}
+
+	pods := &corev1.PodList{}
+	if err := crclient.List(ctx, pods, client.InNamespace(router.Namespace), client.MatchingLabels{"app": "egress-router-cni"}); err != nil {
+		return false, err
+	}
+
+	now := metav1.Now()
+	conditions := egressRouterConditions(deployment, pods.Items, nad, applyErr, now.Time)
+	// An invalid router does not change until its spec does, which triggers a reconcile
+	settled := (conditions[0].Status == netopv1.ConditionTrue && conditions[1].Status == netopv1.ConditionFalse) ||
+		conditions[2].Reason == "InvalidSpec"
+	updated := router.DeepCopy()
+	updated.Status.Conditions = setEgressRouterConditions(router.Status.Conditions, conditions, now)
+	if reflect.DeepEqual(router.Status, updated.Status) {
+		return settled, nil
+	}
+	if degraded := conditions[2]; degraded.Status == netopv1.ConditionTrue {
+		changed := true
+		for _, cond := range router.Status.Conditions {
+			if cond.Type == degraded.Type && cond.Status == degraded.Status && cond.Message == degraded.Message {
+				changed = false
+			}
+		}
+		if changed {
+			r.recorder.Event(router, corev1.EventTypeWarning, degraded.Reason, degraded.Message)
+		}
+	}
+	klog.Infof("Updating the status of Egress Router %s/%s", router.Namespace, router.Name)
+	if err := crclient.Status().Update(ctx, updated); err != nil {
+		return false, fmt.Errorf("failed to update the status of Egress Router %s/%s: %w", router.Namespace, router.Name, err)
+	}
+	return settled, nil
+}
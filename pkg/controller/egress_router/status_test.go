@@ -0,0 +1,170 @@
+package egress_router
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func egressRouter(rules ...netopv1.L4RedirectRule) *netopv1.EgressRouter {
+	return &netopv1.EgressRouter{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "egress", Name: "router"},
+		Spec: netopv1.EgressRouterSpec{
+			Mode:      netopv1.EgressRouterModeRedirect,
+			Redirect:  &netopv1.RedirectConfig{RedirectRules: rules},
+			Addresses: []netopv1.EgressRouterAddress{{IP: "192.168.12.99/24", Gateway: "192.168.12.1"}},
+		},
+	}
+}
+
+func routerDeployment(available, updated int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "egress", Name: egressRouterDeploymentName, Generation: 1},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			Replicas:           1,
+			UpdatedReplicas:    updated,
+			AvailableReplicas:  available,
+		},
+	}
+}
+
+func routerNAD() *uns.Unstructured {
+	nad := &uns.Unstructured{}
+	nad.SetGroupVersionKind(nadGVK)
+	nad.SetNamespace("egress")
+	nad.SetName(egressRouterNADName)
+	return nad
+}
+
+// conditionSummary maps each condition type to its status and reason
+func conditionSummary(conditions []netopv1.EgressRouterStatusCondition) map[netopv1.EgressRouterStatusConditionType]string {
+	out := map[netopv1.EgressRouterStatusConditionType]string{}
+	for _, cond := range conditions {
+		out[cond.Type] = string(cond.Status) + "/" + cond.Reason
+	}
+	return out
+}
+
+func TestEgressRouterConditions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(conditionSummary(egressRouterConditions(routerDeployment(1, 1), nil, routerNAD(), nil, time.Now()))).To(Equal(
+		map[netopv1.EgressRouterStatusConditionType]string{
+			netopv1.EgressRouterAvailable:   "True/RouterAvailable",
+			netopv1.EgressRouterProgressing: "False/RouterDeployed",
+			netopv1.EgressRouterDegraded:    "False/AsExpected",
+		}))
+
+	g.Expect(conditionSummary(egressRouterConditions(nil, nil, nil, fmt.Errorf("forbidden"), time.Now()))).To(Equal(
+		map[netopv1.EgressRouterStatusConditionType]string{
+			netopv1.EgressRouterAvailable:   "False/NetworkAttachmentDefinitionMissing",
+			netopv1.EgressRouterProgressing: "False/RouterDeployed",
+			netopv1.EgressRouterDegraded:    "True/ApplyFailed",
+		}))
+
+	g.Expect(conditionSummary(egressRouterConditions(routerDeployment(0, 0), nil, routerNAD(), nil, time.Now()))).To(Equal(
+		map[netopv1.EgressRouterStatusConditionType]string{
+			netopv1.EgressRouterAvailable:   "False/PodNotAvailable",
+			netopv1.EgressRouterProgressing: "True/Deploying",
+			netopv1.EgressRouterDegraded:    "False/AsExpected",
+		}))
+
+	failing := routerDeployment(0, 1)
+	failing.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type:    appsv1.DeploymentReplicaFailure,
+		Status:  corev1.ConditionTrue,
+		Message: "pods are forbidden",
+	}}
+	conditions := egressRouterConditions(failing, nil, routerNAD(), nil, time.Now())
+	g.Expect(conditions[2].Reason).To(Equal("DeploymentFailed"))
+	g.Expect(conditions[2].Message).To(ContainSubstring("pods are forbidden"))
+
+	// A pod whose probe of the redirect targets keeps failing degrades the router,
+	// once its container had the time to pass the probe
+	now := time.Now()
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "egress", Name: "router-pod"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  egressRouterContainerName,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(now.Add(-time.Minute))}},
+		}}},
+	}
+	g.Expect(conditionSummary(egressRouterConditions(routerDeployment(1, 1), []corev1.Pod{pod}, routerNAD(), nil, now))).To(
+		HaveKeyWithValue(netopv1.EgressRouterDegraded, "False/AsExpected"))
+	conditions = egressRouterConditions(routerDeployment(1, 1), []corev1.Pod{pod}, routerNAD(), nil, now.Add(egressRouterProbeGrace))
+	g.Expect(conditions[2].Reason).To(Equal("RedirectTargetUnreachable"))
+	g.Expect(conditions[2].Message).To(Equal("pod router-pod cannot reach its redirect targets"))
+	pod.Status.ContainerStatuses[0].Ready = true
+	g.Expect(conditionSummary(egressRouterConditions(routerDeployment(1, 1), []corev1.Pod{pod}, routerNAD(), nil, now.Add(egressRouterProbeGrace)))).To(
+		HaveKeyWithValue(netopv1.EgressRouterDegraded, "False/AsExpected"))
+}
+
+func TestSyncEgressRouterStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	router := egressRouter(netopv1.L4RedirectRule{DestinationIP: "10.0.0.10", Port: 80, Protocol: netopv1.ProtocolTypeTCP})
+	deployment := routerDeployment(1, 1)
+	deployment.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type:    appsv1.DeploymentProgressing,
+		Status:  corev1.ConditionFalse,
+		Message: "progress deadline exceeded",
+	}}
+	client := fake.NewFakeClient(router, deployment, routerNAD())
+	recorder := record.NewFakeRecorder(10)
+	r := &EgressRouterReconciler{client: client, recorder: recorder}
+	getRouter := func() *netopv1.EgressRouter {
+		obj := &netopv1.EgressRouter{}
+		g.Expect(client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: "egress", Name: "router"}, obj)).To(Succeed())
+		return obj
+	}
+
+	settled, err := r.syncEgressRouterStatus(ctx, getRouter(), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(settled).To(BeTrue())
+	status := getRouter().Status
+	g.Expect(conditionSummary(status.Conditions)).To(HaveKeyWithValue(netopv1.EgressRouterDegraded, "True/DeploymentFailed"))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning DeploymentFailed")))
+
+	// The transition times are kept while the conditions do not change
+	_, err = r.syncEgressRouterStatus(ctx, getRouter(), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(getRouter().Status).To(Equal(status))
+	g.Expect(recorder.Events).NotTo(Receive())
+}
+
+func TestRenderEgressRouterProbe(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter(
+		netopv1.L4RedirectRule{DestinationIP: "10.0.0.10", Port: 80, Protocol: netopv1.ProtocolTypeTCP},
+		netopv1.L4RedirectRule{DestinationIP: "10.0.0.11", Port: 8080, Protocol: netopv1.ProtocolTypeTCP, TargetPort: 80},
+		netopv1.L4RedirectRule{DestinationIP: "10.0.0.12", Port: 53, Protocol: netopv1.ProtocolTypeUDP},
+	)
+	objs, err := renderEgressRouter("../../../bindata", "egress", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	containers, _, _ := uns.NestedSlice(objs[1].Object, "spec", "template", "spec", "containers")
+	command, _, _ := uns.NestedStringSlice(containers[0].(map[string]interface{}), "readinessProbe", "exec", "command")
+	g.Expect(command).To(HaveLen(3))
+	g.Expect(command[2]).To(ContainSubstring("for target in 10.0.0.10/80 10.0.0.11/80 ; do"))
+
+	// A router without TCP targets has nothing to probe
+	router = egressRouter(netopv1.L4RedirectRule{DestinationIP: "10.0.0.12", Port: 53, Protocol: netopv1.ProtocolTypeUDP})
+	objs, err = renderEgressRouter("../../../bindata", "egress", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	containers, _, _ = uns.NestedSlice(objs[1].Object, "spec", "template", "spec", "containers")
+	g.Expect(containers[0]).NotTo(HaveKey("readinessProbe"))
+}
@@ -0,0 +1,235 @@
+package egress_router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	configv1 "github.com/openshift/api/config/v1"
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// invalidEgressRouterError lists the problems of an EgressRouter that prevent
+// it from being deployed
+type invalidEgressRouterError struct {
+	problems []string
+}
+
+func (e *invalidEgressRouterError) Error() string {
+	return "invalid egress router: " + strings.Join(e.problems, "; ")
+}
+
+// egressRouterClusterNetworks are the networks of the cluster that the
+// addresses of a router must not overlap
+type egressRouterClusterNetworks struct {
+	ClusterNetworks []*net.IPNet
+	ServiceNetworks []*net.IPNet
+	MachineNetworks []*net.IPNet
+}
+
+// parseCIDRs parses the valid CIDRs of a list, and ignores the others
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	out := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
+			out = append(out, ipnet)
+		}
+	}
+	return out
+}
+
+// overlaps tells whether two subnets share addresses
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// sameSubnet tells whether two subnets are the same
+func sameSubnet(a, b *net.IPNet) bool {
+	return a.IP.Equal(b.IP) && a.Mask.String() == b.Mask.String()
+}
+
+// machineNetworksFromInstallConfig returns the machine networks of the install-config
+func machineNetworksFromInstallConfig(installConfig string) ([]*net.IPNet, error) {
+	var ic struct {
+		Networking struct {
+			MachineCIDR    string `json:"machineCIDR"`
+			MachineNetwork []struct {
+				CIDR string `json:"cidr"`
+			} `json:"machineNetwork,omitempty"`
+		} `json:"networking"`
+	}
+	if err := yaml.Unmarshal([]byte(installConfig), &ic); err != nil {
+		return nil, fmt.Errorf("invalid install-config: %v", err)
+	}
+	cidrs := []string{}
+	if ic.Networking.MachineCIDR != "" {
+		cidrs = append(cidrs, ic.Networking.MachineCIDR)
+	}
+	for _, mn := range ic.Networking.MachineNetwork {
+		cidrs = append(cidrs, mn.CIDR)
+	}
+	machineNetworks := []*net.IPNet{}
+	for _, ipnet := range parseCIDRs(cidrs) {
+		// The default route is not a machine network
+		if ones, _ := ipnet.Mask.Size(); ones > 0 {
+			machineNetworks = append(machineNetworks, ipnet)
+		}
+	}
+	return machineNetworks, nil
+}
+
+// getClusterNetworks reads the cluster and service networks from the status of
+// the cluster network config, and the machine networks from the install-config,
+// that clusters installed without it do not have.
+func (r *EgressRouterReconciler) getClusterNetworks(ctx context.Context) (*egressRouterClusterNetworks, error) {
+	crclient := r.client.Default().CRClient()
+	networks := &egressRouterClusterNetworks{}
+
+	clusterConfig := &configv1.Network{}
+	if err := crclient.Get(ctx, types.NamespacedName{Name: names.CLUSTER_CONFIG}, clusterConfig); err != nil {
+		return nil, fmt.Errorf("could not get the cluster network config: %w", err)
+	}
+	clusterNetworks := []string{}
+	for _, cn := range clusterConfig.Status.ClusterNetwork {
+		clusterNetworks = append(clusterNetworks, cn.CIDR)
+	}
+	networks.ClusterNetworks = parseCIDRs(clusterNetworks)
+	networks.ServiceNetworks = parseCIDRs(clusterConfig.Status.ServiceNetwork)
+
+	installConfig := &corev1.ConfigMap{}
+	err := crclient.Get(ctx, types.NamespacedName{Namespace: "kube-system", Name: "cluster-config-v1"}, installConfig)
+	if apierrors.IsNotFound(err) {
+		klog.V(4).Infof("No install-config, the egress routers are not checked against the machine networks")
+		return networks, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get the install-config: %w", err)
+	}
+	networks.MachineNetworks, err = machineNetworksFromInstallConfig(installConfig.Data["install-config"])
+	if err != nil {
+		return nil, err
+	}
+	return networks, nil
+}
+
+// validateAddress checks an address of a router against the networks of the cluster.
+// An address in the cluster and service networks would be routed by the pod network
+// instead of the egress interface. An address is usually in the network of the nodes,
+// which the plugin infers the interface from, but then its subnet must be a machine
+// network, not a subnet that partly overlaps one.
+func validateAddress(address netopv1.EgressRouterAddress, networks *egressRouterClusterNetworks) []string {
+	ip, ipnet, err := net.ParseCIDR(address.IP)
+	if err != nil {
+		return []string{fmt.Sprintf("address %q is not a valid CIDR", address.IP)}
+	}
+	problems := []string{}
+	for _, cn := range networks.ClusterNetworks {
+		if overlaps(ipnet, cn) {
+			problems = append(problems, fmt.Sprintf("address %s overlaps the cluster network %s", address.IP, cn))
+		}
+	}
+	for _, sn := range networks.ServiceNetworks {
+		if overlaps(ipnet, sn) {
+			problems = append(problems, fmt.Sprintf("address %s overlaps the service network %s", address.IP, sn))
+		}
+	}
+	for _, mn := range networks.MachineNetworks {
+		if overlaps(ipnet, mn) && !sameSubnet(ipnet, mn) {
+			problems = append(problems, fmt.Sprintf("address %s overlaps the machine network %s", address.IP, mn))
+		}
+	}
+
+	// The gateway is optional, the plugin determines it when it is not set
+	if address.Gateway == "" {
+		return problems
+	}
+	gateway := net.ParseIP(address.Gateway)
+	switch {
+	case gateway == nil:
+		problems = append(problems, fmt.Sprintf("gateway %q of address %s is not a valid IP", address.Gateway, address.IP))
+	case !ipnet.Contains(gateway):
+		problems = append(problems, fmt.Sprintf("gateway %s is not in the subnet of address %s", address.Gateway, address.IP))
+	case gateway.Equal(ip):
+		problems = append(problems, fmt.Sprintf("gateway %s is address %s itself", address.Gateway, address.IP))
+	}
+	return problems
+}
+
+// validateRedirectRules checks that no two redirect rules handle the same port and
+// protocol. A rule without a port is the fallback of the router, like the fallback
+// IP, so there can only be one of them.
+func validateRedirectRules(redirect *netopv1.RedirectConfig) []string {
+	if redirect == nil {
+		return nil
+	}
+	problems := []string{}
+	ports := map[string]string{}
+	fallback := ""
+	if redirect.FallbackIP != "" {
+		fallback = "fallback IP " + redirect.FallbackIP
+	}
+	for _, rule := range redirect.RedirectRules {
+		if rule.Port == 0 {
+			if fallback != "" {
+				problems = append(problems, fmt.Sprintf("redirect rule to %s without a port collides with the %s", rule.DestinationIP, fallback))
+			} else {
+				fallback = "redirect rule to " + rule.DestinationIP + " without a port"
+			}
+			continue
+		}
+		key := fmt.Sprintf("%d/%s", rule.Port, rule.Protocol)
+		if destination, ok := ports[key]; ok {
+			problems = append(problems, fmt.Sprintf("redirect rules to %s and %s both use port %s", destination, rule.DestinationIP, key))
+			continue
+		}
+		ports[key] = rule.DestinationIP
+	}
+	return problems
+}
+
+// validateEgressRouterSpec checks a router against the networks of the cluster
+func validateEgressRouterSpec(router *netopv1.EgressRouter, networks *egressRouterClusterNetworks) []string {
+	problems := []string{}
+	if len(router.Spec.Addresses) == 0 {
+		problems = append(problems, "router without addresses")
+	}
+	for _, address := range router.Spec.Addresses {
+		problems = append(problems, validateAddress(address, networks)...)
+	}
+	problems = append(problems, validateRedirectRules(router.Spec.Redirect)...)
+	if _, err := egressRouterReplicas(router); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := egressRouterProxyConfig(router); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// validateEgressRouter checks a router against the cluster before anything is
+// rendered for it, and returns an invalidEgressRouterError with all its problems.
+func (r *EgressRouterReconciler) validateEgressRouter(ctx context.Context, router *netopv1.EgressRouter) error {
+	networks, err := r.getClusterNetworks(ctx)
+	if err != nil {
+		return err
+	}
+	problems := validateEgressRouterSpec(router, networks)
+	var invalid *invalidEgressRouterError
+	if err := r.validateEgressRouterAddresses(ctx, router); errors.As(err, &invalid) {
+		problems = append(problems, invalid.problems...)
+	} else if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return &invalidEgressRouterError{problems: problems}
+	}
+	return nil
+}
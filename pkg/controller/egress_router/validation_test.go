@@ -0,0 +1,195 @@
+package egress_router
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var testClusterNetworks = &egressRouterClusterNetworks{
+	ClusterNetworks: parseCIDRs([]string{"10.128.0.0/14"}),
+	ServiceNetworks: parseCIDRs([]string{"172.30.0.0/16"}),
+	MachineNetworks: parseCIDRs([]string{"192.168.12.0/24"}),
+}
+
+func TestValidateAddress(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, tc := range []struct {
+		address  netopv1.EgressRouterAddress
+		problems []string
+	}{
+		{
+			// The address of a router is usually in the network of the nodes
+			address: netopv1.EgressRouterAddress{IP: "192.168.12.99/24", Gateway: "192.168.12.1"},
+		},
+		{
+			address: netopv1.EgressRouterAddress{IP: "192.168.100.99/24", Gateway: "192.168.100.1"},
+		},
+		{
+			// The gateway is optional
+			address: netopv1.EgressRouterAddress{IP: "192.168.100.98/24"},
+		},
+		{
+			address:  netopv1.EgressRouterAddress{IP: "192.168.12.99/16", Gateway: "192.168.12.1"},
+			problems: []string{"address 192.168.12.99/16 overlaps the machine network 192.168.12.0/24"},
+		},
+		{
+			address:  netopv1.EgressRouterAddress{IP: "10.130.0.99/24", Gateway: "10.130.0.1"},
+			problems: []string{"address 10.130.0.99/24 overlaps the cluster network 10.128.0.0/14"},
+		},
+		{
+			address:  netopv1.EgressRouterAddress{IP: "172.16.0.99/12", Gateway: "172.16.0.1"},
+			problems: []string{"address 172.16.0.99/12 overlaps the service network 172.30.0.0/16"},
+		},
+		{
+			address:  netopv1.EgressRouterAddress{IP: "192.168.12.99/24", Gateway: "192.168.13.1"},
+			problems: []string{"gateway 192.168.13.1 is not in the subnet of address 192.168.12.99/24"},
+		},
+		{
+			address:  netopv1.EgressRouterAddress{IP: "192.168.12.99/24", Gateway: "192.168.12.99"},
+			problems: []string{"gateway 192.168.12.99 is address 192.168.12.99/24 itself"},
+		},
+		{
+			address:  netopv1.EgressRouterAddress{IP: "192.168.12.99/24", Gateway: "gateway"},
+			problems: []string{`gateway "gateway" of address 192.168.12.99/24 is not a valid IP`},
+		},
+		{
+			address:  netopv1.EgressRouterAddress{IP: "192.168.12.99", Gateway: "192.168.12.1"},
+			problems: []string{`address "192.168.12.99" is not a valid CIDR`},
+		},
+	} {
+		problems := validateAddress(tc.address, testClusterNetworks)
+		if tc.problems == nil {
+			g.Expect(problems).To(BeEmpty(), tc.address.IP)
+		} else {
+			g.Expect(problems).To(Equal(tc.problems), tc.address.IP)
+		}
+	}
+}
+
+func TestValidateRedirectRules(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(validateRedirectRules(nil)).To(BeEmpty())
+	g.Expect(validateRedirectRules(&netopv1.RedirectConfig{
+		RedirectRules: []netopv1.L4RedirectRule{
+			{DestinationIP: "10.0.0.10", Port: 80, Protocol: netopv1.ProtocolTypeTCP},
+			{DestinationIP: "10.0.0.11", Port: 80, Protocol: netopv1.ProtocolTypeUDP},
+			{DestinationIP: "10.0.0.12"},
+		},
+	})).To(BeEmpty())
+
+	g.Expect(validateRedirectRules(&netopv1.RedirectConfig{
+		FallbackIP: "10.0.0.1",
+		RedirectRules: []netopv1.L4RedirectRule{
+			{DestinationIP: "10.0.0.10", Port: 80, Protocol: netopv1.ProtocolTypeTCP},
+			{DestinationIP: "10.0.0.11", Port: 80, Protocol: netopv1.ProtocolTypeTCP, TargetPort: 8080},
+			{DestinationIP: "10.0.0.12"},
+		},
+	})).To(Equal([]string{
+		"redirect rules to 10.0.0.10 and 10.0.0.11 both use port 80/TCP",
+		"redirect rule to 10.0.0.12 without a port collides with the fallback IP 10.0.0.1",
+	}))
+}
+
+func TestMachineNetworksFromInstallConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	machineNetworks, err := machineNetworksFromInstallConfig(`
+networking:
+  machineCIDR: 10.0.0.0/16
+  machineNetwork:
+  - cidr: 192.168.12.0/24
+  - cidr: 0.0.0.0/0
+`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(machineNetworks).To(Equal(parseCIDRs([]string{"10.0.0.0/16", "192.168.12.0/24"})))
+
+	_, err = machineNetworksFromInstallConfig("networking: [")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestValidateEgressRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	clusterConfig := &configv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: names.CLUSTER_CONFIG},
+		Status: configv1.NetworkStatus{
+			ClusterNetwork: []configv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/14"}},
+			ServiceNetwork: []string{"172.30.0.0/16"},
+		},
+	}
+	installConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "cluster-config-v1"},
+		Data: map[string]string{"install-config": `
+networking:
+  machineNetwork:
+  - cidr: 192.168.0.0/16
+`},
+	}
+
+	router := egressRouter()
+	r := &EgressRouterReconciler{client: fake.NewFakeClient(clusterConfig, installConfig, router)}
+	err := r.validateEgressRouter(ctx, router)
+	g.Expect(err).To(MatchError("invalid egress router: address 192.168.12.99/24 overlaps the machine network 192.168.0.0/16"))
+
+	// Every problem of the router is reported
+	router.Spec.Addresses = []netopv1.EgressRouterAddress{{IP: "10.128.10.99/24", Gateway: "10.128.11.1"}}
+	router.Annotations = map[string]string{names.EgressRouterReplicasAnnotation: "none"}
+	err = r.validateEgressRouter(ctx, router)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.(*invalidEgressRouterError).problems).To(HaveLen(3))
+
+	conditions := egressRouterConditions(nil, nil, nil, err, time.Now())
+	g.Expect(conditions[2].Reason).To(Equal("InvalidSpec"))
+	g.Expect(conditions[2].Message).To(ContainSubstring("overlaps the cluster network 10.128.0.0/14"))
+
+	// Without an install-config, the machine networks are not checked
+	router = egressRouter()
+	r = &EgressRouterReconciler{client: fake.NewFakeClient(clusterConfig, router)}
+	g.Expect(r.validateEgressRouter(ctx, router)).To(Succeed())
+}
+
+func TestRenderEgressRouterGateway(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter()
+	router.Spec.Redirect.FallbackIP = "192.168.12.100"
+	for _, gateway := range []string{"192.168.12.1", ""} {
+		router.Spec.Addresses[0].Gateway = gateway
+		g.Expect(validateEgressRouterSpec(router, testClusterNetworks)).To(BeEmpty())
+		objs, err := renderEgressRouter("../../../bindata", "egress", router)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		config, _, _ := uns.NestedString(objs[0].Object, "spec", "config")
+		nad := map[string]interface{}{}
+		g.Expect(json.Unmarshal([]byte(config), &nad)).To(Succeed())
+		ip := nad["ip"].(map[string]interface{})
+		g.Expect(ip).To(HaveKeyWithValue("fallbackIP", "192.168.12.100"))
+
+		networks := []map[string]interface{}{}
+		template, _, _ := uns.NestedStringMap(objs[1].Object, "spec", "template", "metadata", "annotations")
+		g.Expect(json.Unmarshal([]byte(template["k8s.v1.cni.cncf.io/networks"]), &networks)).To(Succeed())
+		g.Expect(networks).To(HaveLen(1))
+		if gateway == "" {
+			g.Expect(ip).NotTo(HaveKey("gateway"))
+			g.Expect(networks[0]).NotTo(HaveKey("default-route"))
+		} else {
+			g.Expect(ip).To(HaveKeyWithValue("gateway", gateway))
+			g.Expect(networks[0]).To(HaveKeyWithValue("default-route", []interface{}{gateway}))
+		}
+	}
+}
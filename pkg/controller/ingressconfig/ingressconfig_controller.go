@@ -2,7 +2,9 @@ package ingressconfig
 
 import (
 	"context"
+	"encoding/json"
 	"log"
+	"sort"
 	"time"
 
 	operv1 "github.com/openshift/api/operator/v1"
@@ -11,6 +13,7 @@ import (
 	"github.com/openshift/cluster-network-operator/pkg/names"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -69,39 +72,32 @@ type ReconcileIngressConfigs struct {
 }
 
 // Reconcile sets the openshift-host-network namespaces' labels as per the
-// endpointPublishingStrategy of the `default` ingress controller object.
-// In particular, when the endpointPublishingStrategy is HostNetwork, it will
-// add the "policy-group.network.openshift.io/ingress="" label and also add
-// the "network.openshift.io/policy-group=ingress" label for legacy reasons
-// to the host network namespace.
-// When the endpointPublishingStrategy is changed to anything other than
-// HostNetwork, it reconciles and removes these labels from the host network
+// endpointPublishingStrategy of all the ingress controller objects.
+// In particular, when the endpointPublishingStrategy of any of them is
+// HostNetwork, it will add the "policy-group.network.openshift.io/ingress=""
+// label and also add the "network.openshift.io/policy-group=ingress" label for
+// legacy reasons to the host network namespace. The ingress controllers that
+// require each label are recorded in the policy-group-sources annotation.
+// When no ingress controller has the HostNetwork endpointPublishingStrategy
+// anymore, it reconciles and removes these labels from the host network
 // namespace.
 func (r *ReconcileIngressConfigs) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
-	if request.Namespace != names.IngressControllerNamespace || request.Name != names.DefaultIngressControllerName {
+	if request.Namespace != names.IngressControllerNamespace {
 		return reconcile.Result{}, nil
 	}
 	log.Printf("Reconciling update to IngressController %s/%s\n", request.Namespace, request.Name)
-	ingressControllerConfig := &operv1.IngressController{TypeMeta: metav1.TypeMeta{APIVersion: operv1.GroupVersion.String(), Kind: "IngressController"}}
-	err := r.client.Get(ctx, request.NamespacedName, ingressControllerConfig)
+	// The labels are the union of the ones required by every ingress controller,
+	// so all of them are evaluated whichever one changed, or was deleted.
+	ingressControllers := &operv1.IngressControllerList{}
+	err := r.client.List(ctx, ingressControllers, crclient.InNamespace(names.IngressControllerNamespace))
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			log.Printf("Ingress Controller configuration %s was deleted", request.NamespacedName.String())
-			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected, since we set
-			// the ownerReference (see https://kubernetes.io/docs/concepts/workloads/controllers/garbage-collection/).
-			// Return and don't requeue
-			return reconcile.Result{}, nil
-		}
-		// Error reading the object - requeue the request.
-		log.Printf("Unable to retrieve IngressController.operator.openshift.io object: %v", err)
+		// Error reading the objects - requeue the request.
+		log.Printf("Unable to list IngressController.operator.openshift.io objects: %v", err)
 		return reconcile.Result{}, err
 	}
-	addLabel := ingressControllerConfig.Status.EndpointPublishingStrategy != nil &&
-		ingressControllerConfig.Status.EndpointPublishingStrategy.Type == operv1.HostNetworkStrategyType
 
-	err = r.updatePolicyGroupLabelOnNamespace(ctx, names.HostNetworkNamespace, addLabel)
+	err = r.updatePolicyGroupLabelsOnNamespace(ctx, names.HostNetworkNamespace, policyGroupLabelSources(ingressControllers.Items))
 	if err != nil {
 		log.Printf("Error setting the host network label on namespace %s: %v", names.HostNetworkNamespace, err)
 		return reconcile.Result{}, err
@@ -109,8 +105,45 @@ func (r *ReconcileIngressConfigs) Reconcile(ctx context.Context, request reconci
 	return reconcile.Result{RequeueAfter: ResyncPeriod}, nil
 }
 
-// setLabelsOnNamespace sets the labels specified on the target namespace using the client API
-func (r *ReconcileIngressConfigs) updatePolicyGroupLabelOnNamespace(ctx context.Context, targetNamespace string, add bool) error {
+// policyGroupLabelValues are the policy group labels managed on the host network
+// namespace, with their values
+var policyGroupLabelValues = map[string]string{
+	names.PolicyGroupLabelIngress: names.PolicyGroupLabelIngressValue,
+	names.PolicyGroupLabelLegacy:  names.PolicyGroupLabelLegacyValue,
+}
+
+// requiredPolicyGroupLabels returns the policy group labels the host network
+// namespace needs for an ingress controller. Only the HostNetwork strategy runs
+// routers in host-network pods; the LoadBalancerService, NodePortService and
+// Private strategies run them in pod-network pods, whose traffic is already
+// selected by the namespace of the routers.
+func requiredPolicyGroupLabels(ingressController *operv1.IngressController) []string {
+	strategy := ingressController.Status.EndpointPublishingStrategy
+	if strategy == nil || strategy.Type != operv1.HostNetworkStrategyType {
+		return nil
+	}
+	return []string{names.PolicyGroupLabelIngress, names.PolicyGroupLabelLegacy}
+}
+
+// policyGroupLabelSources returns the union of the policy group labels required
+// by the ingress controllers, mapped to the sorted names of the ingress
+// controllers that require them.
+func policyGroupLabelSources(ingressControllers []operv1.IngressController) map[string][]string {
+	sources := map[string][]string{}
+	for i := range ingressControllers {
+		for _, label := range requiredPolicyGroupLabels(&ingressControllers[i]) {
+			sources[label] = append(sources[label], ingressControllers[i].Name)
+		}
+	}
+	for _, controllers := range sources {
+		sort.Strings(controllers)
+	}
+	return sources
+}
+
+// updatePolicyGroupLabelsOnNamespace sets the policy group labels with sources on
+// the target namespace, and removes the others, using the client API
+func (r *ReconcileIngressConfigs) updatePolicyGroupLabelsOnNamespace(ctx context.Context, targetNamespace string, sources map[string][]string) error {
 	var err error
 	namespace := &corev1.Namespace{TypeMeta: metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "Namespace"}}
 	err = r.client.Get(ctx, types.NamespacedName{Name: targetNamespace}, namespace)
@@ -132,15 +165,38 @@ func (r *ReconcileIngressConfigs) updatePolicyGroupLabelOnNamespace(ctx context.
 	if existingLabels == nil {
 		existingLabels = map[string]string{}
 	}
-	if !add {
-		delete(existingLabels, names.PolicyGroupLabelIngress)
-		delete(existingLabels, names.PolicyGroupLabelLegacy)
-	} else {
-		existingLabels[names.PolicyGroupLabelIngress] = names.PolicyGroupLabelIngressValue
-		existingLabels[names.PolicyGroupLabelLegacy] = names.PolicyGroupLabelLegacyValue
+	for label, value := range policyGroupLabelValues {
+		if _, ok := sources[label]; ok {
+			existingLabels[label] = value
+		} else {
+			delete(existingLabels, label)
+		}
 	}
-
 	newNamespace.SetLabels(existingLabels)
 
-	return r.client.Patch(context.TODO(), newNamespace, crclient.MergeFrom(namespace))
+	existingAnnotations := newNamespace.GetAnnotations()
+	if existingAnnotations == nil {
+		existingAnnotations = map[string]string{}
+	}
+	if len(sources) > 0 {
+		data, err := json.Marshal(sources)
+		if err != nil {
+			return err
+		}
+		existingAnnotations[names.PolicyGroupSourcesAnnotation] = string(data)
+	} else {
+		delete(existingAnnotations, names.PolicyGroupSourcesAnnotation)
+	}
+	newNamespace.SetAnnotations(existingAnnotations)
+
+	if equality.Semantic.DeepEqual(namespace.ObjectMeta, newNamespace.ObjectMeta) {
+		return nil
+	}
+	if len(sources) > 0 {
+		log.Printf("Setting the policy group labels of namespace %s required by ingress controllers: %s",
+			targetNamespace, existingAnnotations[names.PolicyGroupSourcesAnnotation])
+	} else {
+		log.Printf("Removing the policy group labels of namespace %s, no ingress controller requires them", targetNamespace)
+	}
+	return r.client.Patch(ctx, newNamespace, crclient.MergeFrom(namespace))
 }
@@ -0,0 +1,92 @@
+package ingressconfig
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func ingressController(name string, strategy operv1.EndpointPublishingStrategyType) *operv1.IngressController {
+	return &operv1.IngressController{
+		ObjectMeta: metav1.ObjectMeta{Namespace: names.IngressControllerNamespace, Name: name},
+		Status: operv1.IngressControllerStatus{
+			EndpointPublishingStrategy: &operv1.EndpointPublishingStrategy{Type: strategy},
+		},
+	}
+}
+
+func TestPolicyGroupLabelSources(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(policyGroupLabelSources(nil)).To(BeEmpty())
+	g.Expect(policyGroupLabelSources([]operv1.IngressController{
+		*ingressController(names.DefaultIngressControllerName, operv1.LoadBalancerServiceStrategyType),
+		*ingressController("private", operv1.PrivateStrategyType),
+		*ingressController("nodeport", operv1.NodePortServiceStrategyType),
+		{ObjectMeta: metav1.ObjectMeta{Name: "unadmitted"}},
+	})).To(BeEmpty())
+	g.Expect(policyGroupLabelSources([]operv1.IngressController{
+		*ingressController("sharded", operv1.HostNetworkStrategyType),
+		*ingressController(names.DefaultIngressControllerName, operv1.LoadBalancerServiceStrategyType),
+		*ingressController("internal", operv1.HostNetworkStrategyType),
+	})).To(Equal(map[string][]string{
+		names.PolicyGroupLabelIngress: {"internal", "sharded"},
+		names.PolicyGroupLabelLegacy:  {"internal", "sharded"},
+	}))
+}
+
+func TestReconcileIngressConfigs(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	client := fake.NewFakeClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: names.HostNetworkNamespace, Labels: map[string]string{"other": "label"}}},
+		ingressController(names.DefaultIngressControllerName, operv1.LoadBalancerServiceStrategyType),
+		ingressController("sharded", operv1.HostNetworkStrategyType),
+	)
+	crclient := client.Default().CRClient()
+	r := newIngressConfigReconciler(crclient, statusmanager.New(client, "testing", names.StandAloneClusterName))
+	reconcileAndGet := func(name string) *corev1.Namespace {
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: names.IngressControllerNamespace, Name: name}})
+		g.Expect(err).NotTo(HaveOccurred())
+		namespace := &corev1.Namespace{}
+		g.Expect(crclient.Get(ctx, types.NamespacedName{Name: names.HostNetworkNamespace}, namespace)).To(Succeed())
+		return namespace
+	}
+
+	// A sharded ingress controller on the host network labels the namespace,
+	// even though the default one does not need it
+	namespace := reconcileAndGet(names.DefaultIngressControllerName)
+	g.Expect(namespace.Labels).To(Equal(map[string]string{
+		"other":                       "label",
+		names.PolicyGroupLabelIngress: names.PolicyGroupLabelIngressValue,
+		names.PolicyGroupLabelLegacy:  names.PolicyGroupLabelLegacyValue,
+	}))
+	g.Expect(namespace.Annotations).To(HaveKeyWithValue(names.PolicyGroupSourcesAnnotation,
+		`{"network.openshift.io/policy-group":["sharded"],"policy-group.network.openshift.io/ingress":["sharded"]}`))
+
+	// The labels stay as long as an ingress controller requires them
+	g.Expect(crclient.Create(ctx, ingressController("internal", operv1.HostNetworkStrategyType))).To(Succeed())
+	g.Expect(crclient.Delete(ctx, ingressController("sharded", operv1.HostNetworkStrategyType))).To(Succeed())
+	namespace = reconcileAndGet("sharded")
+	g.Expect(namespace.Labels).To(HaveKey(names.PolicyGroupLabelIngress))
+	g.Expect(namespace.Annotations).To(HaveKeyWithValue(names.PolicyGroupSourcesAnnotation,
+		`{"network.openshift.io/policy-group":["internal"],"policy-group.network.openshift.io/ingress":["internal"]}`))
+
+	// ... and are removed once none does
+	g.Expect(crclient.Delete(ctx, ingressController("internal", operv1.HostNetworkStrategyType))).To(Succeed())
+	namespace = reconcileAndGet("internal")
+	g.Expect(namespace.Labels).To(Equal(map[string]string{"other": "label"}))
+	g.Expect(namespace.Annotations).NotTo(HaveKey(names.PolicyGroupSourcesAnnotation))
+}
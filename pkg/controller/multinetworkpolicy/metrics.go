@@ -0,0 +1,27 @@
+package multinetworkpolicy
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var synced = metrics.NewGaugeVec(
+	&metrics.GaugeOpts{
+		Namespace:      "network_operator",
+		Subsystem:      "multi_networkpolicy",
+		Name:           "synced",
+		Help:           "Whether the multi-networkpolicy iptables rules are synced on a node, that is whether the current multus-networkpolicy daemon is ready there.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"node"},
+)
+
+func init() {
+	legacyregistry.MustRegister(synced)
+}
+
+// resetMetrics clears the MultiNetworkPolicy metrics, so that nodes that were
+// removed since the last reconcile, or a disabled daemon, are not reported.
+func resetMetrics() {
+	synced.Reset()
+}
@@ -0,0 +1,273 @@
+package multinetworkpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// daemonSetName is the multi-networkpolicy iptables daemon, rendered by
+	// the operator when useMultiNetworkPolicy is enabled
+	daemonSetName = "multus-networkpolicy"
+
+	// podTemplateGenerationLabel is set by the DaemonSet controller on its pods
+	// to the generation of the pod template they were created from, which the
+	// DaemonSet records in its appsv1.DeprecatedTemplateGeneration annotation.
+	// It is not the generation of the DaemonSet, which also changes with the
+	// rest of its spec.
+	podTemplateGenerationLabel = "pod-template-generation"
+)
+
+// resyncPeriod is how often the daemon pods are checked, since nothing
+// triggers a reconcile when they come and go
+var resyncPeriod = time.Minute
+
+// maxItemsInMessage caps the number of namespaces or nodes listed in the condition
+const maxItemsInMessage = 10
+
+var multiNetworkPolicyGVK = schema.GroupVersionKind{Group: "k8s.cni.cncf.io", Version: "v1beta1", Kind: "MultiNetworkPolicyList"}
+
+// Add creates a new MultiNetworkPolicy controller and adds it to the manager.
+func Add(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client) error {
+	return add(mgr, newReconciler(c))
+}
+
+func newReconciler(c cnoclient.Client) *ReconcileMultiNetworkPolicy {
+	return &ReconcileMultiNetworkPolicy{client: c}
+}
+
+func add(mgr manager.Manager, r *ReconcileMultiNetworkPolicy) error {
+	c, err := controller.New("multinetworkpolicy-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Reconcile as soon as the operator configuration changes, so that enabling
+	// or disabling useMultiNetworkPolicy is reported while it is rolled out.
+	// From then on, the reconciler requeues itself.
+	return c.Watch(source.Kind(mgr.GetCache(), &operv1.Network{}), &handler.EnqueueRequestForObject{},
+		predicate.GenerationChangedPredicate{})
+}
+
+var _ reconcile.Reconciler = &ReconcileMultiNetworkPolicy{}
+
+// ReconcileMultiNetworkPolicy reports whether the MultiNetworkPolicy objects
+// of the cluster are enforced, and on which nodes the multi-networkpolicy
+// iptables rules are synced.
+type ReconcileMultiNetworkPolicy struct {
+	client cnoclient.Client
+}
+
+func (r *ReconcileMultiNetworkPolicy) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	if request.Name != names.OPERATOR_CONFIG {
+		return reconcile.Result{}, nil
+	}
+
+	operConfig := &operv1.Network{}
+	err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig)
+	if apierrors.IsNotFound(err) {
+		return reconcile.Result{}, nil
+	} else if err != nil {
+		klog.Errorf("Unable to retrieve Network.operator.openshift.io object: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	if operConfig.Spec.DisableMultiNetwork != nil && *operConfig.Spec.DisableMultiNetwork {
+		resetMetrics()
+		return reconcile.Result{}, statusmanager.RemoveOperatorConditions(ctx, r.client, names.MultiNetworkPolicyEnforced)
+	}
+	enabled := operConfig.Spec.UseMultiNetworkPolicy != nil && *operConfig.Spec.UseMultiNetworkPolicy
+
+	namespaces, err := policyNamespaces(ctx, r.client)
+	if err != nil {
+		klog.Errorf("Failed to list MultiNetworkPolicies: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	ds := &appsv1.DaemonSet{}
+	deployed := true
+	err = r.client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.MULTUS_NAMESPACE, Name: daemonSetName}, ds)
+	if apierrors.IsNotFound(err) {
+		deployed = false
+	} else if err != nil {
+		klog.Errorf("Failed to get the %s DaemonSet: %v", daemonSetName, err)
+		return reconcile.Result{}, err
+	}
+
+	resetMetrics()
+	unsynced := []string{}
+	if deployed {
+		pods := &corev1.PodList{}
+		if err := r.client.Default().CRClient().List(ctx, pods,
+			crclient.InNamespace(names.MULTUS_NAMESPACE), crclient.MatchingLabels{"app": daemonSetName}); err != nil {
+			klog.Errorf("Failed to list %s pods: %v", daemonSetName, err)
+			return reconcile.Result{}, err
+		}
+		nodes := syncedNodes(ds, pods.Items)
+		for node, ok := range nodes {
+			value := 0.0
+			if ok {
+				value = 1
+			} else {
+				unsynced = append(unsynced, node)
+			}
+			synced.WithLabelValues(node).Set(value)
+		}
+	}
+
+	condition := enforcedCondition(enabled, deployed, namespaces, sets.List(sets.New[string](unsynced...)))
+	if condition == nil {
+		err = statusmanager.RemoveOperatorConditions(ctx, r.client, names.MultiNetworkPolicyEnforced)
+	} else {
+		err = statusmanager.SetOperatorConditions(ctx, r.client, *condition)
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// ReportChange reports the namespaces whose MultiNetworkPolicy objects start or
+// stop being enforced when useMultiNetworkPolicy changes from prev to next. It
+// is called before the change is rendered, so that the MultiNetworkPolicyEnforced
+// condition warns about it before the multus-networkpolicy DaemonSet is deployed
+// or removed. Once the change is rolled out, the controller takes over.
+func ReportChange(ctx context.Context, client cnoclient.Client, prev, next *operv1.NetworkSpec) error {
+	if prev == nil || (next.DisableMultiNetwork != nil && *next.DisableMultiNetwork) {
+		return nil
+	}
+	wasEnabled := prev.UseMultiNetworkPolicy != nil && *prev.UseMultiNetworkPolicy
+	enabled := next.UseMultiNetworkPolicy != nil && *next.UseMultiNetworkPolicy
+	if wasEnabled == enabled {
+		return nil
+	}
+
+	namespaces, err := policyNamespaces(ctx, client)
+	if err != nil {
+		return err
+	}
+	if len(namespaces) > 0 {
+		if enabled {
+			klog.Warningf("useMultiNetworkPolicy is enabled: the MultiNetworkPolicy objects in namespaces %s will start being enforced", truncatedList(namespaces))
+		} else {
+			klog.Warningf("useMultiNetworkPolicy is disabled: the MultiNetworkPolicy objects in namespaces %s will stop being enforced", truncatedList(namespaces))
+		}
+	}
+	// The DaemonSet is deployed as long as the previous configuration enabled it
+	condition := enforcedCondition(enabled, wasEnabled, namespaces, nil)
+	if condition == nil {
+		return statusmanager.RemoveOperatorConditions(ctx, client, names.MultiNetworkPolicyEnforced)
+	}
+	return statusmanager.SetOperatorConditions(ctx, client, *condition)
+}
+
+// policyNamespaces returns the sorted namespaces that have MultiNetworkPolicy
+// objects. If the MultiNetworkPolicy CRD is not installed, there are none.
+func policyNamespaces(ctx context.Context, client cnoclient.Client) ([]string, error) {
+	list := &uns.UnstructuredList{}
+	list.SetGroupVersionKind(multiNetworkPolicyGVK)
+	err := client.Default().CRClient().List(ctx, list)
+	if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list %s: %w", multiNetworkPolicyGVK.Kind, err)
+	}
+	namespaces := sets.New[string]()
+	for _, policy := range list.Items {
+		namespaces.Insert(policy.GetNamespace())
+	}
+	return sets.List(namespaces), nil
+}
+
+// syncedNodes returns, for each node that runs a multus-networkpolicy pod,
+// whether the rules are synced there: the pod must be ready, and created from
+// the current pod template of the DaemonSet.
+func syncedNodes(ds *appsv1.DaemonSet, pods []corev1.Pod) map[string]bool {
+	generation := ds.Annotations[appsv1.DeprecatedTemplateGeneration]
+	nodes := map[string]bool{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		ready := false
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				ready = true
+			}
+		}
+		current := pod.Labels[podTemplateGenerationLabel] == generation
+		// During a rolling update, a node may briefly have an old and a new pod
+		nodes[pod.Spec.NodeName] = nodes[pod.Spec.NodeName] || (ready && current)
+	}
+	return nodes
+}
+
+// enforcedCondition builds the MultiNetworkPolicyEnforced condition. It
+// returns nil when there is nothing to report, that is when
+// useMultiNetworkPolicy is disabled and there are no MultiNetworkPolicies.
+func enforcedCondition(enabled, deployed bool, namespaces, unsyncedNodes []string) *operv1.OperatorCondition {
+	condition := &operv1.OperatorCondition{
+		Type:   names.MultiNetworkPolicyEnforced,
+		Status: operv1.ConditionFalse,
+	}
+	switch {
+	case !enabled && len(namespaces) == 0:
+		return nil
+	case !enabled && deployed:
+		condition.Reason = "Disabling"
+		condition.Message = fmt.Sprintf("useMultiNetworkPolicy is being disabled: the MultiNetworkPolicy objects in namespaces %s will stop being enforced",
+			truncatedList(namespaces))
+	case !enabled:
+		condition.Reason = "Disabled"
+		condition.Message = fmt.Sprintf("useMultiNetworkPolicy is disabled: the MultiNetworkPolicy objects in namespaces %s are not enforced",
+			truncatedList(namespaces))
+	case !deployed && len(namespaces) > 0:
+		condition.Reason = "Enabling"
+		condition.Message = fmt.Sprintf("useMultiNetworkPolicy is being enabled: the MultiNetworkPolicy objects in namespaces %s will start being enforced",
+			truncatedList(namespaces))
+	case !deployed:
+		condition.Reason = "Enabling"
+		condition.Message = "useMultiNetworkPolicy is being enabled: the multus-networkpolicy daemon is not deployed yet"
+	case len(unsyncedNodes) > 0:
+		condition.Reason = "NotSynced"
+		condition.Message = fmt.Sprintf("The multi-networkpolicy rules are not synced on nodes %s", truncatedList(unsyncedNodes))
+	default:
+		condition.Status = operv1.ConditionTrue
+		condition.Reason = "Enforced"
+		condition.Message = fmt.Sprintf("The MultiNetworkPolicy objects in %d namespaces are enforced on every node", len(namespaces))
+	}
+	return condition
+}
+
+// truncatedList joins items, listing at most maxItemsInMessage of them
+func truncatedList(items []string) string {
+	if len(items) > maxItemsInMessage {
+		return strings.Join(items[:maxItemsInMessage], ", ") + fmt.Sprintf(" and %d more", len(items)-maxItemsInMessage)
+	}
+	return strings.Join(items, ", ")
+}
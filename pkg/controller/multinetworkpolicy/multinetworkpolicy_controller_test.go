@@ -0,0 +1,176 @@
+package multinetworkpolicy
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func daemonPod(name, node, generation string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: names.MULTUS_NAMESPACE,
+			Name:      name,
+			Labels:    map[string]string{"app": daemonSetName, podTemplateGenerationLabel: generation},
+		},
+		Spec:   corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+	}
+}
+
+func policy(namespace, name string) *uns.Unstructured {
+	obj := &uns.Unstructured{}
+	obj.SetAPIVersion("k8s.cni.cncf.io/v1beta1")
+	obj.SetKind("MultiNetworkPolicy")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestSyncedNodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// The template generation lags the generation of the DaemonSet once
+	// something else than its template changed
+	ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{
+		Generation:  3,
+		Annotations: map[string]string{appsv1.DeprecatedTemplateGeneration: "2"},
+	}}
+	pods := []corev1.Pod{
+		*daemonPod("a", "node-a", "2", true),
+		*daemonPod("b", "node-b", "2", false),
+		*daemonPod("c-old", "node-c", "1", true),
+		*daemonPod("d-old", "node-d", "1", true),
+		*daemonPod("d-new", "node-d", "2", true),
+		*daemonPod("pending", "", "2", false),
+	}
+	g.Expect(syncedNodes(ds, pods)).To(Equal(map[string]bool{
+		"node-a": true,
+		"node-b": false,
+		"node-c": false,
+		"node-d": true,
+	}))
+}
+
+func TestEnforcedCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(enforcedCondition(false, false, nil, nil)).To(BeNil())
+
+	cond := enforcedCondition(false, true, []string{"ns1", "ns2"}, nil)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionFalse))
+	g.Expect(cond.Reason).To(Equal("Disabling"))
+	g.Expect(cond.Message).To(ContainSubstring("namespaces ns1, ns2 will stop being enforced"))
+
+	cond = enforcedCondition(false, false, []string{"ns1"}, nil)
+	g.Expect(cond.Reason).To(Equal("Disabled"))
+
+	cond = enforcedCondition(true, false, []string{"ns1"}, nil)
+	g.Expect(cond.Reason).To(Equal("Enabling"))
+	g.Expect(cond.Message).To(ContainSubstring("namespaces ns1 will start being enforced"))
+
+	cond = enforcedCondition(true, true, []string{"ns1"}, []string{"node-b"})
+	g.Expect(cond.Reason).To(Equal("NotSynced"))
+	g.Expect(cond.Message).To(ContainSubstring("node-b"))
+
+	cond = enforcedCondition(true, true, []string{"ns1"}, nil)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal("Enforced"))
+}
+
+func TestReconcile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	disabled := false
+	client := fake.NewFakeClient(
+		&operv1.Network{
+			ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG},
+			Spec:       operv1.NetworkSpec{UseMultiNetworkPolicy: &disabled},
+		},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   names.MULTUS_NAMESPACE,
+			Name:        daemonSetName,
+			Annotations: map[string]string{appsv1.DeprecatedTemplateGeneration: "1"},
+		}},
+		daemonPod("a", "node-a", "1", true),
+		policy("ns1", "deny-all"),
+		policy("ns2", "deny-all"),
+	)
+	r := newReconciler(client)
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: names.OPERATOR_CONFIG}}
+
+	// The daemon is still deployed but useMultiNetworkPolicy is disabled
+	_, err := r.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	oc := &operv1.Network{}
+	g.Expect(client.Default().CRClient().Get(context.TODO(), request.NamespacedName, oc)).To(Succeed())
+	cond := v1helpers.FindOperatorCondition(oc.Status.Conditions, names.MultiNetworkPolicyEnforced)
+	g.Expect(cond).NotTo(BeNil())
+	g.Expect(cond.Reason).To(Equal("Disabling"))
+	g.Expect(cond.Message).To(ContainSubstring("ns1, ns2"))
+
+	// Once enabled, the policies are enforced on the ready node
+	enabled := true
+	oc.Spec.UseMultiNetworkPolicy = &enabled
+	g.Expect(client.Default().CRClient().Update(context.TODO(), oc)).To(Succeed())
+	_, err = r.Reconcile(context.TODO(), request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(client.Default().CRClient().Get(context.TODO(), request.NamespacedName, oc)).To(Succeed())
+	cond = v1helpers.FindOperatorCondition(oc.Status.Conditions, names.MultiNetworkPolicyEnforced)
+	g.Expect(cond).NotTo(BeNil())
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+}
+
+func TestReportChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	enabled, disabled := true, false
+	client := fake.NewFakeClient(
+		&operv1.Network{ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG}},
+		policy("ns1", "deny-all"),
+	)
+	condition := func() *operv1.OperatorCondition {
+		oc := &operv1.Network{}
+		g.Expect(client.Default().CRClient().Get(context.TODO(), types.NamespacedName{Name: names.OPERATOR_CONFIG}, oc)).To(Succeed())
+		return v1helpers.FindOperatorCondition(oc.Status.Conditions, names.MultiNetworkPolicyEnforced)
+	}
+
+	// Nothing is reported without a change
+	g.Expect(ReportChange(context.TODO(), client, nil, &operv1.NetworkSpec{UseMultiNetworkPolicy: &enabled})).To(Succeed())
+	g.Expect(ReportChange(context.TODO(), client,
+		&operv1.NetworkSpec{UseMultiNetworkPolicy: &enabled}, &operv1.NetworkSpec{UseMultiNetworkPolicy: &enabled})).To(Succeed())
+	g.Expect(condition()).To(BeNil())
+
+	// Disabling is reported while the DaemonSet is still deployed
+	g.Expect(ReportChange(context.TODO(), client,
+		&operv1.NetworkSpec{UseMultiNetworkPolicy: &enabled}, &operv1.NetworkSpec{UseMultiNetworkPolicy: &disabled})).To(Succeed())
+	cond := condition()
+	g.Expect(cond).NotTo(BeNil())
+	g.Expect(cond.Reason).To(Equal("Disabling"))
+	g.Expect(cond.Message).To(ContainSubstring("ns1 will stop being enforced"))
+
+	// Enabling is reported before the DaemonSet is deployed
+	g.Expect(ReportChange(context.TODO(), client,
+		&operv1.NetworkSpec{UseMultiNetworkPolicy: &disabled}, &operv1.NetworkSpec{UseMultiNetworkPolicy: &enabled})).To(Succeed())
+	cond = condition()
+	g.Expect(cond).NotTo(BeNil())
+	g.Expect(cond.Reason).To(Equal("Enabling"))
+	g.Expect(cond.Message).To(ContainSubstring("ns1 will start being enforced"))
+}
@@ -112,31 +112,37 @@ func enableMulticastOVN(ctx context.Context, client cnoclient.Client) error {
 	// 2. iterate through netnamespaces
 	//    - any with multicast-enabled="true" annotation will cause an update to the corresponding
 	//      namespace to add the necessary OVN annotation
-	for _, nns := range netNamespaceList {
-		multicastAnnotation := nns.GetAnnotations()[multicastEnabledSDN]
-		if multicastAnnotation == "true" {
-			// update namespace to have the same annotation
-			nspStr := nns.GetName()
-
-			if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-				namespaceObj, err := client.Default().Kubernetes().CoreV1().Namespaces().Get(ctx, nspStr, metav1.GetOptions{})
-				if err != nil {
-					return err
-				}
-				if namespaceObj.Annotations == nil {
-					namespaceObj.Annotations = make(map[string]string)
-				}
-				namespaceObj.Annotations[multicastEnabledOVN] = "true"
-				_, err = client.Default().Kubernetes().CoreV1().Namespaces().Update(ctx, namespaceObj, metav1.UpdateOptions{})
-				return err
-			}); err != nil {
+	for _, nspStr := range multicastEnabledNetNamespaces(netNamespaceList) {
+		// update namespace to have the same annotation
+		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			namespaceObj, err := client.Default().Kubernetes().CoreV1().Namespaces().Get(ctx, nspStr, metav1.GetOptions{})
+			if err != nil {
 				return err
 			}
+			if namespaceObj.Annotations == nil {
+				namespaceObj.Annotations = make(map[string]string)
+			}
+			namespaceObj.Annotations[multicastEnabledOVN] = "true"
+			_, err = client.Default().Kubernetes().CoreV1().Namespaces().Update(ctx, namespaceObj, metav1.UpdateOptions{})
+			return err
+		}); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// multicastEnabledNetNamespaces returns the names of the netnamespaces with multicast enabled
+func multicastEnabledNetNamespaces(netNamespaceList []*uns.Unstructured) []string {
+	enabled := []string{}
+	for _, nns := range netNamespaceList {
+		if nns.GetAnnotations()[multicastEnabledSDN] == "true" {
+			enabled = append(enabled, nns.GetName())
+		}
+	}
+	return enabled
+}
+
 func enableMulticastSDN(ctx context.Context, client cnoclient.Client) error {
 	multicastRollbackReady, err := netNamespacesExistForAllNamespaces(ctx, client)
 	if !multicastRollbackReady {
@@ -200,11 +206,27 @@ func convertEgressNetworkPolicyToEgressFirewall(ctx context.Context, client cnoc
 	if err != nil {
 		return err
 	}
+	egressFirewallList, err := egressFirewallsFromEgressNetworkPolicies(egressNetworkPolicyList)
+	if err != nil {
+		return err
+	}
+	for _, egressFirewall := range egressFirewallList {
+		klog.Infof("Convert EgressNetworkPolicy to EgressFirewall %s/%s", egressFirewall.GetNamespace(), egressFirewall.GetName())
+		if err := apply.ApplyObject(ctx, client, egressFirewall, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// egressFirewallsFromEgressNetworkPolicies converts each EgressNetworkPolicy to the
+// EgressFirewall of its namespace
+func egressFirewallsFromEgressNetworkPolicies(egressNetworkPolicyList []*uns.Unstructured) ([]*uns.Unstructured, error) {
+	egressFirewallList := []*uns.Unstructured{}
 	for _, enp := range egressNetworkPolicyList {
-		klog.Infof("Convert EgressNetworkPolicy %s/%s", enp.GetNamespace(), enp.GetName())
 		spec, ok := enp.Object["spec"]
 		if !ok {
-			return fmt.Errorf("fail to retrieve spec from EgressNetworkPolicy %s/%s", enp.GetNamespace(), enp.GetName())
+			return nil, fmt.Errorf("fail to retrieve spec from EgressNetworkPolicy %s/%s", enp.GetNamespace(), enp.GetName())
 		}
 
 		egressFirewall := &uns.Unstructured{
@@ -219,11 +241,9 @@ func convertEgressNetworkPolicyToEgressFirewall(ctx context.Context, client cnoc
 				"spec": spec,
 			},
 		}
-		if err := apply.ApplyObject(ctx, client, egressFirewall, ""); err != nil {
-			return err
-		}
+		egressFirewallList = append(egressFirewallList, egressFirewall)
 	}
-	return nil
+	return egressFirewallList, nil
 }
 
 func convertEgressFirewallToEgressNetworkPolicy(ctx context.Context, client cnoclient.Client) error {
@@ -275,26 +295,17 @@ func convertSdnEgressIpToOvnEgressIp(ctx context.Context, client cnoclient.Clien
 
 	// 2. iterate through hostsubnets
 	//    - any with egressIP configured will cause update to node label "egress-assignable"
-	hostSubnetFound := false
-	for _, hsn := range hostSubnetList {
-		hostSubnetHasEgressIpConfigAutomatic := hostSubnetHasEgressIpConfigAutomatic(*hsn)
-		hostSubnetHasEgressIpConfigManual := hostSubnetHasEgressIpConfigManual(*hsn)
-
-		// mark node from hostsubnet with annotation as follows:
-		// - k8s.ovn.org/egress-assignable: ""
-		if hostSubnetHasEgressIpConfigAutomatic || hostSubnetHasEgressIpConfigManual {
-			hostSubnetFound = true
-			if err := labelNodeAndRemoveHostSubnetConfig(ctx, client, hsn); err != nil {
-				return nil, nil, err
-			}
+	egressHostSubnets := egressIpHostSubnets(hostSubnetList)
+	for _, hsn := range egressHostSubnets {
+		if err := labelNodeAndRemoveHostSubnetConfig(ctx, client, hsn); err != nil {
+			return nil, nil, err
 		}
-
-		if hostSubnetHasEgressIpConfigManual {
+		if hostSubnetHasEgressIpConfigManual(*hsn) {
 			klog.Infof("Manual configuration of SDN egressIP detected and is unsupported for migration; OVN egressIPs will be generated but will not maintain individual node assignments from SDN hostsubnets")
 		}
 	}
 
-	if !hostSubnetFound {
+	if len(egressHostSubnets) == 0 {
 		klog.Infof("did not find a hostsubnet object with egressIP configured, quitting process early")
 		return nil, nil, nil
 	} else {
@@ -304,23 +315,41 @@ func convertSdnEgressIpToOvnEgressIp(ctx context.Context, client cnoclient.Clien
 	// 3. iterate through netnamespaces
 	//    - any with egressIP configured will cause an egressIP ovn resource to be created via k8s api
 	//    - a corresponding namespace label will be added to match the egressIP resource's namespace selector field
-	egressIpList := []*uns.Unstructured{}
+	//    - the cloudprivateipconfig of each egressIP is deleted first, if it exists
 	for _, nns := range netNamespaceList {
 		if netNamespaceHasEgressIpConfig(*nns) {
-			// config detected, translating to ovn
-			// - delete cloudprivateipconfig if it exists
-			// - create egressip custom resource for ovn
 			if err := deleteCloudPrivateIpConfigs(ctx, client, nns.Object["egressIPs"].([]interface{})); err != nil {
 				return nil, nil, err
 			}
+		}
+	}
+
+	return ovnEgressIpsFromNetNamespaces(netNamespaceList), netNamespaceList, nil // success
+}
+
+// egressIpHostSubnets returns the hostsubnets with egressIP configured, automatically or manually.
+// The nodes of these hostsubnets are the ones made egress-assignable.
+func egressIpHostSubnets(hostSubnetList []*uns.Unstructured) []*uns.Unstructured {
+	egressHostSubnets := []*uns.Unstructured{}
+	for _, hsn := range hostSubnetList {
+		if hostSubnetHasEgressIpConfigAutomatic(*hsn) || hostSubnetHasEgressIpConfigManual(*hsn) {
+			egressHostSubnets = append(egressHostSubnets, hsn)
+		}
+	}
+	return egressHostSubnets
+}
 
+// ovnEgressIpsFromNetNamespaces converts the egressIPs of each netnamespace to an OVN egressIP
+func ovnEgressIpsFromNetNamespaces(netNamespaceList []*uns.Unstructured) []*uns.Unstructured {
+	egressIpList := []*uns.Unstructured{}
+	for _, nns := range netNamespaceList {
+		if netNamespaceHasEgressIpConfig(*nns) {
 			egressIpName := fmt.Sprint("egressip-", nns.GetName())
 			egressIP := unstructuredEgressIpObject(egressIpName, nns.Object["egressIPs"].([]interface{}), nns.Object["netname"])
 			egressIpList = append(egressIpList, egressIP)
 		}
 	}
-
-	return egressIpList, netNamespaceList, nil // success
+	return egressIpList
 }
 
 func convertOvnEgressIpToSdnEgressIp(ctx context.Context, client cnoclient.Client) error {
@@ -531,27 +560,16 @@ func labelNodeAndRemoveHostSubnetConfig(ctx context.Context, client cnoclient.Cl
 			nodeObj.Labels[egressAssignable] = ""
 		}
 
-		// copy egressCIDRs to node label if possible
-		// - if egressCIDRs contains values (automatic config), on rollback this annotation will provide the respective hostsubnet with its original egressCIDRs values
-		// - if egressCIDRs is empty (manual config), on rollback this label will not exist, and so we default to node's subnet for egressCIDR field.
-		egressCIDRs, found, err := uns.NestedStringSlice(hsn.Object, "egressCIDRs")
+		annotations, err := egressAssignableNodeAnnotations(hsn)
 		if err != nil {
-			return fmt.Errorf("egressCIDRs not found in HostSubnet object %s, probable underlying error: %v", hsn.GetName(), err)
-		} else if found {
-			// automatic configuration
-			ovnMigrationAnnotation := OVNMigrationNodeAnnotation{
-				EgressCIDRs: egressCIDRs,
-			}
-
-			if nodeObj.Annotations == nil {
-				nodeObj.Annotations = make(map[string]string)
-			}
-			if _, ok := nodeObj.Annotations[egressCIDRAnnotationName]; !ok {
-				egressCIDRsText, err := json.Marshal(ovnMigrationAnnotation)
-				if err != nil {
-					return err
-				}
-				nodeObj.Annotations[egressCIDRAnnotationName] = string(egressCIDRsText)
+			return err
+		}
+		if nodeObj.Annotations == nil && len(annotations) > 0 {
+			nodeObj.Annotations = make(map[string]string)
+		}
+		for key, value := range annotations {
+			if _, ok := nodeObj.Annotations[key]; !ok {
+				nodeObj.Annotations[key] = value
 			}
 		}
 		_, err = client.Default().Kubernetes().CoreV1().Nodes().Update(ctx, nodeObj, metav1.UpdateOptions{})
@@ -577,6 +595,25 @@ func labelNodeAndRemoveHostSubnetConfig(ctx context.Context, client cnoclient.Cl
 	return nil
 }
 
+// egressAssignableNodeAnnotations returns the annotations that keep the egressIP configuration
+// of a hostsubnet on its node, for the rollback.
+// - if egressCIDRs contains values (automatic config), on rollback this annotation will provide the respective hostsubnet with its original egressCIDRs values
+// - if egressCIDRs is empty (manual config), on rollback this annotation will not exist, and so we default to node's subnet for egressCIDR field.
+func egressAssignableNodeAnnotations(hsn *uns.Unstructured) (map[string]string, error) {
+	egressCIDRs, found, err := uns.NestedStringSlice(hsn.Object, "egressCIDRs")
+	if err != nil {
+		return nil, fmt.Errorf("egressCIDRs not found in HostSubnet object %s, probable underlying error: %v", hsn.GetName(), err)
+	} else if !found {
+		return nil, nil
+	}
+	// automatic configuration
+	egressCIDRsText, err := json.Marshal(OVNMigrationNodeAnnotation{EgressCIDRs: egressCIDRs})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{egressCIDRAnnotationName: string(egressCIDRsText)}, nil
+}
+
 func deleteCloudPrivateIpConfigs(ctx context.Context, client cnoclient.Client, egressIps []interface{}) error {
 	for _, egressIp := range egressIps {
 		egressIpStr := fmt.Sprintf("%v", egressIp)
@@ -653,11 +690,26 @@ func (r *ReconcileOperConfig) syncNetworkTypeMigrationConditions(ctx context.Con
 		return err
 	}
 
-	_, inProgress, err := r.ensureMachineConfigPools(ctx, clusterConfigUpdated, names.NetworkTypeMigrationMTUReady, routebleMtuUnitMatch, nowTimestamp)
+	mtuApplied, inProgress, err := r.ensureMachineConfigPools(ctx, clusterConfigUpdated, names.NetworkTypeMigrationMTUReady, routebleMtuUnitMatch, nowTimestamp)
 	if err != nil {
 		return err
 	}
 
+	// Each milestone only advances once the dataplane it leaves behind is verified
+	r.migrationMilestoneReached(names.NetworkTypeMigrationMTUReady, mtuApplied, nowTimestamp.Time)
+	r.migrationMilestoneReached(names.NetworkTypeMigrationTargetCNIInUse, targetMachineConfigApplied, nowTimestamp.Time)
+	if mtuApplied {
+		if _, err := r.migrationConnectivityVerified(ctx, operConfig, clusterConfigUpdated, names.NetworkTypeMigrationMTUReady, nowTimestamp); err != nil {
+			return err
+		}
+	}
+	if targetMachineConfigApplied {
+		targetMachineConfigApplied, err = r.migrationConnectivityVerified(ctx, operConfig, clusterConfigUpdated, names.NetworkTypeMigrationTargetCNIInUse, nowTimestamp)
+		if err != nil {
+			return err
+		}
+	}
+
 	if inProgress {
 		// MCP updating is in progress, skip conditions sync
 		klog.Infof("network type migration is in progress")
@@ -672,9 +724,18 @@ func (r *ReconcileOperConfig) syncNetworkTypeMigrationConditions(ctx context.Con
 			return err
 		}
 
+		r.migrationMilestoneReached(names.NetworkTypeMigrationOriginalCNIPurged, originalCNIPurged, nowTimestamp.Time)
+		if originalCNIPurged {
+			originalCNIPurged, err = r.migrationConnectivityVerified(ctx, operConfig, clusterConfigUpdated, names.NetworkTypeMigrationOriginalCNIPurged, nowTimestamp)
+			if err != nil {
+				return err
+			}
+		}
+
 		if originalCNIPurged && targetMachineConfigApplied && targetCNIReady {
 			klog.Infof("network type migration is completed")
 			resetMigrationConditions(&clusterConfigUpdated.Status.Conditions, nowTimestamp)
+			r.migrationMilestones = nil
 		}
 	}
 
@@ -0,0 +1,160 @@
+package operconfig
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operv1 "github.com/openshift/api/operator/v1"
+	operatorcontrolplanev1alpha1 "github.com/openshift/api/operatorcontrolplane/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// connectivityCheckNamespace is where the connectivity check controller
+	// maintains the PodNetworkConnectivityChecks of network-check-source
+	connectivityCheckNamespace = "openshift-network-diagnostics"
+
+	// migrationConnectivityMaxAge is how recent the latest result of each
+	// connectivity check must be to be trusted at a migration milestone
+	migrationConnectivityMaxAge = 5 * time.Minute
+)
+
+// The connectivity verified at each migration milestone
+const (
+	connectivityPodToPod     = "pod-to-pod"
+	connectivityPodToService = "pod-to-service"
+	connectivityPodToAPI     = "pod-to-API"
+)
+
+var migrationConnectivityKinds = []string{connectivityPodToPod, connectivityPodToService, connectivityPodToAPI}
+
+// The reasons of the NetworkTypeMigration conditions held back by the connectivity verification
+const (
+	connectivityCheckPending = "ConnectivityCheckPending"
+	connectivityCheckFailed  = "ConnectivityCheckFailed"
+)
+
+// connectivityCheckKind returns what a PodNetworkConnectivityCheck verifies, from
+// the target in its name, see connectivitycheck.NewNetworkConnectivityCheckController.
+// Checks that do not matter for the migration, like the API load balancers, are ignored.
+func connectivityCheckKind(name string) string {
+	switch {
+	case strings.Contains(name, "-to-network-check-target-service-"):
+		return connectivityPodToService
+	case strings.Contains(name, "-to-network-check-target-"):
+		return connectivityPodToPod
+	case strings.Contains(name, "-to-kubernetes-default-service-"),
+		strings.Contains(name, "-to-kubernetes-apiserver-service-"),
+		strings.Contains(name, "-to-kubernetes-apiserver-endpoint-"),
+		strings.Contains(name, "-to-openshift-apiserver-service-"),
+		strings.Contains(name, "-to-openshift-apiserver-endpoint-"):
+		return connectivityPodToAPI
+	}
+	return ""
+}
+
+// latestConnectivityResult returns the most recent log entry of a check, or nil if it has none
+func latestConnectivityResult(check *operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck) *operatorcontrolplanev1alpha1.LogEntry {
+	var latest *operatorcontrolplanev1alpha1.LogEntry
+	for _, entries := range [][]operatorcontrolplanev1alpha1.LogEntry{check.Status.Successes, check.Status.Failures} {
+		for i := range entries {
+			if latest == nil || entries[i].Start.After(latest.Start.Time) {
+				latest = &entries[i]
+			}
+		}
+	}
+	return latest
+}
+
+// verifyMigrationConnectivity verifies the pod-to-pod, pod-to-service and pod-to-API
+// connectivity from the results of the connectivity checks. It passes when each kind
+// of connectivity is checked, and the latest result of every check is a recent
+// success, started once the milestone was reached. Results from before then tell
+// nothing of the dataplane the milestone leaves behind. Otherwise it returns the
+// reason and message for the held back condition.
+func verifyMigrationConnectivity(checks []operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck, reached, now time.Time) (bool, string, string) {
+	checked := map[string]int{}
+	var failed, stale []string
+	for i := range checks {
+		check := &checks[i]
+		kind := connectivityCheckKind(check.Name)
+		if kind == "" {
+			continue
+		}
+		checked[kind]++
+		latest := latestConnectivityResult(check)
+		switch {
+		case latest == nil || latest.Start.Time.Before(reached) || now.Sub(latest.Start.Time) > migrationConnectivityMaxAge:
+			stale = append(stale, check.Name)
+		case !latest.Success:
+			failed = append(failed, fmt.Sprintf("%s (%s): %s", check.Name, kind, latest.Message))
+		}
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return false, connectivityCheckFailed, fmt.Sprintf("%d connectivity checks failed, first: %s", len(failed), failed[0])
+	}
+	for _, kind := range migrationConnectivityKinds {
+		if checked[kind] == 0 {
+			return false, connectivityCheckPending, fmt.Sprintf("Waiting for the %s connectivity checks in namespace %s", kind, connectivityCheckNamespace)
+		}
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		return false, connectivityCheckPending, fmt.Sprintf("Waiting for a result of %d connectivity checks since %s, first: %s",
+			len(stale), reached.UTC().Format(time.RFC3339), stale[0])
+	}
+	return true, "", ""
+}
+
+// migrationConnectivityVerified is the connectivity verification pass of a migration
+// milestone. The condition of the milestone is only left to advance when it passes,
+// otherwise it is set to False with the reason of the failure.
+func (r *ReconcileOperConfig) migrationConnectivityVerified(ctx context.Context, operConfig *operv1.Network, clusterConfig *configv1.Network, condType string, nowTimestamp metav1.Time) (bool, error) {
+	if operConfig.Spec.DisableNetworkDiagnostics {
+		klog.Warningf("Network diagnostics are disabled, not verifying the connectivity for %s", condType)
+		return true, nil
+	}
+	checks := &operatorcontrolplanev1alpha1.PodNetworkConnectivityCheckList{}
+	if err := r.client.Default().CRClient().List(ctx, checks, &client.ListOptions{Namespace: connectivityCheckNamespace}); err != nil {
+		return false, fmt.Errorf("failed to list the connectivity checks: %w", err)
+	}
+	passed, reason, message := verifyMigrationConnectivity(checks.Items, r.migrationMilestoneReached(condType, true, nowTimestamp.Time), nowTimestamp.Time)
+	if !passed {
+		klog.Infof("The connectivity verification for %s did not pass: %s", condType, message)
+		syncNetworkTypeMigrationCondition(ctx, clusterConfig, &metav1.Condition{
+			Type:               condType,
+			Status:             metav1.ConditionFalse,
+			Reason:             reason,
+			Message:            message,
+			LastTransitionTime: nowTimestamp,
+		})
+	}
+	return passed, nil
+}
+
+// migrationMilestoneReached returns when the milestone condType was first seen
+// reached, recording now if it was not yet. Once the milestone is not reached,
+// it is forgotten. After a restart of the operator, the milestones are taken to
+// be reached when they are first seen again, which only delays them.
+func (r *ReconcileOperConfig) migrationMilestoneReached(condType string, reached bool, now time.Time) time.Time {
+	if !reached {
+		delete(r.migrationMilestones, condType)
+		return time.Time{}
+	}
+	if r.migrationMilestones == nil {
+		r.migrationMilestones = map[string]time.Time{}
+	}
+	if _, ok := r.migrationMilestones[condType]; !ok {
+		r.migrationMilestones[condType] = now
+	}
+	return r.migrationMilestones[condType]
+}
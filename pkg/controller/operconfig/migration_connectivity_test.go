@@ -0,0 +1,133 @@
+package operconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	operv1 "github.com/openshift/api/operator/v1"
+	operatorcontrolplanev1alpha1 "github.com/openshift/api/operatorcontrolplane/v1alpha1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func connectivityCheck(target string, success bool, at time.Time) *operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck {
+	check := &operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "network-check-source-node-a-to-" + target,
+			Namespace: connectivityCheckNamespace,
+		},
+	}
+	entry := operatorcontrolplanev1alpha1.LogEntry{Start: metav1.NewTime(at), Success: success, Message: "connection refused"}
+	if success {
+		check.Status.Successes = []operatorcontrolplanev1alpha1.LogEntry{entry}
+	} else {
+		check.Status.Failures = []operatorcontrolplanev1alpha1.LogEntry{entry}
+	}
+	return check
+}
+
+func TestConnectivityCheckKind(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(connectivityCheckKind("network-check-source-node-a-to-network-check-target-node-b")).To(Equal(connectivityPodToPod))
+	g.Expect(connectivityCheckKind("network-check-source-node-a-to-network-check-target-service-cluster")).To(Equal(connectivityPodToService))
+	g.Expect(connectivityCheckKind("network-check-source-node-a-to-kubernetes-default-service-cluster-0")).To(Equal(connectivityPodToAPI))
+	g.Expect(connectivityCheckKind("network-check-source-node-a-to-kubernetes-apiserver-endpoint-master-0")).To(Equal(connectivityPodToAPI))
+	g.Expect(connectivityCheckKind("network-check-source-node-a-to-load-balancer-api-external")).To(BeEmpty())
+}
+
+func TestVerifyMigrationConnectivity(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Now()
+
+	checks := func(objs ...*operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck) []operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck {
+		out := []operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck{}
+		for _, obj := range objs {
+			out = append(out, *obj)
+		}
+		return out
+	}
+	pod := connectivityCheck("network-check-target-node-b", true, now.Add(-time.Minute))
+	service := connectivityCheck("network-check-target-service-cluster", true, now.Add(-time.Minute))
+	api := connectivityCheck("kubernetes-apiserver-service-cluster", true, now.Add(-time.Minute))
+
+	reached := now.Add(-2 * time.Minute)
+	passed, _, _ := verifyMigrationConnectivity(checks(pod, service, api), reached, now)
+	g.Expect(passed).To(BeTrue())
+
+	// Each kind of connectivity must be checked
+	passed, reason, message := verifyMigrationConnectivity(checks(pod, api), reached, now)
+	g.Expect(passed).To(BeFalse())
+	g.Expect(reason).To(Equal(connectivityCheckPending))
+	g.Expect(message).To(ContainSubstring("pod-to-service"))
+
+	// The latest result must be recent
+	stale := connectivityCheck("network-check-target-node-c", true, now.Add(-time.Hour))
+	passed, reason, _ = verifyMigrationConnectivity(checks(pod, service, api, stale), reached, now)
+	g.Expect(passed).To(BeFalse())
+	g.Expect(reason).To(Equal(connectivityCheckPending))
+
+	// The latest result must be recorded once the milestone was reached
+	passed, reason, message = verifyMigrationConnectivity(checks(pod, service, api), now.Add(-30*time.Second), now)
+	g.Expect(passed).To(BeFalse())
+	g.Expect(reason).To(Equal(connectivityCheckPending))
+	g.Expect(message).To(ContainSubstring("Waiting for a result of 3 connectivity checks since"))
+
+	// The latest result must be a success
+	broken := connectivityCheck("kubernetes-default-service-cluster-0", true, now.Add(-2*time.Minute))
+	broken.Status.Failures = []operatorcontrolplanev1alpha1.LogEntry{{Start: metav1.NewTime(now.Add(-time.Minute)), Message: "i/o timeout"}}
+	passed, reason, message = verifyMigrationConnectivity(checks(pod, service, api, broken), reached, now)
+	g.Expect(passed).To(BeFalse())
+	g.Expect(reason).To(Equal(connectivityCheckFailed))
+	g.Expect(message).To(ContainSubstring("pod-to-API): i/o timeout"))
+}
+
+func TestMigrationConnectivityVerified(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+	// The results are stored with a precision of a second
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+
+	client := fake.NewFakeClient(
+		connectivityCheck("network-check-target-node-b", true, now.Time),
+		connectivityCheck("network-check-target-service-cluster", false, now.Time),
+		connectivityCheck("kubernetes-apiserver-service-cluster", true, now.Time),
+	)
+	r := &ReconcileOperConfig{client: client}
+	operConfig := &operv1.Network{}
+	clusterConfig := &configv1.Network{}
+	meta.SetStatusCondition(&clusterConfig.Status.Conditions, metav1.Condition{
+		Type:   names.NetworkTypeMigrationTargetCNIInUse,
+		Status: metav1.ConditionTrue,
+		Reason: "MachineConfigApplied",
+	})
+
+	passed, err := r.migrationConnectivityVerified(ctx, operConfig, clusterConfig, names.NetworkTypeMigrationTargetCNIInUse, now)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(passed).To(BeFalse())
+	cond := meta.FindStatusCondition(clusterConfig.Status.Conditions, names.NetworkTypeMigrationTargetCNIInUse)
+	g.Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(cond.Reason).To(Equal(connectivityCheckFailed))
+
+	// Results are only trusted from the time the milestone was first seen
+	// reached, so the failure recorded before it is not reported either
+	g.Expect(r.migrationMilestoneReached(names.NetworkTypeMigrationTargetCNIInUse, true, now.Add(time.Minute))).To(Equal(now.Time))
+	r.migrationMilestoneReached(names.NetworkTypeMigrationTargetCNIInUse, false, now.Time)
+	g.Expect(r.migrationMilestoneReached(names.NetworkTypeMigrationTargetCNIInUse, true, now.Add(time.Minute))).To(Equal(now.Add(time.Minute)))
+	passed, err = r.migrationConnectivityVerified(ctx, operConfig, clusterConfig, names.NetworkTypeMigrationTargetCNIInUse, now)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(passed).To(BeFalse())
+	cond = meta.FindStatusCondition(clusterConfig.Status.Conditions, names.NetworkTypeMigrationTargetCNIInUse)
+	g.Expect(cond.Reason).To(Equal(connectivityCheckPending))
+
+	// Without network diagnostics, there is nothing to verify
+	operConfig.Spec.DisableNetworkDiagnostics = true
+	passed, err = r.migrationConnectivityVerified(ctx, operConfig, clusterConfig, names.NetworkTypeMigrationTargetCNIInUse, now)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(passed).To(BeTrue())
+}
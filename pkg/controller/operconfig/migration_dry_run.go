@@ -0,0 +1,244 @@
+package operconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// migrationDryRunConfigMap holds the result of a migration dry run
+	migrationDryRunConfigMap = "network-migration-dry-run"
+	// migrationDryRunObjectsKey is the key of the JSON list of the objects the migration would write
+	migrationDryRunObjectsKey = "objects.json"
+	// migrationDryRunLossesKey is the key of the JSON list of what the conversion would lose or change
+	migrationDryRunLossesKey = "losses.json"
+	// migrationDryRunSummaryKey is the key of the one line summary
+	migrationDryRunSummaryKey = "summary"
+
+	// maxMigrationDryRunSize leaves some room below the 1MiB limit of a ConfigMap
+	maxMigrationDryRunSize = 900 * 1024
+)
+
+// The actions a migration takes on an object, as listed in the dry run
+const (
+	// dryRunApply means that the object is applied as is
+	dryRunApply = "Apply"
+	// dryRunUpdate means that the listed fields of the object are set, or removed when null
+	dryRunUpdate = "Update"
+	// dryRunDelete means that the object is deleted
+	dryRunDelete = "Delete"
+)
+
+// dryRunObject is an object the migration would write
+type dryRunObject struct {
+	Action  string                 `json:"action"`
+	Feature string                 `json:"feature"`
+	Object  map[string]interface{} `json:"object"`
+}
+
+// reconcileMigrationDryRun runs the conversions of the migration to the network
+// type in the NetworkTypeMigrationDryRunAnnotation, for the features selected
+// by spec.migration.features, and writes what they would do to the migration
+// dry run ConfigMap instead of the cluster. The dry run only runs while no
+// migration is in progress. Once the annotation is removed, so is the report.
+func (r *ReconcileOperConfig) reconcileMigrationDryRun(ctx context.Context, operConfig *operv1.Network) error {
+	target, requested := operConfig.Annotations[names.NetworkTypeMigrationDryRunAnnotation]
+	if !requested {
+		return deleteReportConfigMap(ctx, r.client, migrationDryRunConfigMap)
+	}
+	migration := operConfig.Spec.Migration
+	if migration != nil && migration.NetworkType != "" {
+		klog.Infof("Ignoring the migration dry run request, a migration to %s is in progress", migration.NetworkType)
+		return nil
+	}
+
+	var features *operv1.FeaturesMigration
+	if migration != nil {
+		features = migration.Features
+	}
+	var objects []dryRunObject
+	var losses []readinessFinding
+	var err error
+	switch operv1.NetworkType(target) {
+	case operv1.NetworkTypeOVNKubernetes:
+		if operConfig.Spec.DefaultNetwork.Type != operv1.NetworkTypeOpenShiftSDN {
+			return fmt.Errorf("cannot dry run a migration to %s from %s", target, operConfig.Spec.DefaultNetwork.Type)
+		}
+		objects, losses, err = dryRunMigrationToOVN(ctx, r.client, features)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid %s annotation %q: the migration dry run only supports %s",
+			names.NetworkTypeMigrationDryRunAnnotation, target, operv1.NetworkTypeOVNKubernetes)
+	}
+
+	data, err := migrationDryRunData(target, objects, losses)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Migration dry run: %s", data[migrationDryRunSummaryKey])
+	return writeReportConfigMap(ctx, r.client, migrationDryRunConfigMap, data)
+}
+
+// dryRunMigrationToOVN runs the conversions of the selected features from
+// openshift-sdn to OVN-Kubernetes, and returns the objects they would write and
+// the findings of the readiness checks of the same features.
+func dryRunMigrationToOVN(ctx context.Context, client cnoclient.Client, features *operv1.FeaturesMigration) ([]dryRunObject, []readinessFinding, error) {
+	objects := []dryRunObject{}
+	losses := []readinessFinding{}
+
+	if features == nil || features.EgressFirewall {
+		egressNetworkPolicyList, err := cnoclient.ListAllOfSpecifiedType(gvrEgressNetworkPolicy, ctx, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		egressFirewallList, err := egressFirewallsFromEgressNetworkPolicies(egressNetworkPolicyList)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, egressFirewall := range egressFirewallList {
+			objects = append(objects, dryRunObject{Action: dryRunApply, Feature: "EgressFirewall", Object: egressFirewall.Object})
+		}
+		losses = append(losses, egressNetworkPolicyReadiness(egressNetworkPolicyList)...)
+	}
+
+	var netNamespaceList []*uns.Unstructured
+	if features == nil || features.Multicast || features.EgressIP {
+		var err error
+		netNamespaceList, err = cnoclient.ListAllOfSpecifiedType(gvrNetnamespace, ctx, client)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if features == nil || features.Multicast {
+		for _, namespace := range multicastEnabledNetNamespaces(netNamespaceList) {
+			objects = append(objects, dryRunObject{Action: dryRunUpdate, Feature: "Multicast", Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata": map[string]interface{}{
+					"name":        namespace,
+					"annotations": map[string]interface{}{multicastEnabledOVN: "true"},
+				},
+			}})
+		}
+		losses = append(losses, multicastReadiness(netNamespaceList)...)
+	}
+
+	if features == nil || features.EgressIP {
+		hostSubnetList, err := cnoclient.ListAllOfSpecifiedType(gvrHostSubnet, ctx, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		egressIpObjects, err := dryRunEgressIpMigrationToOVN(hostSubnetList, netNamespaceList)
+		if err != nil {
+			return nil, nil, err
+		}
+		objects = append(objects, egressIpObjects...)
+		losses = append(losses, egressIPReadiness(hostSubnetList, netNamespaceList)...)
+	}
+	return objects, losses, nil
+}
+
+// dryRunEgressIpMigrationToOVN lists the changes of convertSdnEgressIpToOvnEgressIp
+// and applyEgressIpList, in the same order.
+func dryRunEgressIpMigrationToOVN(hostSubnetList, netNamespaceList []*uns.Unstructured) ([]dryRunObject, error) {
+	objects := []dryRunObject{}
+	egressHostSubnets := egressIpHostSubnets(hostSubnetList)
+	for _, hsn := range egressHostSubnets {
+		annotations, err := egressAssignableNodeAnnotations(hsn)
+		if err != nil {
+			return nil, err
+		}
+		metadata := map[string]interface{}{
+			"name":   fmt.Sprint(hsn.Object["host"]),
+			"labels": map[string]interface{}{egressAssignable: ""},
+		}
+		if len(annotations) > 0 {
+			metadata["annotations"] = map[string]interface{}{egressCIDRAnnotationName: annotations[egressCIDRAnnotationName]}
+		}
+		objects = append(objects,
+			dryRunObject{Action: dryRunUpdate, Feature: "EgressIP", Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Node",
+				"metadata":   metadata,
+			}},
+			dryRunObject{Action: dryRunUpdate, Feature: "EgressIP", Object: map[string]interface{}{
+				"apiVersion":  "network.openshift.io/v1",
+				"kind":        "HostSubnet",
+				"metadata":    map[string]interface{}{"name": hsn.GetName()},
+				"egressCIDRs": nil,
+				"egressIPs":   nil,
+			}})
+	}
+	if len(egressHostSubnets) == 0 {
+		// nothing else is converted, see convertSdnEgressIpToOvnEgressIp
+		return objects, nil
+	}
+
+	for _, nns := range netNamespaceList {
+		if !netNamespaceHasEgressIpConfig(*nns) {
+			continue
+		}
+		for _, egressIp := range nns.Object["egressIPs"].([]interface{}) {
+			objects = append(objects, dryRunObject{Action: dryRunDelete, Feature: "EgressIP", Object: map[string]interface{}{
+				"apiVersion": "cloud.network.openshift.io/v1",
+				"kind":       "CloudPrivateIPConfig",
+				"metadata":   map[string]interface{}{"name": fmt.Sprint(egressIp)},
+			}})
+		}
+	}
+	for _, egressIp := range ovnEgressIpsFromNetNamespaces(netNamespaceList) {
+		objects = append(objects, dryRunObject{Action: dryRunApply, Feature: "EgressIP", Object: egressIp.Object})
+	}
+	for _, nns := range netNamespaceList {
+		if netNamespaceHasEgressIpConfig(*nns) {
+			objects = append(objects, dryRunObject{Action: dryRunUpdate, Feature: "EgressIP", Object: map[string]interface{}{
+				"apiVersion": "network.openshift.io/v1",
+				"kind":       "NetNamespace",
+				"metadata":   map[string]interface{}{"name": nns.GetName()},
+				"egressIPs":  nil,
+			}})
+		}
+	}
+	return objects, nil
+}
+
+// migrationDryRunData builds the content of the migration dry run ConfigMap.
+// If the objects do not fit, only the losses and the summary are kept.
+func migrationDryRunData(target string, objects []dryRunObject, losses []readinessFinding) (map[string]string, error) {
+	objectsJSON, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	lossesJSON, err := json.MarshalIndent(losses, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	blockers := 0
+	for _, loss := range losses {
+		if loss.Severity == readinessBlocker {
+			blockers++
+		}
+	}
+	summary := fmt.Sprintf("The migration to %s would write %d objects, with %d losses of which %d are blocking",
+		target, len(objects), len(losses), blockers)
+	if len(objectsJSON)+len(lossesJSON) > maxMigrationDryRunSize {
+		summary += "; the objects are too large to be listed"
+		objectsJSON = []byte("[]")
+	}
+	return map[string]string{
+		migrationDryRunObjectsKey: string(objectsJSON),
+		migrationDryRunLossesKey:  string(lossesJSON),
+		migrationDryRunSummaryKey: summary,
+	}, nil
+}
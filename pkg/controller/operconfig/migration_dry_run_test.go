@@ -0,0 +1,136 @@
+package operconfig
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// dryRunSummary lists the action, kind and name of each object of a dry run
+func dryRunSummary(objects []dryRunObject) []string {
+	out := []string{}
+	for _, obj := range objects {
+		u := uns.Unstructured{Object: obj.Object}
+		name := u.GetName()
+		if u.GetNamespace() != "" {
+			name = u.GetNamespace() + "/" + name
+		}
+		out = append(out, obj.Action+" "+u.GetKind()+" "+name)
+	}
+	return out
+}
+
+func TestDryRunEgressIpMigrationToOVN(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	nns := netNamespace("alpha", 10)
+	nns.Object["egressIPs"] = []interface{}{"10.0.0.10", "10.0.0.11"}
+	netNamespaces := []*uns.Unstructured{nns, netNamespace("beta", 11)}
+
+	// Without a HostSubnet holding an egress IP, nothing is converted
+	objects, err := dryRunEgressIpMigrationToOVN([]*uns.Unstructured{hostSubnet("node-a", nil, nil)}, netNamespaces)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objects).To(BeEmpty())
+
+	objects, err = dryRunEgressIpMigrationToOVN([]*uns.Unstructured{
+		hostSubnet("node-a", []interface{}{"10.0.0.10"}, []interface{}{"10.0.0.0/24"}),
+		hostSubnet("node-b", nil, nil),
+	}, netNamespaces)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(dryRunSummary(objects)).To(Equal([]string{
+		"Update Node node-a",
+		"Update HostSubnet node-a",
+		"Delete CloudPrivateIPConfig 10.0.0.10",
+		"Delete CloudPrivateIPConfig 10.0.0.11",
+		"Apply EgressIP egressip-alpha",
+		"Update NetNamespace alpha",
+	}))
+	node := uns.Unstructured{Object: objects[0].Object}
+	g.Expect(node.GetLabels()).To(HaveKey(egressAssignable))
+	g.Expect(node.GetAnnotations()).To(HaveKeyWithValue(egressCIDRAnnotationName, `{"EgressCIDRs":["10.0.0.0/24"]}`))
+}
+
+func TestReconcileMigrationDryRun(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	operConfig := &operv1.Network{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        names.OPERATOR_CONFIG,
+			Annotations: map[string]string{names.NetworkTypeMigrationDryRunAnnotation: string(operv1.NetworkTypeOVNKubernetes)},
+		},
+		Spec: *sdnSpec(operv1.SDNModeNetworkPolicy),
+	}
+	multicast := netNamespace("alpha", 10)
+	multicast.SetAnnotations(map[string]string{multicastEnabledSDN: "true"})
+	multicast.Object["egressIPs"] = []interface{}{"10.0.0.10"}
+	client := fake.NewFakeClient(
+		operConfig,
+		hostSubnet("node-a", []interface{}{"10.0.0.10"}, []interface{}{"10.0.0.0/24"}),
+		multicast,
+		egressNetworkPolicy("alpha", "first", egressRule("Deny", "cidrSelector", "0.0.0.0/0")),
+		egressNetworkPolicy("alpha", "second", egressRule("Deny", "cidrSelector", "0.0.0.0/0")),
+	)
+	r := &ReconcileOperConfig{client: client}
+	reportName := types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: migrationDryRunConfigMap}
+	report := func() ([]dryRunObject, []readinessFinding, string) {
+		cm := &corev1.ConfigMap{}
+		g.Expect(client.Default().CRClient().Get(ctx, reportName, cm)).To(Succeed())
+		objects := []dryRunObject{}
+		g.Expect(json.Unmarshal([]byte(cm.Data[migrationDryRunObjectsKey]), &objects)).To(Succeed())
+		losses := []readinessFinding{}
+		g.Expect(json.Unmarshal([]byte(cm.Data[migrationDryRunLossesKey]), &losses)).To(Succeed())
+		return objects, losses, cm.Data[migrationDryRunSummaryKey]
+	}
+
+	g.Expect(r.reconcileMigrationDryRun(ctx, operConfig)).To(Succeed())
+	objects, losses, summary := report()
+	g.Expect(dryRunSummary(objects)).To(Equal([]string{
+		"Apply EgressFirewall alpha/default",
+		"Apply EgressFirewall alpha/default",
+		"Update Namespace alpha",
+		"Update Node node-a",
+		"Update HostSubnet node-a",
+		"Delete CloudPrivateIPConfig 10.0.0.10",
+		"Apply EgressIP egressip-alpha",
+		"Update NetNamespace alpha",
+	}))
+	g.Expect(losses).To(HaveLen(2))
+	g.Expect(summary).To(Equal("The migration to OVNKubernetes would write 8 objects, with 2 losses of which 1 are blocking"))
+
+	// Nothing was written to the cluster
+	hsn, err := client.Default().Dynamic().Resource(gvrHostSubnet).Get(ctx, "node-a", metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hsn.Object["egressIPs"]).To(Equal([]interface{}{"10.0.0.10"}))
+	nns, err := client.Default().Dynamic().Resource(gvrNetnamespace).Get(ctx, "alpha", metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(nns.Object["egressIPs"]).To(Equal([]interface{}{"10.0.0.10"}))
+
+	// Only the selected features are run
+	operConfig.Spec.Migration = &operv1.NetworkMigration{Features: &operv1.FeaturesMigration{Multicast: true}}
+	g.Expect(r.reconcileMigrationDryRun(ctx, operConfig)).To(Succeed())
+	objects, losses, _ = report()
+	g.Expect(dryRunSummary(objects)).To(Equal([]string{"Update Namespace alpha"}))
+	g.Expect(losses).To(HaveLen(1))
+
+	// Not during a migration, and only to OVNKubernetes
+	operConfig.Spec.Migration.NetworkType = string(operv1.NetworkTypeOVNKubernetes)
+	g.Expect(r.reconcileMigrationDryRun(ctx, operConfig)).To(Succeed())
+	operConfig.Spec.Migration = nil
+	operConfig.Annotations[names.NetworkTypeMigrationDryRunAnnotation] = string(operv1.NetworkTypeOpenShiftSDN)
+	g.Expect(r.reconcileMigrationDryRun(ctx, operConfig)).To(MatchError(ContainSubstring("only supports OVNKubernetes")))
+
+	// The report is removed with the annotation
+	operConfig.Annotations = nil
+	g.Expect(r.reconcileMigrationDryRun(ctx, operConfig)).To(Succeed())
+	g.Expect(client.Default().CRClient().Get(ctx, reportName, &corev1.ConfigMap{})).NotTo(Succeed())
+}
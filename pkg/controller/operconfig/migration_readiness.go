@@ -0,0 +1,515 @@
+package operconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+	"github.com/openshift/cluster-network-operator/pkg/util"
+	iputil "github.com/openshift/cluster-network-operator/pkg/util/ip"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// migrationReadinessConfigMap holds the readiness report for the live
+	// migration from openshift-sdn to OVN-Kubernetes
+	migrationReadinessConfigMap = "sdn-to-ovn-migration-readiness"
+	// migrationReadinessReportKey is the key of the JSON list of findings
+	migrationReadinessReportKey = "report.json"
+	// migrationReadinessSummaryKey is the key of the one line summary, the
+	// same as the message of the OVNKubernetesMigrationReady condition
+	migrationReadinessSummaryKey = "summary"
+
+	// The subnets used internally by ovn-kubernetes when they are not set in
+	// the ovnKubernetesConfig. openshift-sdn is IPv4 only.
+	defaultV4JoinSubnet          = "100.64.0.0/16"
+	defaultV4MasqueradeSubnet    = "169.254.169.0/29"
+	defaultV4TransitSwitchSubnet = "100.88.0.0/16"
+
+	// The per packet overhead of the overlay of each network plugin
+	vxlanOverhead  = 50
+	geneveOverhead = 100
+)
+
+// egressFirewallDNSNameRegexp is the validation of dnsName in the EgressFirewall CRD,
+// which is stricter than the one of EgressNetworkPolicy.
+var egressFirewallDNSNameRegexp = regexp.MustCompile(`^(\*\.)?([A-Za-z0-9-]+\.)*[A-Za-z0-9-]+\.?$`)
+
+type readinessSeverity string
+
+const (
+	// readinessBlocker findings are configurations that the migration loses or
+	// that break the cluster network once migrated
+	readinessBlocker readinessSeverity = "Blocker"
+	// readinessWarning findings are configurations that are migrated, but
+	// behave differently with OVN-Kubernetes
+	readinessWarning readinessSeverity = "Warning"
+	// readinessInfo findings are migrated as they are
+	readinessInfo readinessSeverity = "Info"
+)
+
+// readinessFinding is an entry of the migration readiness report
+type readinessFinding struct {
+	Check    string            `json:"check"`
+	Severity readinessSeverity `json:"severity"`
+	Object   string            `json:"object,omitempty"`
+	Message  string            `json:"message"`
+}
+
+// reconcileMigrationReadiness inspects an openshift-sdn cluster for what the
+// live migration to OVN-Kubernetes cannot carry over, and writes the findings
+// to the migration readiness ConfigMap and the OVNKubernetesMigrationReady
+// condition. The report describes the cluster before the migration, so it is
+// left as is once a migration has started, and removed from clusters that do
+// not run openshift-sdn.
+func (r *ReconcileMigrationReadiness) reconcileMigrationReadiness(ctx context.Context, operConfig *operv1.Network) error {
+	if operConfig.Spec.Migration != nil && operConfig.Spec.Migration.NetworkType != "" {
+		return nil
+	}
+	if operConfig.Spec.DefaultNetwork.Type != operv1.NetworkTypeOpenShiftSDN {
+		if err := deleteReportConfigMap(ctx, r.client, migrationReadinessConfigMap); err != nil {
+			return err
+		}
+		return statusmanager.RemoveOperatorConditions(ctx, r.client, names.OVNKubernetesMigrationReady)
+	}
+
+	if err := r.watchSDNObjects(); err != nil {
+		return err
+	}
+	findings, err := migrationReadinessFindings(ctx, r.reader, r.client, &operConfig.Spec)
+	if err != nil {
+		return err
+	}
+	condition := migrationReadinessCondition(findings)
+	report, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		migrationReadinessReportKey:  string(report),
+		migrationReadinessSummaryKey: condition.Message,
+	}
+	if err := writeReportConfigMap(ctx, r.client, migrationReadinessConfigMap, data); err != nil {
+		return fmt.Errorf("could not write the migration readiness report: %w", err)
+	}
+	return statusmanager.SetOperatorConditions(ctx, r.client, condition)
+}
+
+// writeReportConfigMap creates or updates a report ConfigMap of the operator namespace
+func writeReportConfigMap(ctx context.Context, client cnoclient.Client, name string, data map[string]string) error {
+	cm := &corev1.ConfigMap{}
+	err := client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: name}, cm)
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: names.APPLIED_NAMESPACE, Name: name},
+			Data:       data,
+		}
+		return client.Default().CRClient().Create(ctx, cm)
+	} else if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(cm.Data, data) {
+		return nil
+	}
+	cm.Data = data
+	return client.Default().CRClient().Update(ctx, cm)
+}
+
+// deleteReportConfigMap deletes a report ConfigMap of the operator namespace, if it exists
+func deleteReportConfigMap(ctx context.Context, client cnoclient.Client, name string) error {
+	cm := &corev1.ConfigMap{}
+	err := client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: name}, cm)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := client.Default().CRClient().Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// migrationReadinessFindings runs every readiness check against the cluster.
+// The openshift-sdn objects are read from reader, which is the cache of the
+// manager.
+func migrationReadinessFindings(ctx context.Context, reader crclient.Reader, client cnoclient.Client, spec *operv1.NetworkSpec) ([]readinessFinding, error) {
+	hostSubnets, err := listSDNObjects(ctx, reader, hostSubnetGVK)
+	if err != nil {
+		return nil, err
+	}
+	netNamespaces, err := listSDNObjects(ctx, reader, netNamespaceGVK)
+	if err != nil {
+		return nil, err
+	}
+	egressNetworkPolicies, err := listSDNObjects(ctx, reader, egressNetworkPolicyGVK)
+	if err != nil {
+		return nil, err
+	}
+	// The machine MTU is kept by the migration. Without the probed value,
+	// assume the current MTU leaves exactly the VXLAN overhead.
+	machineMTU, err := util.ReadMTUConfigMap(ctx, client)
+	if err != nil {
+		klog.Infof("Could not read the machine MTU, assuming the VXLAN overhead only: %v", err)
+		machineMTU = 0
+	}
+
+	findings := egressIPReadiness(hostSubnets, netNamespaces)
+	findings = append(findings, egressNetworkPolicyReadiness(egressNetworkPolicies)...)
+	findings = append(findings, multicastReadiness(netNamespaces)...)
+	findings = append(findings, internalSubnetReadiness(spec)...)
+	findings = append(findings, mtuReadiness(spec, machineMTU)...)
+	return findings, nil
+}
+
+// listSDNObjects lists the openshift-sdn objects of kind gvk, which have no
+// typed client.
+func listSDNObjects(ctx context.Context, reader crclient.Reader, gvk schema.GroupVersionKind) ([]*uns.Unstructured, error) {
+	list := &uns.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := reader.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("could not list %ss: %w", gvk.Kind, err)
+	}
+	objects := make([]*uns.Unstructured, 0, len(list.Items))
+	for i := range list.Items {
+		objects = append(objects, &list.Items[i])
+	}
+	return objects, nil
+}
+
+// egressIPReadiness reports the egress IP layouts that convertSdnEgressIpToOvnEgressIp
+// cannot express: only the nodes whose HostSubnet currently holds egress IPs
+// become egress-assignable, and each NetNamespace becomes a single EgressIP.
+func egressIPReadiness(hostSubnets, netNamespaces []*uns.Unstructured) []readinessFinding {
+	findings := []readinessFinding{}
+	hostSubnetFound := false
+	egressCIDRs := []net.IPNet{}
+	manualIPs := sets.New[string]()
+	for _, hsn := range hostSubnets {
+		object := "hostsubnet/" + hsn.GetName()
+		switch {
+		case hostSubnetHasEgressIpConfigAutomatic(*hsn):
+			hostSubnetFound = true
+			for _, cidr := range stringList(hsn.Object["egressCIDRs"]) {
+				if _, n, err := net.ParseCIDR(cidr); err == nil {
+					egressCIDRs = append(egressCIDRs, *n)
+				}
+			}
+		case hostSubnetHasEgressIpConfigManual(*hsn):
+			hostSubnetFound = true
+			ips := stringList(hsn.Object["egressIPs"])
+			manualIPs.Insert(ips...)
+			findings = append(findings, readinessFinding{
+				Check:    "EgressIP",
+				Severity: readinessWarning,
+				Object:   object,
+				Message: fmt.Sprintf("egress IPs %s are assigned manually to node %v; OVN-Kubernetes may assign them to any egress-assignable node",
+					strings.Join(ips, ", "), hsn.Object["host"]),
+			})
+		case len(stringList(hsn.Object["egressCIDRs"])) > 0:
+			findings = append(findings, readinessFinding{
+				Check:    "EgressIP",
+				Severity: readinessWarning,
+				Object:   object,
+				Message: fmt.Sprintf("node %v has egressCIDRs but holds no egress IP, so it will not be made egress-assignable and will not host egress IPs after the migration",
+					hsn.Object["host"]),
+			})
+		}
+	}
+
+	for _, nns := range netNamespaces {
+		if !netNamespaceHasEgressIpConfig(*nns) {
+			continue
+		}
+		object := "netnamespace/" + nns.GetName()
+		ips := stringList(nns.Object["egressIPs"])
+		if !hostSubnetFound {
+			findings = append(findings, readinessFinding{
+				Check:    "EgressIP",
+				Severity: readinessBlocker,
+				Object:   object,
+				Message: fmt.Sprintf("egress IPs %s will not be migrated: no HostSubnet holds an egress IP, so no EgressIP object is created",
+					strings.Join(ips, ", ")),
+			})
+			continue
+		}
+		if len(ips) > 1 {
+			findings = append(findings, readinessFinding{
+				Check:    "EgressIP",
+				Severity: readinessWarning,
+				Object:   object,
+				Message: fmt.Sprintf("egress IPs %s are used one at a time by openshift-sdn, OVN-Kubernetes balances the traffic across all of them",
+					strings.Join(ips, ", ")),
+			})
+		}
+		for _, ip := range ips {
+			if manualIPs.Has(ip) || inAnyNet(ip, egressCIDRs) {
+				continue
+			}
+			findings = append(findings, readinessFinding{
+				Check:    "EgressIP",
+				Severity: readinessWarning,
+				Object:   object,
+				Message:  fmt.Sprintf("egress IP %s is not within the egressCIDRs of any node that will be made egress-assignable", ip),
+			})
+		}
+	}
+	return findings
+}
+
+// egressNetworkPolicyReadiness reports the EgressNetworkPolicies that
+// convertEgressNetworkPolicyToEgressFirewall does not convert faithfully. The
+// spec is copied as is to the only EgressFirewall of the namespace, so it must
+// also be a valid EgressFirewall spec.
+func egressNetworkPolicyReadiness(egressNetworkPolicies []*uns.Unstructured) []readinessFinding {
+	findings := []readinessFinding{}
+	byNamespace := map[string][]string{}
+	for _, enp := range egressNetworkPolicies {
+		byNamespace[enp.GetNamespace()] = append(byNamespace[enp.GetNamespace()], enp.GetName())
+		object := fmt.Sprintf("egressnetworkpolicy/%s/%s", enp.GetNamespace(), enp.GetName())
+		blocker := func(format string, args ...interface{}) {
+			findings = append(findings, readinessFinding{
+				Check:    "EgressNetworkPolicy",
+				Severity: readinessBlocker,
+				Object:   object,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		rules, _, err := uns.NestedSlice(enp.Object, "spec", "egress")
+		if err != nil {
+			blocker("spec.egress is malformed: %v", err)
+			continue
+		}
+		for i, rule := range rules {
+			r, ok := rule.(map[string]interface{})
+			if !ok {
+				blocker("rule %d is malformed", i)
+				continue
+			}
+			ruleType, _, _ := uns.NestedString(r, "type")
+			cidrSelector, _, _ := uns.NestedString(r, "to", "cidrSelector")
+			dnsName, _, _ := uns.NestedString(r, "to", "dnsName")
+			switch {
+			case ruleType != "Allow" && ruleType != "Deny":
+				blocker("rule %d has type %q, EgressFirewall rules must be Allow or Deny", i, ruleType)
+			case (cidrSelector == "") == (dnsName == ""):
+				blocker("rule %d must set exactly one of cidrSelector and dnsName", i)
+			case cidrSelector != "":
+				if _, _, err := net.ParseCIDR(cidrSelector); err != nil {
+					blocker("rule %d has an invalid cidrSelector %q", i, cidrSelector)
+				}
+			case !egressFirewallDNSNameRegexp.MatchString(dnsName):
+				blocker("rule %d has a dnsName %q that is not valid in an EgressFirewall", i, dnsName)
+			}
+		}
+	}
+
+	namespaces := make([]string, 0, len(byNamespace))
+	for namespace := range byNamespace {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		if policies := byNamespace[namespace]; len(policies) > 1 {
+			sort.Strings(policies)
+			findings = append(findings, readinessFinding{
+				Check:    "EgressNetworkPolicy",
+				Severity: readinessBlocker,
+				Object:   "namespace/" + namespace,
+				Message: fmt.Sprintf("EgressNetworkPolicies %s are all converted to the EgressFirewall %q, only one of them is kept",
+					strings.Join(policies, ", "), defaultEgressFirewallName),
+			})
+		}
+	}
+	return findings
+}
+
+// multicastReadiness reports the namespaces with multicast enabled. With
+// OVN-Kubernetes, multicast traffic does not cross namespaces, even those that
+// share a NetNamespace in the Multitenant mode.
+func multicastReadiness(netNamespaces []*uns.Unstructured) []readinessFinding {
+	findings := []readinessFinding{}
+	byNetID := map[int64][]string{}
+	for _, nns := range netNamespaces {
+		if netID, found, err := uns.NestedInt64(nns.Object, "netid"); err == nil && found {
+			byNetID[netID] = append(byNetID[netID], nns.GetName())
+		}
+	}
+	for _, nns := range netNamespaces {
+		if nns.GetAnnotations()[multicastEnabledSDN] != "true" {
+			continue
+		}
+		object := "netnamespace/" + nns.GetName()
+		netID, _, _ := uns.NestedInt64(nns.Object, "netid")
+		if joined := byNetID[netID]; netID != globalNetID && len(joined) > 1 {
+			findings = append(findings, readinessFinding{
+				Check:    "Multicast",
+				Severity: readinessWarning,
+				Object:   object,
+				Message: fmt.Sprintf("multicast is enabled on a network shared with namespaces %s, OVN-Kubernetes only delivers multicast within the namespace",
+					strings.Join(joined, ", ")),
+			})
+			continue
+		}
+		findings = append(findings, readinessFinding{
+			Check:    "Multicast",
+			Severity: readinessInfo,
+			Object:   object,
+			Message:  fmt.Sprintf("multicast is enabled, it will be enabled with the %s annotation", multicastEnabledOVN),
+		})
+	}
+	return findings
+}
+
+// internalSubnetReadiness reports the cluster and service networks that
+// overlap with the subnets ovn-kubernetes uses internally. These can only be
+// moved with the ovnKubernetesConfig before the migration starts.
+func internalSubnetReadiness(spec *operv1.NetworkSpec) []readinessFinding {
+	type internalSubnet struct {
+		name, cidr, field string
+	}
+	join := internalSubnet{"join", defaultV4JoinSubnet, "v4InternalSubnet"}
+	masquerade := internalSubnet{"masquerade", defaultV4MasqueradeSubnet, "gatewayConfig.ipv4.internalMasqueradeSubnet"}
+	if oc := spec.DefaultNetwork.OVNKubernetesConfig; oc != nil {
+		if oc.V4InternalSubnet != "" {
+			join.cidr = oc.V4InternalSubnet
+		}
+		if oc.GatewayConfig != nil && oc.GatewayConfig.IPv4.InternalMasqueradeSubnet != "" {
+			masquerade.cidr = oc.GatewayConfig.IPv4.InternalMasqueradeSubnet
+		}
+	}
+	internalSubnets := []internalSubnet{join, masquerade, {"transit switch", defaultV4TransitSwitchSubnet, ""}}
+
+	clusterCIDRs := []string{}
+	for _, cn := range spec.ClusterNetwork {
+		clusterCIDRs = append(clusterCIDRs, cn.CIDR)
+	}
+	findings := []readinessFinding{}
+	check := func(kind string, cidrs []string) {
+		for _, cidr := range cidrs {
+			_, cnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+			for _, internal := range internalSubnets {
+				_, inet, err := net.ParseCIDR(internal.cidr)
+				if err != nil || !iputil.NetsOverlap(*cnet, *inet) {
+					continue
+				}
+				message := fmt.Sprintf("%s %s overlaps with the ovn-kubernetes %s subnet %s", kind, cidr, internal.name, internal.cidr)
+				if internal.field != "" {
+					message += fmt.Sprintf(", set another subnet in ovnKubernetesConfig.%s", internal.field)
+				}
+				findings = append(findings, readinessFinding{
+					Check:    "InternalSubnets",
+					Severity: readinessBlocker,
+					Message:  message,
+				})
+			}
+		}
+	}
+	check("cluster network", clusterCIDRs)
+	check("service network", spec.ServiceNetwork)
+	return findings
+}
+
+// mtuReadiness reports whether the machine MTU leaves enough room for the
+// Geneve overhead. The migration keeps the machine MTU, so the pod MTU shrinks
+// by the difference between the Geneve and VXLAN overheads.
+func mtuReadiness(spec *operv1.NetworkSpec, machineMTU int) []readinessFinding {
+	sc := spec.DefaultNetwork.OpenShiftSDNConfig
+	if sc == nil || sc.MTU == nil {
+		return nil
+	}
+	current := int(*sc.MTU)
+	if machineMTU == 0 {
+		machineMTU = current + vxlanOverhead
+	}
+	migrated := machineMTU - geneveOverhead
+	switch {
+	case migrated < int(network.MinMTUIPv4):
+		return []readinessFinding{{
+			Check:    "MTU",
+			Severity: readinessBlocker,
+			Message: fmt.Sprintf("the machine MTU %d leaves a pod MTU of %d with Geneve, below the minimum of %d",
+				machineMTU, migrated, network.MinMTUIPv4),
+		}}
+	case migrated < current:
+		return []readinessFinding{{
+			Check:    "MTU",
+			Severity: readinessInfo,
+			Message:  fmt.Sprintf("the pod MTU will decrease from %d to %d to make room for the Geneve overhead", current, migrated),
+		}}
+	}
+	return nil
+}
+
+// migrationReadinessCondition summarizes the findings in the
+// OVNKubernetesMigrationReady condition, which is False if any of them is a
+// Blocker.
+func migrationReadinessCondition(findings []readinessFinding) operv1.OperatorCondition {
+	counts := map[readinessSeverity]int{}
+	var firstBlocker *readinessFinding
+	for i := range findings {
+		counts[findings[i].Severity]++
+		if findings[i].Severity == readinessBlocker && firstBlocker == nil {
+			firstBlocker = &findings[i]
+		}
+	}
+	message := fmt.Sprintf("The migration to OVNKubernetes has %d blocking and %d warning findings, see ConfigMap %s/%s",
+		counts[readinessBlocker], counts[readinessWarning], names.APPLIED_NAMESPACE, migrationReadinessConfigMap)
+	if firstBlocker != nil {
+		return operv1.OperatorCondition{
+			Type:    names.OVNKubernetesMigrationReady,
+			Status:  operv1.ConditionFalse,
+			Reason:  "MigrationBlocked",
+			Message: fmt.Sprintf("%s. First blocker: %s", message, firstBlocker.Message),
+		}
+	}
+	return operv1.OperatorCondition{
+		Type:    names.OVNKubernetesMigrationReady,
+		Status:  operv1.ConditionTrue,
+		Reason:  "Ready",
+		Message: message,
+	}
+}
+
+// stringList returns the string items of an unstructured list field
+func stringList(field interface{}) []string {
+	items, _ := field.([]interface{})
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, fmt.Sprint(item))
+	}
+	return out
+}
+
+func inAnyNet(ip string, nets []net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	for _, n := range nets {
+		if parsed != nil && n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package operconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	crcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// The kinds of the openshift-sdn objects the migration readiness is checked
+// against
+var (
+	hostSubnetGVK          = gvrHostSubnet.GroupVersion().WithKind("HostSubnet")
+	netNamespaceGVK        = gvrNetnamespace.GroupVersion().WithKind("NetNamespace")
+	egressNetworkPolicyGVK = gvrEgressNetworkPolicy.GroupVersion().WithKind("EgressNetworkPolicy")
+)
+
+// AddMigrationReadiness creates the controller of the migration readiness
+// report and adds it to the Manager. The report is only written again when the
+// operator configuration or the openshift-sdn objects it checks change.
+func AddMigrationReadiness(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client) error {
+	r := &ReconcileMigrationReadiness{
+		client: c,
+		status: status,
+		reader: mgr.GetCache(),
+		cache:  mgr.GetCache(),
+	}
+	ctrl, err := controller.New("migration-readiness-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	r.controller = ctrl
+
+	return ctrl.Watch(source.Kind(mgr.GetCache(), &operv1.Network{}),
+		handler.EnqueueRequestsFromMapFunc(migrationReadinessRequest),
+		predicate.Funcs{
+			UpdateFunc: func(evt event.UpdateEvent) bool {
+				old, ok := evt.ObjectOld.(*operv1.Network)
+				if !ok {
+					return true
+				}
+				new, ok := evt.ObjectNew.(*operv1.Network)
+				if !ok {
+					return true
+				}
+				return !reflect.DeepEqual(old.Spec, new.Spec)
+			},
+		})
+}
+
+var _ reconcile.Reconciler = &ReconcileMigrationReadiness{}
+
+// ReconcileMigrationReadiness writes the readiness report for the live
+// migration from openshift-sdn to OVN-Kubernetes.
+type ReconcileMigrationReadiness struct {
+	client cnoclient.Client
+	status *statusmanager.StatusManager
+	// reader reads the operator configuration and the openshift-sdn objects
+	// from the cache of the manager.
+	reader crclient.Reader
+
+	// The controller and the cache of its watches, to watch the openshift-sdn
+	// objects once the cluster is found to run openshift-sdn, as they do not
+	// exist otherwise.
+	controller controller.Controller
+	cache      crcache.Cache
+	sdnWatched sets.Set[schema.GroupVersionKind]
+}
+
+// Reconcile writes the migration readiness report of the operator configuration.
+func (r *ReconcileMigrationReadiness) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
+
+	operConfig := &operv1.Network{}
+	if err := r.reader.Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	// The readiness report is advisory, failing to write it must not degrade the operator
+	if err := r.reconcileMigrationReadiness(ctx, operConfig); err != nil {
+		klog.Warningf("Could not update the migration readiness report: %v", err)
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// watchSDNObjects triggers a reconciliation on changes to the openshift-sdn
+// objects the readiness is checked against.
+func (r *ReconcileMigrationReadiness) watchSDNObjects() error {
+	if r.controller == nil {
+		return nil
+	}
+	if r.sdnWatched == nil {
+		r.sdnWatched = sets.New[schema.GroupVersionKind]()
+	}
+	for _, gvk := range []schema.GroupVersionKind{hostSubnetGVK, netNamespaceGVK, egressNetworkPolicyGVK} {
+		if r.sdnWatched.Has(gvk) {
+			continue
+		}
+		obj := &uns.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		if err := r.controller.Watch(source.Kind(r.cache, obj), handler.EnqueueRequestsFromMapFunc(migrationReadinessRequest)); err != nil {
+			return fmt.Errorf("could not watch %ss: %w", gvk.Kind, err)
+		}
+		r.sdnWatched.Insert(gvk)
+	}
+	return nil
+}
+
+// migrationReadinessRequest maps the events of the watched objects to the
+// single request of the controller.
+func migrationReadinessRequest(_ context.Context, _ crclient.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: names.OPERATOR_CONFIG}}}
+}
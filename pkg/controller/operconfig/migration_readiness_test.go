@@ -0,0 +1,246 @@
+package operconfig
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func hostSubnet(host string, egressIPs, egressCIDRs []interface{}) *uns.Unstructured {
+	hsn := &uns.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "network.openshift.io/v1",
+			"kind":       "HostSubnet",
+			"host":       host,
+			"metadata": map[string]interface{}{
+				"name": host,
+			},
+		},
+	}
+	if egressIPs != nil {
+		hsn.Object["egressIPs"] = egressIPs
+	}
+	if egressCIDRs != nil {
+		hsn.Object["egressCIDRs"] = egressCIDRs
+	}
+	return hsn
+}
+
+func egressNetworkPolicy(namespace, name string, rules ...interface{}) *uns.Unstructured {
+	return &uns.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "network.openshift.io/v1",
+			"kind":       "EgressNetworkPolicy",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"egress": rules,
+			},
+		},
+	}
+}
+
+func egressRule(ruleType, key, value string) interface{} {
+	return map[string]interface{}{
+		"type": ruleType,
+		"to":   map[string]interface{}{key: value},
+	}
+}
+
+// findingsFor returns the severity of the findings of each object
+func findingsFor(findings []readinessFinding) map[string][]readinessSeverity {
+	out := map[string][]readinessSeverity{}
+	for _, f := range findings {
+		out[f.Object] = append(out[f.Object], f.Severity)
+	}
+	return out
+}
+
+func TestEgressIPReadiness(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	withEgressIPs := func(nns *uns.Unstructured, ips ...interface{}) *uns.Unstructured {
+		nns.Object["egressIPs"] = ips
+		return nns
+	}
+	hostSubnets := []*uns.Unstructured{
+		hostSubnet("node-a", []interface{}{"10.0.0.10"}, []interface{}{"10.0.0.0/24"}),
+		hostSubnet("node-b", []interface{}{"10.0.1.10"}, nil),
+		hostSubnet("node-c", nil, []interface{}{"10.0.2.0/24"}),
+		hostSubnet("node-d", nil, nil),
+	}
+	netNamespaces := []*uns.Unstructured{
+		withEgressIPs(netNamespace("automatic", 1), "10.0.0.10"),
+		withEgressIPs(netNamespace("manual", 2), "10.0.1.10"),
+		withEgressIPs(netNamespace("failover", 3), "10.0.0.11", "10.0.0.12"),
+		withEgressIPs(netNamespace("uncovered", 4), "10.0.2.10"),
+		netNamespace("none", 5),
+	}
+	g.Expect(findingsFor(egressIPReadiness(hostSubnets, netNamespaces))).To(Equal(map[string][]readinessSeverity{
+		"hostsubnet/node-b":     {readinessWarning},
+		"hostsubnet/node-c":     {readinessWarning},
+		"netnamespace/failover": {readinessWarning},
+		// node-c only has egressCIDRs, so it is not made egress-assignable
+		"netnamespace/uncovered": {readinessWarning},
+	}))
+
+	// Without any HostSubnet holding an egress IP, nothing is converted
+	findings := egressIPReadiness(hostSubnets[2:], netNamespaces[:1])
+	g.Expect(findingsFor(findings)).To(Equal(map[string][]readinessSeverity{
+		"hostsubnet/node-c":      {readinessWarning},
+		"netnamespace/automatic": {readinessBlocker},
+	}))
+}
+
+func TestEgressNetworkPolicyReadiness(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	findings := egressNetworkPolicyReadiness([]*uns.Unstructured{
+		egressNetworkPolicy("valid", "policy",
+			egressRule("Allow", "dnsName", "www.example.com"),
+			egressRule("Deny", "cidrSelector", "0.0.0.0/0")),
+		egressNetworkPolicy("invalid", "policy",
+			egressRule("Reject", "cidrSelector", "10.0.0.0/8"),
+			egressRule("Allow", "cidrSelector", "10.0.0.0"),
+			egressRule("Allow", "dnsName", "under_score.example.com"),
+			map[string]interface{}{"type": "Allow", "to": map[string]interface{}{}}),
+		egressNetworkPolicy("twice", "first", egressRule("Deny", "cidrSelector", "0.0.0.0/0")),
+		egressNetworkPolicy("twice", "second", egressRule("Deny", "cidrSelector", "0.0.0.0/0")),
+	})
+	g.Expect(findingsFor(findings)).To(Equal(map[string][]readinessSeverity{
+		"egressnetworkpolicy/invalid/policy": {readinessBlocker, readinessBlocker, readinessBlocker, readinessBlocker},
+		"namespace/twice":                    {readinessBlocker},
+	}))
+	g.Expect(findings[4].Message).To(ContainSubstring("first, second"))
+}
+
+func TestMulticastReadiness(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	multicast := func(nns *uns.Unstructured) *uns.Unstructured {
+		nns.SetAnnotations(map[string]string{multicastEnabledSDN: "true"})
+		return nns
+	}
+	findings := multicastReadiness([]*uns.Unstructured{
+		multicast(netNamespace("alone", 10)),
+		multicast(netNamespace("joined", 20)),
+		netNamespace("other", 20),
+		multicast(netNamespace("global", 0)),
+		netNamespace("default", 0),
+		netNamespace("disabled", 30),
+	})
+	g.Expect(findingsFor(findings)).To(Equal(map[string][]readinessSeverity{
+		"netnamespace/alone":  {readinessInfo},
+		"netnamespace/joined": {readinessWarning},
+		"netnamespace/global": {readinessInfo},
+	}))
+}
+
+func TestInternalSubnetReadiness(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := &operv1.NetworkSpec{
+		ClusterNetwork: []operv1.ClusterNetworkEntry{{CIDR: "100.64.0.0/10", HostPrefix: 23}},
+		ServiceNetwork: []string{"172.30.0.0/16"},
+	}
+	findings := internalSubnetReadiness(spec)
+	g.Expect(findings).To(HaveLen(2))
+	g.Expect(findings[0].Message).To(ContainSubstring("join subnet 100.64.0.0/16, set another subnet in ovnKubernetesConfig.v4InternalSubnet"))
+	g.Expect(findings[1].Message).To(ContainSubstring("transit switch subnet 100.88.0.0/16"))
+
+	// The internal subnets set ahead of the migration are the ones checked
+	spec.ClusterNetwork = []operv1.ClusterNetworkEntry{{CIDR: "100.64.0.0/16", HostPrefix: 23}}
+	spec.ServiceNetwork = []string{"100.65.0.0/16"}
+	spec.DefaultNetwork.OVNKubernetesConfig = &operv1.OVNKubernetesConfig{V4InternalSubnet: "100.65.0.0/16"}
+	findings = internalSubnetReadiness(spec)
+	g.Expect(findings).To(HaveLen(1))
+	g.Expect(findings[0].Message).To(HavePrefix("service network 100.65.0.0/16 overlaps with the ovn-kubernetes join subnet 100.65.0.0/16"))
+
+	spec.ClusterNetwork = []operv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/14", HostPrefix: 23}}
+	spec.ServiceNetwork = []string{"172.30.0.0/16"}
+	g.Expect(internalSubnetReadiness(spec)).To(BeEmpty())
+}
+
+func TestMTUReadiness(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	mtu := uint32(1450)
+	spec := sdnSpec(operv1.SDNModeNetworkPolicy)
+	spec.DefaultNetwork.OpenShiftSDNConfig.MTU = &mtu
+
+	findings := mtuReadiness(spec, 1500)
+	g.Expect(findings).To(HaveLen(1))
+	g.Expect(findings[0].Severity).To(Equal(readinessInfo))
+	g.Expect(findings[0].Message).To(ContainSubstring("from 1450 to 1400"))
+
+	// The machine MTU is derived from the VXLAN overhead when it is unknown
+	mtu = 620
+	findings = mtuReadiness(spec, 0)
+	g.Expect(findings).To(HaveLen(1))
+	g.Expect(findings[0].Severity).To(Equal(readinessBlocker))
+	g.Expect(findings[0].Message).To(ContainSubstring("machine MTU 670 leaves a pod MTU of 570"))
+
+	// A machine MTU with room to spare for Geneve changes nothing
+	mtu = 1450
+	g.Expect(mtuReadiness(spec, 9000)).To(BeEmpty())
+}
+
+func TestReconcileMigrationReadiness(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	operConfig := &operv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG},
+		Spec:       *sdnSpec(operv1.SDNModeNetworkPolicy),
+	}
+	client := fake.NewFakeClient(
+		operConfig,
+		hostSubnet("node-a", nil, nil),
+		netNamespace("alpha", 10),
+		egressNetworkPolicy("alpha", "first", egressRule("Deny", "cidrSelector", "0.0.0.0/0")),
+		egressNetworkPolicy("alpha", "second", egressRule("Deny", "cidrSelector", "0.0.0.0/0")),
+	)
+	r := &ReconcileMigrationReadiness{client: client, reader: client.Default().CRClient()}
+	getConfig := func() *operv1.Network {
+		oc := &operv1.Network{}
+		g.Expect(client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, oc)).To(Succeed())
+		return oc
+	}
+	reportName := types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: migrationReadinessConfigMap}
+
+	oc := getConfig()
+	oc.Spec.ClusterNetwork = []operv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/14", HostPrefix: 23}}
+	oc.Spec.ServiceNetwork = []string{"172.30.0.0/16"}
+	g.Expect(r.reconcileMigrationReadiness(ctx, oc)).To(Succeed())
+	cond := v1helpers.FindOperatorCondition(getConfig().Status.Conditions, names.OVNKubernetesMigrationReady)
+	g.Expect(cond).NotTo(BeNil())
+	g.Expect(cond.Status).To(Equal(operv1.ConditionFalse))
+	g.Expect(cond.Message).To(ContainSubstring("1 blocking and 0 warning findings"))
+
+	cm := &corev1.ConfigMap{}
+	g.Expect(client.Default().CRClient().Get(ctx, reportName, cm)).To(Succeed())
+	g.Expect(cm.Data[migrationReadinessSummaryKey]).To(Equal(cond.Message))
+	findings := []readinessFinding{}
+	g.Expect(json.Unmarshal([]byte(cm.Data[migrationReadinessReportKey]), &findings)).To(Succeed())
+	g.Expect(findings).To(HaveLen(1))
+	g.Expect(findings[0].Object).To(Equal("namespace/alpha"))
+
+	// Once the cluster runs OVN-Kubernetes, the report is removed
+	oc = getConfig()
+	oc.Spec.DefaultNetwork = operv1.DefaultNetworkDefinition{Type: operv1.NetworkTypeOVNKubernetes}
+	g.Expect(r.reconcileMigrationReadiness(ctx, oc)).To(Succeed())
+	g.Expect(getConfig().Status.Conditions).To(BeEmpty())
+	g.Expect(client.Default().CRClient().Get(ctx, reportName, cm)).NotTo(Succeed())
+}
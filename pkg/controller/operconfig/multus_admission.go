@@ -0,0 +1,51 @@
+package operconfig
+
+import (
+	"context"
+	"reflect"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+
+	"k8s.io/apimachinery/pkg/types"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// multusNamespacePredicate passes the namespace events that change the list of
+// namespaces ignored by the multus admission controller, which then needs to be
+// re-rendered: the creation or deletion of an ignored namespace, and the label
+// changes that make a namespace ignored or no longer ignored. Other namespace
+// events are dropped, so that namespace churn does not re-render everything.
+// The operator configuration is read from reader, the cache of the manager.
+func multusNamespacePredicate(reader crclient.Reader) predicate.Funcs {
+	ignored := func(obj crclient.Object) bool {
+		operConfig := &operv1.Network{}
+		if err := reader.Get(context.TODO(), types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig); err != nil {
+			return false
+		}
+		if operConfig.Spec.DisableMultiNetwork != nil && *operConfig.Spec.DisableMultiNetwork {
+			return false
+		}
+		return network.MultusAdmissionControllerIgnoresNamespace(&operConfig.Spec, obj.GetName(), obj.GetLabels())
+	}
+	return predicate.Funcs{
+		CreateFunc: func(evt event.CreateEvent) bool {
+			return ignored(evt.Object)
+		},
+		UpdateFunc: func(evt event.UpdateEvent) bool {
+			if reflect.DeepEqual(evt.ObjectOld.GetLabels(), evt.ObjectNew.GetLabels()) {
+				return false
+			}
+			return ignored(evt.ObjectOld) != ignored(evt.ObjectNew)
+		},
+		DeleteFunc: func(evt event.DeleteEvent) bool {
+			return ignored(evt.Object)
+		},
+		GenericFunc: func(_ event.GenericEvent) bool {
+			return false
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package operconfig
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	cnofake "github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestMultusNamespacePredicate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	overrides, err := json.Marshal(map[string]interface{}{
+		"multusAdmissionController": map[string]string{"ignoredNamespaceSelector": "example.com/multus-ignored=true"},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	client := cnofake.NewFakeClient(&operv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG},
+		Spec: operv1.NetworkSpec{OperatorSpec: operv1.OperatorSpec{
+			UnsupportedConfigOverrides: runtime.RawExtension{Raw: overrides},
+		}},
+	})
+	p := multusNamespacePredicate(client.Default().CRClient())
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	openshift := namespace("openshift-foo", map[string]string{"openshift.io/cluster-monitoring": "true"})
+	user := namespace("user-ns", map[string]string{"team": "a"})
+
+	// Only ignored namespaces coming and going change the list
+	g.Expect(p.Create(event.CreateEvent{Object: openshift})).To(BeTrue())
+	g.Expect(p.Create(event.CreateEvent{Object: user})).To(BeFalse())
+	g.Expect(p.Delete(event.DeleteEvent{Object: openshift})).To(BeTrue())
+	g.Expect(p.Delete(event.DeleteEvent{Object: user})).To(BeFalse())
+
+	// Only the label changes that move a namespace in or out of the list matter
+	relabeled := namespace("user-ns", map[string]string{"team": "b"})
+	g.Expect(p.Update(event.UpdateEvent{ObjectOld: user, ObjectNew: relabeled})).To(BeFalse())
+	ignored := namespace("user-ns", map[string]string{"team": "b", "example.com/multus-ignored": "true"})
+	g.Expect(p.Update(event.UpdateEvent{ObjectOld: relabeled, ObjectNew: ignored})).To(BeTrue())
+	g.Expect(p.Update(event.UpdateEvent{ObjectOld: ignored, ObjectNew: relabeled})).To(BeTrue())
+	annotated := openshift.DeepCopy()
+	annotated.Annotations = map[string]string{"a": "b"}
+	g.Expect(p.Update(event.UpdateEvent{ObjectOld: openshift, ObjectNew: annotated})).To(BeFalse())
+
+	// Without multus, no namespace matters
+	operConfig := &operv1.Network{}
+	g.Expect(client.Default().CRClient().Get(context.TODO(), types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig)).To(Succeed())
+	disabled := true
+	operConfig.Spec.DisableMultiNetwork = &disabled
+	g.Expect(client.Default().CRClient().Update(context.TODO(), operConfig)).To(Succeed())
+	g.Expect(p.Create(event.CreateEvent{Object: openshift})).To(BeFalse())
+}
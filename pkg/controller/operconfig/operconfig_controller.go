@@ -7,6 +7,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/openshift/cluster-network-operator/pkg/hypershift"
@@ -18,6 +19,7 @@ import (
 	configinformers "github.com/openshift/client-go/config/informers/externalversions"
 	"github.com/openshift/cluster-network-operator/pkg/apply"
 	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/multinetworkpolicy"
 	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
 	"github.com/openshift/cluster-network-operator/pkg/names"
 	"github.com/openshift/cluster-network-operator/pkg/network"
@@ -36,6 +38,7 @@ import (
 	v1coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+	crcache "sigs.k8s.io/controller-runtime/pkg/cache"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -52,6 +55,10 @@ import (
 // hasn't changed.
 var ResyncPeriod = 3 * time.Minute
 
+// waitForRequeuePeriod is how often we check again on an object referenced
+// by a wait-for annotation that was not ready yet.
+const waitForRequeuePeriod = 10 * time.Second
+
 // ManifestPaths is the path to the manifest templates
 // bad, but there's no way to pass configuration to the reconciler right now
 var ManifestPath = "./bindata"
@@ -120,6 +127,7 @@ func newReconciler(mgr manager.Manager, status *statusmanager.StatusManager, c c
 		status:       status,
 		mapper:       mgr.GetRESTMapper(),
 		featureGates: featureGates,
+		validated:    apply.NewValidationCache(),
 	}, nil
 }
 
@@ -130,6 +138,8 @@ func add(mgr manager.Manager, r *ReconcileOperConfig) error {
 	if err != nil {
 		return err
 	}
+	r.controller = c
+	r.mgrCache = mgr.GetCache()
 
 	// Watch for changes to primary resource Network (as long as the spec changes)
 	err = c.Watch(source.Kind(mgr.GetCache(), &operv1.Network{}), &handler.EnqueueRequestForObject{}, predicate.Funcs{
@@ -198,6 +208,16 @@ func add(mgr manager.Manager, r *ReconcileOperConfig) error {
 		return err
 	}
 
+	// Watch namespaces, to re-render the multus admission controller when the
+	// namespaces it ignores change.
+	if err := c.Watch(
+		source.Kind(mgr.GetCache(), &corev1.Namespace{}),
+		handler.EnqueueRequestsFromMapFunc(reconcileOperConfig),
+		multusNamespacePredicate(mgr.GetCache()),
+	); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -213,6 +233,23 @@ type ReconcileOperConfig struct {
 	mtuProberCleanedUp bool
 	// maintain the copy of feature gates in the cluster
 	featureGates featuregates.FeatureGate
+
+	// The rendered objects that passed validation, which are only validated
+	// again once they change.
+	validated *apply.ValidationCache
+
+	// The controller and the cache of its watches, to watch the NetNamespaces
+	// once an openshift-sdn mode migration starts and to read the objects the
+	// migration is verified against.
+	controller           controller.Controller
+	mgrCache             crcache.Cache
+	netNamespacesWatched bool
+	sdnModeMigrating     atomic.Bool
+	isolationVerifier    policyIsolationVerifier
+
+	// When each network type migration milestone was first seen reached, so
+	// that only the connectivity results recorded since then verify it.
+	migrationMilestones map[string]time.Time
 }
 
 // Reconcile updates the state of the cluster to match that which is desired
@@ -352,6 +389,30 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 	// once updated, use the new config
 	operConfig = newOperConfig
 
+	// Moving openshift-sdn from the Multitenant to the NetworkPolicy mode
+	// requires the NetworkPolicies replacing the multitenant isolation to be
+	// in place first. Until then, keep rendering (and recording as applied)
+	// the Multitenant mode.
+	modeMigrated, err := r.reconcileSDNModeMigration(ctx, prev, operConfig)
+	if err != nil {
+		log.Printf("Failed to migrate the openshift-sdn mode: %v", err)
+		r.status.SetDegraded(statusmanager.OperatorConfig, "SDNModeMigrationFailed",
+			fmt.Sprintf("Failed to migrate openshift-sdn from the Multitenant to the NetworkPolicy mode: %v", err))
+		return reconcile.Result{}, err
+	}
+	if !modeMigrated {
+		operConfig.Spec.DefaultNetwork.OpenShiftSDNConfig.Mode = operv1.SDNModeMultitenant
+	}
+
+	// Warn about the MultiNetworkPolicy objects that start or stop being
+	// enforced before the multus-networkpolicy DaemonSet is deployed or removed.
+	if err := multinetworkpolicy.ReportChange(ctx, r.client, prev, &operConfig.Spec); err != nil {
+		log.Printf("Failed to report the MultiNetworkPolicy enforcement change: %v", err)
+		r.status.SetDegraded(statusmanager.OperatorConfig, "MultiNetworkPolicyReportError",
+			fmt.Sprintf("Failed to report the MultiNetworkPolicy objects affected by the useMultiNetworkPolicy change: %v", err))
+		return reconcile.Result{}, err
+	}
+
 	// Generate the objects.
 	// Note that Render might have side effects in the passed in operConfig that
 	// will be reflected later on in the updated status.
@@ -450,7 +511,24 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 	// Apply the objects to the cluster
 	setDegraded := false
 	var degradedErr error
+	waitingFor := ""
 	for _, obj := range objs {
+		// If wait-for is specified, don't apply this or any later object
+		// until the referenced object is ready.
+		ready, reason, err := apply.WaitForReady(ctx, r.client, obj)
+		if err != nil {
+			err = errors.Wrapf(err, "could not check wait-for of (%s) %s/%s", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+			log.Println(err)
+			setDegraded = true
+			degradedErr = err
+			break
+		}
+		if !ready {
+			log.Printf("Object (%s) %s/%s is waiting: %s, postponing the remaining objects", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName(), reason)
+			waitingFor = reason
+			break
+		}
+
 		// TODO: OwnerRef for non default clusters. For HyperShift this should probably be HostedControlPlane CR
 		if apply.GetClusterName(obj) == "" {
 			// Mark the object to be GC'd if the owner is deleted.
@@ -463,8 +541,15 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 			}
 		}
 
-		// Open question: should an error here indicate we will never retry?
-		if err := apply.ApplyObject(ctx, r.client, obj, ControllerName); err != nil {
+		// Validate the object against the live schema first, so that an invalid
+		// object is reported as such instead of being partially applied. Only
+		// new and changed objects are validated.
+		err = r.validated.ValidateObject(ctx, r.client, obj, ControllerName)
+		if err == nil {
+			// Open question: should an error here indicate we will never retry?
+			err = apply.ApplyObject(ctx, r.client, obj, ControllerName)
+		}
+		if err != nil {
 			err = errors.Wrapf(err, "could not apply (%s) %s/%s", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName())
 
 			// If error comes from nonexistent namespace print out a help message.
@@ -493,6 +578,13 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, degradedErr
 	}
 
+	if waitingFor != "" {
+		r.status.SetProgressing(statusmanager.OperatorConfig, "WaitingForObject",
+			fmt.Sprintf("Waiting to apply remaining objects: %s", waitingFor))
+		return reconcile.Result{RequeueAfter: waitForRequeuePeriod}, nil
+	}
+	r.status.UnsetProgressing(statusmanager.OperatorConfig)
+
 	if operConfig.Spec.Migration != nil && operConfig.Spec.Migration.NetworkType != "" {
 		if !(operConfig.Spec.Migration.NetworkType == string(operv1.NetworkTypeOpenShiftSDN) || operConfig.Spec.Migration.NetworkType == string(operv1.NetworkTypeOVNKubernetes)) {
 			err = fmt.Errorf("Error: operConfig.Spec.Migration.NetworkType: %s is not equal to either \"OpenshiftSDN\" or \"OVNKubernetes\"", operConfig.Spec.Migration.NetworkType)
@@ -543,14 +635,42 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 		}
 	}
 
+	if err := r.reconcileMigrationDryRun(ctx, operConfig); err != nil {
+		log.Printf("Could not run the requested migration dry run: %v", err)
+	}
+	if err := reportUnknownRawCNIPlugins(ctx, r.client, &operConfig.Spec); err != nil {
+		log.Printf("Could not report the Raw additional network plugins: %v", err)
+	}
+
 	r.status.SetNotDegraded(statusmanager.OperatorConfig)
 
 	// All was successful. Request that this be re-triggered after ResyncPeriod,
 	// so we can reconcile state again.
 	log.Printf("Operconfig Controller complete")
+	if !modeMigrated {
+		// check the generated NetworkPolicies again soon
+		return reconcile.Result{RequeueAfter: waitForRequeuePeriod}, nil
+	}
 	return reconcile.Result{RequeueAfter: ResyncPeriod}, nil
 }
 
+// reportUnknownRawCNIPlugins sets the RawCNIConfigPluginsShipped condition while
+// Raw additional networks use plugins that are not shipped with the cluster.
+// Other operators install plugins of their own, so these are not rejected.
+func reportUnknownRawCNIPlugins(ctx context.Context, client cnoclient.Client, spec *operv1.NetworkSpec) error {
+	unknown := network.UnknownRawCNIPlugins(spec)
+	if len(unknown) == 0 {
+		return statusmanager.RemoveOperatorConditions(ctx, client, names.RawCNIConfigPluginsShipped)
+	}
+	return statusmanager.SetOperatorConditions(ctx, client, operv1.OperatorCondition{
+		Type:   names.RawCNIConfigPluginsShipped,
+		Status: operv1.ConditionFalse,
+		Reason: "UnknownPlugins",
+		Message: fmt.Sprintf("The following are not shipped with the cluster, and must be installed on the nodes by other means: %s.",
+			strings.Join(unknown, ", ")),
+	})
+}
+
 func reconcileOperConfig(ctx context.Context, obj crclient.Object) []reconcile.Request {
 	log.Printf("%s %s/%s changed, triggering operconf reconciliation", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetNamespace(), obj.GetName())
 	// Update reconcile.Request object to align with unnamespaced default network,
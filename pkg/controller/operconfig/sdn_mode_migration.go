@@ -0,0 +1,626 @@
+package operconfig
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// sdnModeMigrationPolicyName is the name of the NetworkPolicy generated in
+	// each namespace to reproduce its multitenant isolation
+	sdnModeMigrationPolicyName = "sdn-multitenant-isolation"
+	// sdnModeMigrationLabel marks the NetworkPolicies generated by the migration
+	sdnModeMigrationLabel = "networkoperator.openshift.io/sdn-mode-migration"
+	// hostNetworkPolicyGroupLabel selects the host network, which multitenant
+	// isolation never blocks
+	hostNetworkPolicyGroupLabel = "policy-group.network.openshift.io/host-network"
+	// globalNetID is the netid of the NetNamespaces made global, which can
+	// reach and be reached from every namespace
+	globalNetID = 0
+	// maxReportedMismatches bounds the namespace pairs listed in the
+	// PoliciesVerified condition
+	maxReportedMismatches = 10
+)
+
+var sdnModeMigrationConditionTypes = []string{
+	names.SDNModeMigrationPoliciesApplied,
+	names.SDNModeMigrationPoliciesVerified,
+	names.SDNModeMigrationModeChanged,
+}
+
+// isSDNModeMigration returns true if the change from prev to next moves
+// openshift-sdn from the Multitenant to the NetworkPolicy mode.
+func isSDNModeMigration(prev, next *operv1.NetworkSpec) bool {
+	if prev == nil || prev.DefaultNetwork.Type != operv1.NetworkTypeOpenShiftSDN || next.DefaultNetwork.Type != operv1.NetworkTypeOpenShiftSDN ||
+		prev.DefaultNetwork.OpenShiftSDNConfig == nil || next.DefaultNetwork.OpenShiftSDNConfig == nil {
+		return false
+	}
+	return prev.DefaultNetwork.OpenShiftSDNConfig.Mode == operv1.SDNModeMultitenant &&
+		next.DefaultNetwork.OpenShiftSDNConfig.Mode == operv1.SDNModeNetworkPolicy
+}
+
+// reconcileSDNModeMigration drives the migration of openshift-sdn from the
+// Multitenant to the NetworkPolicy mode. NetworkPolicies reproducing the
+// isolation of the NetNamespaces are generated, applied and verified; only then
+// is the mode allowed to change. It returns true if operConfig may be rendered
+// with its requested mode, false if the Multitenant mode must be kept for now.
+func (r *ReconcileOperConfig) reconcileSDNModeMigration(ctx context.Context, prev *operv1.NetworkSpec, operConfig *operv1.Network) (bool, error) {
+	inProgress := v1helpers.IsOperatorConditionTrue(operConfig.Status.Conditions, names.SDNModeMigrationInProgress)
+	migrating := isSDNModeMigration(prev, &operConfig.Spec)
+	r.sdnModeMigrating.Store(migrating)
+	if !migrating {
+		// Once the NetworkPolicy mode has been applied, the migration is complete
+		if inProgress && prev != nil && prev.DefaultNetwork.OpenShiftSDNConfig != nil &&
+			prev.DefaultNetwork.OpenShiftSDNConfig.Mode == operv1.SDNModeNetworkPolicy {
+			klog.Infof("openshift-sdn mode migration is completed")
+			return true, statusmanager.SetOperatorConditions(ctx, r.client, completedSDNModeMigrationConditions()...)
+		}
+		return true, nil
+	}
+
+	conditions := []operv1.OperatorCondition{}
+	if !inProgress {
+		klog.Infof("Starting the openshift-sdn mode migration from Multitenant to NetworkPolicy")
+		conditions = initSDNModeMigrationConditions()
+	}
+
+	if err := r.watchNetNamespaces(); err != nil {
+		return false, err
+	}
+	reader := r.cachedReader()
+	policies, err := multitenantNetworkPolicies(ctx, reader)
+	if err != nil {
+		return false, fmt.Errorf("could not generate NetworkPolicies from NetNamespaces: %w", err)
+	}
+	if err := applyNetworkPolicies(ctx, reader, r.client.Default().CRClient(), policies); err != nil {
+		conditions = append(conditions, operv1.OperatorCondition{
+			Type:    names.SDNModeMigrationPoliciesApplied,
+			Status:  operv1.ConditionFalse,
+			Reason:  "ApplyFailed",
+			Message: err.Error(),
+		})
+		if condErr := statusmanager.SetOperatorConditions(ctx, r.client, conditions...); condErr != nil {
+			klog.Errorf("Failed to update the openshift-sdn mode migration conditions: %v", condErr)
+		}
+		return false, err
+	}
+	conditions = append(conditions, operv1.OperatorCondition{
+		Type:    names.SDNModeMigrationPoliciesApplied,
+		Status:  operv1.ConditionTrue,
+		Reason:  "PoliciesApplied",
+		Message: fmt.Sprintf("%d NetworkPolicies were generated from the NetNamespaces", len(policies)),
+	})
+
+	mismatched, err := r.isolationVerifier.verify(ctx, reader, operConfig.Spec.ClusterNetwork)
+	if err != nil {
+		return false, err
+	}
+	if len(mismatched) > 0 {
+		if len(mismatched) > maxReportedMismatches {
+			mismatched = append(mismatched[:maxReportedMismatches], fmt.Sprintf("and %d more", len(mismatched)-maxReportedMismatches))
+		}
+		conditions = append(conditions, operv1.OperatorCondition{
+			Type:    names.SDNModeMigrationPoliciesVerified,
+			Status:  operv1.ConditionFalse,
+			Reason:  "PoliciesNotVerified",
+			Message: fmt.Sprintf("The NetworkPolicies do not reproduce the isolation of the NetNamespaces: %s", strings.Join(mismatched, ", ")),
+		})
+		return false, statusmanager.SetOperatorConditions(ctx, r.client, conditions...)
+	}
+	conditions = append(conditions,
+		operv1.OperatorCondition{
+			Type:    names.SDNModeMigrationPoliciesVerified,
+			Status:  operv1.ConditionTrue,
+			Reason:  "PoliciesVerified",
+			Message: "The generated NetworkPolicies reproduce the isolation of the NetNamespaces",
+		},
+		operv1.OperatorCondition{
+			Type:    names.SDNModeMigrationModeChanged,
+			Status:  operv1.ConditionFalse,
+			Reason:  "ModeChanging",
+			Message: "openshift-sdn is being switched to the NetworkPolicy mode",
+		})
+	return true, statusmanager.SetOperatorConditions(ctx, r.client, conditions...)
+}
+
+// watchNetNamespaces starts watching the NetNamespaces when a mode migration
+// starts, so that the policies are generated and verified again as soon as a
+// namespace is created, joined or made global before the mode changes. The
+// watch is only added then, as NetNamespaces only exist with openshift-sdn.
+func (r *ReconcileOperConfig) watchNetNamespaces() error {
+	if r.controller == nil || r.netNamespacesWatched {
+		return nil
+	}
+	netNamespace := &uns.Unstructured{}
+	netNamespace.SetGroupVersionKind(gvrNetnamespace.GroupVersion().WithKind("NetNamespace"))
+	if err := r.controller.Watch(
+		source.Kind(r.mgrCache, netNamespace),
+		handler.EnqueueRequestsFromMapFunc(r.reconcileSDNModeMigrationNetNamespace),
+	); err != nil {
+		return fmt.Errorf("could not watch NetNamespaces: %w", err)
+	}
+	r.netNamespacesWatched = true
+	return nil
+}
+
+// cachedReader returns the cache of the manager, from which the migration reads
+// the NetNamespaces, namespaces and NetworkPolicies on every reconciliation,
+// or the client itself when the reconciler runs without a manager.
+func (r *ReconcileOperConfig) cachedReader() crclient.Reader {
+	if r.mgrCache != nil {
+		return r.mgrCache
+	}
+	return r.client.Default().CRClient()
+}
+
+// listNetNamespaces returns the NetNamespaces, which have no typed client.
+func listNetNamespaces(ctx context.Context, reader crclient.Reader) ([]uns.Unstructured, error) {
+	netNamespaceList := &uns.UnstructuredList{}
+	netNamespaceList.SetGroupVersionKind(gvrNetnamespace.GroupVersion().WithKind("NetNamespaceList"))
+	if err := reader.List(ctx, netNamespaceList); err != nil {
+		return nil, fmt.Errorf("could not list NetNamespaces: %w", err)
+	}
+	return netNamespaceList.Items, nil
+}
+
+// reconcileSDNModeMigrationNetNamespace triggers an operconf reconciliation on
+// NetNamespace events while the mode migration is in progress.
+func (r *ReconcileOperConfig) reconcileSDNModeMigrationNetNamespace(ctx context.Context, obj crclient.Object) []reconcile.Request {
+	if !r.sdnModeMigrating.Load() {
+		return nil
+	}
+	return reconcileOperConfig(ctx, obj)
+}
+
+func initSDNModeMigrationConditions() []operv1.OperatorCondition {
+	conditions := []operv1.OperatorCondition{}
+	for _, conditionType := range sdnModeMigrationConditionTypes {
+		conditions = append(conditions, operv1.OperatorCondition{
+			Type:    conditionType,
+			Status:  operv1.ConditionFalse,
+			Reason:  "SDNModeMigrationInitialized",
+			Message: "network operator initialize openshift-sdn mode migration status",
+		})
+	}
+	return append(conditions, operv1.OperatorCondition{
+		Type:    names.SDNModeMigrationInProgress,
+		Status:  operv1.ConditionTrue,
+		Reason:  "SDNModeMigrationStarted",
+		Message: "openshift-sdn mode migration from Multitenant to NetworkPolicy is started",
+	})
+}
+
+func completedSDNModeMigrationConditions() []operv1.OperatorCondition {
+	return []operv1.OperatorCondition{
+		{
+			Type:    names.SDNModeMigrationModeChanged,
+			Status:  operv1.ConditionTrue,
+			Reason:  "ModeChanged",
+			Message: "openshift-sdn runs in the NetworkPolicy mode",
+		},
+		{
+			Type:    names.SDNModeMigrationInProgress,
+			Status:  operv1.ConditionFalse,
+			Reason:  "SDNModeMigrationCompleted",
+			Message: "openshift-sdn mode migration is completed",
+		},
+	}
+}
+
+// multitenantNetworkPolicies returns, for each NetNamespace that is not global,
+// a NetworkPolicy that only admits traffic from the namespaces joined to it,
+// from the global namespaces and from the host network, as the Multitenant
+// mode does. Global namespaces get no policy, since they accept all traffic.
+func multitenantNetworkPolicies(ctx context.Context, reader crclient.Reader) ([]*networkingv1.NetworkPolicy, error) {
+	netNamespaceList, err := listNetNamespaces(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+	byNetID := map[int64]sets.Set[string]{}
+	for _, nns := range netNamespaceList {
+		netID, found, err := uns.NestedInt64(nns.Object, "netid")
+		if err != nil || !found {
+			klog.Warningf("Ignoring NetNamespace %s without a valid netid", nns.GetName())
+			continue
+		}
+		if byNetID[netID] == nil {
+			byNetID[netID] = sets.New[string]()
+		}
+		byNetID[netID].Insert(nns.GetName())
+	}
+
+	global := byNetID[globalNetID]
+	netIDs := []int64{}
+	for netID := range byNetID {
+		if netID != globalNetID {
+			netIDs = append(netIDs, netID)
+		}
+	}
+	sort.Slice(netIDs, func(i, j int) bool { return netIDs[i] < netIDs[j] })
+
+	policies := []*networkingv1.NetworkPolicy{}
+	for _, netID := range netIDs {
+		allowed := sets.List(byNetID[netID].Union(global))
+		for _, namespace := range sets.List(byNetID[netID]) {
+			policies = append(policies, multitenantNetworkPolicy(namespace, allowed))
+		}
+	}
+	return policies, nil
+}
+
+func multitenantNetworkPolicy(namespace string, allowedNamespaces []string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      sdnModeMigrationPolicyName,
+			Labels:    map[string]string{sdnModeMigrationLabel: ""},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{
+							MatchExpressions: []metav1.LabelSelectorRequirement{{
+								Key:      "kubernetes.io/metadata.name",
+								Operator: metav1.LabelSelectorOpIn,
+								Values:   allowedNamespaces,
+							}},
+						},
+					}},
+				},
+				{
+					From: []networkingv1.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{hostNetworkPolicyGroupLabel: ""},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// applyNetworkPolicies creates the generated NetworkPolicies, or updates them
+// if they changed since they were created. The policies generated before in
+// namespaces that no longer get one, such as namespaces made global, are
+// deleted. The existing policies are read from reader, and only the policies
+// that differ are written with writer.
+func applyNetworkPolicies(ctx context.Context, reader crclient.Reader, writer crclient.Client, policies []*networkingv1.NetworkPolicy) error {
+	existingList := &networkingv1.NetworkPolicyList{}
+	if err := reader.List(ctx, existingList, crclient.HasLabels{sdnModeMigrationLabel}); err != nil {
+		return fmt.Errorf("could not list the generated NetworkPolicies: %w", err)
+	}
+	existing := map[string]*networkingv1.NetworkPolicy{}
+	for i := range existingList.Items {
+		if existingList.Items[i].Name == sdnModeMigrationPolicyName {
+			existing[existingList.Items[i].Namespace] = &existingList.Items[i]
+		}
+	}
+	generated := sets.New[string]()
+	for _, policy := range policies {
+		generated.Insert(policy.Namespace)
+	}
+	for namespace, policy := range existing {
+		if generated.Has(namespace) {
+			continue
+		}
+		klog.Infof("Deleting NetworkPolicy %s/%s, as NetNamespace %s no longer needs it", policy.Namespace, policy.Name, policy.Namespace)
+		if err := writer.Delete(ctx, policy); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete NetworkPolicy %s/%s: %w", policy.Namespace, policy.Name, err)
+		}
+	}
+
+	for _, policy := range policies {
+		current := existing[policy.Namespace]
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			if current == nil {
+				err := writer.Create(ctx, policy.DeepCopy())
+				if !apierrors.IsAlreadyExists(err) {
+					return err
+				}
+				// The policy is not in the cache yet, or the cached copy was stale
+				current = &networkingv1.NetworkPolicy{}
+				if err := writer.Get(ctx, crclient.ObjectKeyFromObject(policy), current); err != nil {
+					return err
+				}
+			}
+			if equality.Semantic.DeepEqual(current.Spec, policy.Spec) && reflect.DeepEqual(current.Labels, policy.Labels) {
+				return nil
+			}
+			updated := current.DeepCopy()
+			updated.Labels = policy.Labels
+			updated.Spec = policy.Spec
+			err := writer.Update(ctx, updated)
+			if apierrors.IsConflict(err) {
+				current = nil
+			}
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("could not apply NetworkPolicy %s/%s: %w", policy.Namespace, policy.Name, err)
+		}
+	}
+	return nil
+}
+
+// policyIsolationVerifier verifies the isolation of the NetworkPolicies, and
+// remembers the result of the last verification. As the migration reconciles
+// every few seconds, the namespaces are only evaluated again once the
+// NetNamespaces, namespaces or NetworkPolicies they are evaluated from change.
+type policyIsolationVerifier struct {
+	inputs     uint64
+	verified   bool
+	mismatched []string
+}
+
+// verify checks that the NetworkPolicies in place reproduce the isolation of
+// the NetNamespaces as they are now, rather than as they were when the
+// policies were generated. For every pair of namespaces, the ingress policies
+// of the destination, including any not generated by the migration, are
+// evaluated against the labels of the source namespace: traffic that the
+// Multitenant mode allows must be admitted to every pod, and traffic it blocks
+// must not be admitted to any. It returns the pairs that do not match.
+//
+// openshift-sdn does not enforce NetworkPolicies in the Multitenant mode, so
+// this is as far as they can be checked before the mode changes.
+func (v *policyIsolationVerifier) verify(ctx context.Context, reader crclient.Reader, clusterNetwork []operv1.ClusterNetworkEntry) ([]string, error) {
+	netNamespaceList, err := listNetNamespaces(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+	namespaceList := &corev1.NamespaceList{}
+	if err := reader.List(ctx, namespaceList); err != nil {
+		return nil, fmt.Errorf("could not list namespaces: %w", err)
+	}
+	policyList := &networkingv1.NetworkPolicyList{}
+	if err := reader.List(ctx, policyList); err != nil {
+		return nil, fmt.Errorf("could not list NetworkPolicies: %w", err)
+	}
+
+	inputs := fnv.New64a()
+	for _, entry := range clusterNetwork {
+		fmt.Fprintf(inputs, "%s;", entry.CIDR)
+	}
+	for i := range netNamespaceList {
+		fmt.Fprintf(inputs, "NetNamespace/%s/%s;", netNamespaceList[i].GetName(), netNamespaceList[i].GetResourceVersion())
+	}
+	for i := range namespaceList.Items {
+		fmt.Fprintf(inputs, "Namespace/%s/%s;", namespaceList.Items[i].Name, namespaceList.Items[i].ResourceVersion)
+	}
+	for i := range policyList.Items {
+		fmt.Fprintf(inputs, "NetworkPolicy/%s/%s/%s;", policyList.Items[i].Namespace, policyList.Items[i].Name, policyList.Items[i].ResourceVersion)
+	}
+	if v.verified && v.inputs == inputs.Sum64() {
+		return append([]string{}, v.mismatched...), nil
+	}
+
+	mismatched, err := verifyNetworkPolicyIsolation(netNamespaceList, namespaceList.Items, policyList.Items, clusterNetwork)
+	if err != nil {
+		return nil, err
+	}
+	v.inputs, v.verified, v.mismatched = inputs.Sum64(), true, mismatched
+	return append([]string{}, mismatched...), nil
+}
+
+// verifyNetworkPolicyIsolation evaluates every pair of namespaces against the
+// NetNamespaces and the NetworkPolicies, as described in verify.
+func verifyNetworkPolicyIsolation(netNamespaceList []uns.Unstructured, namespaceList []corev1.Namespace, policyList []networkingv1.NetworkPolicy, clusterNetwork []operv1.ClusterNetworkEntry) ([]string, error) {
+	podNetworks := []*net.IPNet{}
+	for _, entry := range clusterNetwork {
+		if _, cidr, err := net.ParseCIDR(entry.CIDR); err == nil {
+			podNetworks = append(podNetworks, cidr)
+		}
+	}
+
+	netIDs := map[string]int64{}
+	for _, nns := range netNamespaceList {
+		if netID, found, err := uns.NestedInt64(nns.Object, "netid"); err == nil && found {
+			netIDs[nns.GetName()] = netID
+		}
+	}
+	ingress := map[string][]*ingressPolicy{}
+	for i := range policyList {
+		policy, err := newIngressPolicy(&policyList[i], podNetworks)
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil {
+			ingress[policy.namespace] = append(ingress[policy.namespace], policy)
+		}
+	}
+
+	// Namespaces without a NetNamespace yet are not isolated by openshift-sdn
+	namespaces := []*corev1.Namespace{}
+	for i := range namespaceList {
+		if _, ok := netIDs[namespaceList[i].Name]; ok {
+			namespaces = append(namespaces, &namespaceList[i])
+		}
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
+
+	mismatched := []string{}
+	for _, dst := range namespaces {
+		for _, src := range namespaces {
+			srcID, dstID := netIDs[src.Name], netIDs[dst.Name]
+			_, hostNetwork := src.Labels[hostNetworkPolicyGroupLabel]
+			allowed := hostNetwork || srcID == dstID || srcID == globalNetID || dstID == globalNetID
+			if allowed && !admitsAllPods(ingress[dst.Name], src, dst.Name) {
+				mismatched = append(mismatched, fmt.Sprintf("%s -> %s is blocked", src.Name, dst.Name))
+			} else if !allowed && admitsAnyPod(ingress[dst.Name], src, dst.Name) {
+				mismatched = append(mismatched, fmt.Sprintf("%s -> %s is admitted", src.Name, dst.Name))
+			}
+		}
+	}
+	return mismatched, nil
+}
+
+// ingressPolicy is a NetworkPolicy of the Ingress type, with its selectors
+// parsed once.
+type ingressPolicy struct {
+	namespace string
+	// allPods is true if the policy selects every pod of its namespace
+	allPods bool
+	rules   []ingressRule
+}
+
+type ingressRule struct {
+	// allPorts is true if the rule does not restrict the ports
+	allPorts bool
+	// any is true if the rule admits every source
+	any   bool
+	peers []ingressPeer
+}
+
+type ingressPeer struct {
+	// namespaces is nil for the namespace of the policy itself
+	namespaces labels.Selector
+	// allPods is true if the peer selects every pod of its namespaces
+	allPods bool
+	// ipBlock is true for the peers selecting IP blocks, and podIPs if the
+	// block overlaps the cluster network, which then admits some pods of
+	// every namespace
+	ipBlock bool
+	podIPs  bool
+}
+
+func newIngressPolicy(policy *networkingv1.NetworkPolicy, podNetworks []*net.IPNet) (*ingressPolicy, error) {
+	isIngress := len(policy.Spec.PolicyTypes) == 0
+	for _, policyType := range policy.Spec.PolicyTypes {
+		if policyType == networkingv1.PolicyTypeIngress {
+			isIngress = true
+		}
+	}
+	if !isIngress {
+		return nil, nil
+	}
+	out := &ingressPolicy{
+		namespace: policy.Namespace,
+		allPods:   isEmptySelector(&policy.Spec.PodSelector),
+	}
+	for _, rule := range policy.Spec.Ingress {
+		r := ingressRule{allPorts: len(rule.Ports) == 0, any: len(rule.From) == 0}
+		for _, from := range rule.From {
+			if from.IPBlock != nil {
+				r.peers = append(r.peers, ingressPeer{ipBlock: true, podIPs: overlapsPodNetworks(from.IPBlock.CIDR, podNetworks)})
+				continue
+			}
+			peer := ingressPeer{allPods: from.PodSelector == nil || isEmptySelector(from.PodSelector)}
+			if from.NamespaceSelector != nil {
+				selector, err := metav1.LabelSelectorAsSelector(from.NamespaceSelector)
+				if err != nil {
+					return nil, fmt.Errorf("invalid namespace selector in NetworkPolicy %s/%s: %w", policy.Namespace, policy.Name, err)
+				}
+				peer.namespaces = selector
+			}
+			r.peers = append(r.peers, peer)
+		}
+		out.rules = append(out.rules, r)
+	}
+	return out, nil
+}
+
+// overlapsPodNetworks returns true if cidr, or a part of it, is in the pod
+// networks. An invalid CIDR is taken to overlap them.
+func overlapsPodNetworks(cidr string, podNetworks []*net.IPNet) bool {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return true
+	}
+	for _, podNetwork := range podNetworks {
+		if podNetwork.Contains(ipNet.IP) || ipNet.Contains(podNetwork.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptySelector(selector *metav1.LabelSelector) bool {
+	return len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0
+}
+
+// selects returns true if the peer selects pods of the namespace src, in a
+// policy of the namespace dst.
+func (p ingressPeer) selects(src *corev1.Namespace, dst string) bool {
+	if p.ipBlock {
+		return false
+	}
+	if p.namespaces == nil {
+		return src.Name == dst
+	}
+	return p.namespaces.Matches(labels.Set(src.Labels))
+}
+
+// admitsAllPods returns true if the ingress policies of the namespace dst admit
+// traffic from every pod of src to every pod of dst, on every port.
+func admitsAllPods(policies []*ingressPolicy, src *corev1.Namespace, dst string) bool {
+	if len(policies) == 0 {
+		return true
+	}
+	for _, policy := range policies {
+		if !policy.allPods {
+			continue
+		}
+		for _, rule := range policy.rules {
+			if !rule.allPorts {
+				continue
+			}
+			if rule.any {
+				return true
+			}
+			for _, peer := range rule.peers {
+				if peer.allPods && peer.selects(src, dst) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// admitsAnyPod returns true if the ingress policies of the namespace dst admit
+// some traffic from src to dst. A namespace whose pods are not all selected by
+// a policy admits everything to the pods left out.
+func admitsAnyPod(policies []*ingressPolicy, src *corev1.Namespace, dst string) bool {
+	isolated := false
+	for _, policy := range policies {
+		isolated = isolated || policy.allPods
+		for _, rule := range policy.rules {
+			if rule.any {
+				return true
+			}
+			for _, peer := range rule.peers {
+				if peer.podIPs || peer.selects(src, dst) {
+					return true
+				}
+			}
+		}
+	}
+	return !isolated
+}
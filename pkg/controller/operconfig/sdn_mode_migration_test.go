@@ -0,0 +1,238 @@
+package operconfig
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func netNamespace(name string, netID int64) *uns.Unstructured {
+	return &uns.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "network.openshift.io/v1",
+			"kind":       "NetNamespace",
+			"netname":    name,
+			"netid":      netID,
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+		},
+	}
+}
+
+func sdnSpec(mode operv1.SDNMode) *operv1.NetworkSpec {
+	return &operv1.NetworkSpec{
+		DefaultNetwork: operv1.DefaultNetworkDefinition{
+			Type:               operv1.NetworkTypeOpenShiftSDN,
+			OpenShiftSDNConfig: &operv1.OpenShiftSDNConfig{Mode: mode},
+		},
+	}
+}
+
+func TestMultitenantNetworkPolicies(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := fake.NewFakeClient(
+		netNamespace("default", 0),
+		netNamespace("openshift-ingress", 0),
+		netNamespace("alpha", 10),
+		netNamespace("beta", 10),
+		netNamespace("gamma", 20),
+	)
+	policies, err := multitenantNetworkPolicies(context.TODO(), client.Default().CRClient())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	allowed := map[string][]string{}
+	for _, policy := range policies {
+		g.Expect(policy.Name).To(Equal(sdnModeMigrationPolicyName))
+		g.Expect(policy.Labels).To(HaveKey(sdnModeMigrationLabel))
+		g.Expect(policy.Spec.Ingress).To(HaveLen(2))
+		g.Expect(policy.Spec.Ingress[1].From[0].NamespaceSelector.MatchLabels).To(HaveKey(hostNetworkPolicyGroupLabel))
+		allowed[policy.Namespace] = policy.Spec.Ingress[0].From[0].NamespaceSelector.MatchExpressions[0].Values
+	}
+	// global namespaces get no policy, joined namespaces admit each other
+	g.Expect(allowed).To(Equal(map[string][]string{
+		"alpha": {"alpha", "beta", "default", "openshift-ingress"},
+		"beta":  {"alpha", "beta", "default", "openshift-ingress"},
+		"gamma": {"default", "gamma", "openshift-ingress"},
+	}))
+}
+
+func TestReconcileSDNModeMigration(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	operConfig := &operv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG},
+		Spec:       *sdnSpec(operv1.SDNModeNetworkPolicy),
+	}
+	client := fake.NewFakeClient(operConfig,
+		netNamespace("default", 0), namespace("default", nil),
+		netNamespace("alpha", 10), namespace("alpha", nil))
+	r := &ReconcileOperConfig{client: client}
+	getConfig := func() *operv1.Network {
+		oc := &operv1.Network{}
+		g.Expect(client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, oc)).To(Succeed())
+		return oc
+	}
+
+	// Without a mode change, nothing happens
+	migrated, err := r.reconcileSDNModeMigration(ctx, sdnSpec(operv1.SDNModeNetworkPolicy), getConfig())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(migrated).To(BeTrue())
+	g.Expect(getConfig().Status.Conditions).To(BeEmpty())
+
+	// The policies are generated and verified before the mode may change
+	migrated, err = r.reconcileSDNModeMigration(ctx, sdnSpec(operv1.SDNModeMultitenant), getConfig())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(migrated).To(BeTrue())
+	policy := &networkingv1.NetworkPolicy{}
+	g.Expect(client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: "alpha", Name: sdnModeMigrationPolicyName}, policy)).To(Succeed())
+	g.Expect(policy.Spec.Ingress[0].From[0].NamespaceSelector.MatchExpressions[0].Values).To(Equal([]string{"alpha", "default"}))
+	err = client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: "default", Name: sdnModeMigrationPolicyName}, policy)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	conditions := getConfig().Status.Conditions
+	g.Expect(v1helpers.IsOperatorConditionTrue(conditions, names.SDNModeMigrationInProgress)).To(BeTrue())
+	g.Expect(v1helpers.IsOperatorConditionTrue(conditions, names.SDNModeMigrationPoliciesApplied)).To(BeTrue())
+	g.Expect(v1helpers.IsOperatorConditionTrue(conditions, names.SDNModeMigrationPoliciesVerified)).To(BeTrue())
+	g.Expect(v1helpers.IsOperatorConditionFalse(conditions, names.SDNModeMigrationModeChanged)).To(BeTrue())
+
+	// Once the NetworkPolicy mode is applied, the migration completes
+	migrated, err = r.reconcileSDNModeMigration(ctx, sdnSpec(operv1.SDNModeNetworkPolicy), getConfig())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(migrated).To(BeTrue())
+	conditions = getConfig().Status.Conditions
+	g.Expect(v1helpers.IsOperatorConditionFalse(conditions, names.SDNModeMigrationInProgress)).To(BeTrue())
+	g.Expect(v1helpers.IsOperatorConditionTrue(conditions, names.SDNModeMigrationModeChanged)).To(BeTrue())
+}
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/metadata.name": name}}}
+	for k, v := range labels {
+		ns.Labels[k] = v
+	}
+	return ns
+}
+
+func TestVerifyNetworkPolicyIsolation(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	clusterNetwork := []operv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/14", HostPrefix: 23}}
+	client := fake.NewFakeClient(
+		netNamespace("default", 0), namespace("default", nil),
+		netNamespace("openshift-host-network", 0), namespace("openshift-host-network", map[string]string{hostNetworkPolicyGroupLabel: ""}),
+		netNamespace("alpha", 10), namespace("alpha", nil),
+		netNamespace("beta", 10), namespace("beta", nil),
+		netNamespace("gamma", 20), namespace("gamma", nil),
+	).Default().CRClient()
+	regenerate := func() {
+		policies, err := multitenantNetworkPolicies(ctx, client)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(applyNetworkPolicies(ctx, client, client, policies)).To(Succeed())
+	}
+	setNetID := func(name string, netID int64) {
+		nns := netNamespace(name, 0)
+		g.Expect(client.Get(ctx, types.NamespacedName{Name: name}, nns)).To(Succeed())
+		g.Expect(uns.SetNestedField(nns.Object, netID, "netid")).To(Succeed())
+		g.Expect(client.Update(ctx, nns)).To(Succeed())
+	}
+	// The same verifier is used throughout, so that every change below must
+	// be verified again rather than answered from the last verification
+	verifier := &policyIsolationVerifier{}
+
+	// Without policies, the isolated namespaces admit each other
+	mismatched, err := verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).To(ConsistOf("alpha -> gamma is admitted", "beta -> gamma is admitted",
+		"gamma -> alpha is admitted", "gamma -> beta is admitted"))
+
+	regenerate()
+	mismatched, err = verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).To(BeEmpty())
+
+	// A policy of the users, not enforced in the Multitenant mode, would open
+	// gamma to every namespace once the mode changes
+	allowAll := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "gamma", Name: "allow-all"},
+		Spec: networkingv1.NetworkPolicySpec{
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+			}},
+		},
+	}
+	g.Expect(client.Create(ctx, allowAll)).To(Succeed())
+	mismatched, err = verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).To(ConsistOf("alpha -> gamma is admitted", "beta -> gamma is admitted"))
+
+	// A block of external addresses does not admit pods
+	allowAll.Spec.Ingress[0].From = []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "192.168.0.0/16"}}}
+	g.Expect(client.Update(ctx, allowAll)).To(Succeed())
+	mismatched, err = verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).To(BeEmpty())
+	g.Expect(client.Delete(ctx, allowAll)).To(Succeed())
+
+	// Joining gamma to alpha after the policies were generated is not
+	// covered until they are generated again
+	setNetID("gamma", 10)
+	mismatched, err = verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).To(ConsistOf("alpha -> gamma is blocked", "beta -> gamma is blocked",
+		"gamma -> alpha is blocked", "gamma -> beta is blocked"))
+	regenerate()
+	mismatched, err = verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).To(BeEmpty())
+
+	// Making gamma global deletes its generated policy
+	setNetID("gamma", 0)
+	regenerate()
+	err = client.Get(ctx, types.NamespacedName{Namespace: "gamma", Name: sdnModeMigrationPolicyName}, &networkingv1.NetworkPolicy{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	mismatched, err = verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).To(BeEmpty())
+
+	// A generated policy edited since does not verify. The missing policy of
+	// beta does not matter, as no namespace is left that beta must block.
+	alpha := &networkingv1.NetworkPolicy{}
+	g.Expect(client.Get(ctx, types.NamespacedName{Namespace: "alpha", Name: sdnModeMigrationPolicyName}, alpha)).To(Succeed())
+	alpha.Spec = multitenantNetworkPolicy("alpha", []string{"alpha"}).Spec
+	g.Expect(client.Update(ctx, alpha)).To(Succeed())
+	g.Expect(client.Delete(ctx, multitenantNetworkPolicy("beta", nil))).To(Succeed())
+	mismatched, err = verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).To(ConsistOf("beta -> alpha is blocked", "default -> alpha is blocked", "gamma -> alpha is blocked"))
+
+	// The mismatches returned are not shared with the verifier
+	mismatched[0] = "changed"
+	mismatched, err = verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).NotTo(ContainElement("changed"))
+
+	// Policies read from a stale cache are read again before they are updated
+	stale := alpha.DeepCopy()
+	g.Expect(client.Update(ctx, alpha)).To(Succeed())
+	policies, err := multitenantNetworkPolicies(ctx, client)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(applyNetworkPolicies(ctx, crfake.NewClientBuilder().WithObjects(stale).Build(), client, policies)).To(Succeed())
+	mismatched, err = verifier.verify(ctx, client, clusterNetwork)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mismatched).To(BeEmpty())
+}
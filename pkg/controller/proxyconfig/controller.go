@@ -8,7 +8,9 @@ import (
 	configv1 "github.com/openshift/api/config/v1"
 	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
 	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/hypershift"
 	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/util/proxyconfig"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -47,9 +49,13 @@ func newReconciler(mgr manager.Manager, status *statusmanager.StatusManager, c c
 
 	r := &ReconcileProxyConfig{
 		client:     c.Default().CRClient(),
+		nodeReader: mgr.GetCache(),
 		status:     status,
 		cmInformer: cmInformer,
 	}
+	if hypershift.NewHyperShiftConfig().Enabled {
+		r.hcpClient = c.ClientFor(names.ManagementClusterName).CRClient()
+	}
 
 	c.Default().AddCustomInformer(cmInformer) // Tell the ClusterClient about this informer
 
@@ -77,7 +83,15 @@ func add(mgr manager.Manager, r *ReconcileProxyConfig) error {
 		return err
 	}
 
-	return nil
+	// Watch the nodes, as their subnets are the machine networks of a
+	// cluster that has no install-config.
+	if err := c.Watch(source.Kind(mgr.GetCache(), &corev1.Node{}),
+		handler.EnqueueRequestsFromMapFunc(enqueueProxyConfig), nodeSubnetsPredicate); err != nil {
+		return err
+	}
+
+	// Keep probing the readinessEndpoints of the proxy once it is accepted
+	return mgr.Add(&proxyHealthMonitor{client: r.client, status: r.status})
 }
 
 // ReconcileProxyConfig reconciles a Proxy object
@@ -87,6 +101,11 @@ type ReconcileProxyConfig struct {
 	client crclient.Client
 	status *statusmanager.StatusManager
 
+	// nodeReader reads the nodes from the cache of the manager, and hcpClient
+	// the HostedControlPlane from the management cluster on HyperShift.
+	nodeReader crclient.Reader
+	hcpClient  crclient.Reader
+
 	cmInformer cache.SharedIndexInformer
 }
 
@@ -185,16 +204,30 @@ func (r *ReconcileProxyConfig) Reconcile(ctx context.Context, request reconcile.
 				fmt.Sprintf("Error getting network config '%s': %v.", names.CLUSTER_CONFIG, err))
 			return reconcile.Result{}, fmt.Errorf("failed to get network config '%s': %v", names.CLUSTER_CONFIG, err)
 		}
+		// Clusters installed by HyperShift or the agent-based installer may have
+		// no install-config, the machine networks then come from elsewhere.
 		if err := r.client.Get(ctx, types.NamespacedName{Name: "cluster-config-v1", Namespace: "kube-system"},
-			clusterConfig); err != nil {
+			clusterConfig); apierrors.IsNotFound(err) {
+			log.Printf("Configmap 'kube-system/cluster-config-v1' not found; the machine networks are not taken from the install-config")
+			clusterConfig = nil
+		} else if err != nil {
 			log.Printf("Failed to get configmap '%s/%s': %v", clusterConfig.Namespace, clusterConfig.Name, err)
 			r.status.SetDegraded(statusmanager.ProxyConfig, "ClusterConfigError",
 				fmt.Sprintf("Error getting cluster config configmap '%s/%s': %v.", clusterConfig.Namespace,
 					clusterConfig.Name, err))
 			return reconcile.Result{}, fmt.Errorf("failed to get configmap '%s/%s': %v", clusterConfig.Namespace, clusterConfig.Name, err)
 		}
+		var machineNetworks *proxyconfig.MachineNetworks
+		if clusterConfig == nil {
+			if machineNetworks, err = r.machineNetworks(ctx); err != nil {
+				log.Printf("Failed to get the machine networks: %v", err)
+				r.status.SetDegraded(statusmanager.ProxyConfig, "MachineNetworksError",
+					fmt.Sprintf("Error getting the machine networks: %v.", err))
+				return reconcile.Result{}, fmt.Errorf("failed to get the machine networks: %v", err)
+			}
+		}
 		// Update proxy status.
-		if err := r.syncProxyStatus(proxyConfig, infraConfig, netConfig, clusterConfig); err != nil {
+		if err := r.syncProxyStatus(proxyConfig, infraConfig, netConfig, clusterConfig, machineNetworks); err != nil {
 			log.Printf("Could not sync proxy '%s' status: %v", proxyConfig.Name, err)
 			r.status.SetDegraded(statusmanager.ProxyConfig, "StatusError",
 				fmt.Sprintf("Could not update proxy '%s' status: %v", proxyConfig.Name, err))
@@ -0,0 +1,109 @@
+package proxyconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/openshift/cluster-network-operator/pkg/hypershift"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/util/proxyconfig"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// nodePrimaryIfAddrAnnotation is set by ovn-kubernetes on each node to the
+// addresses, with their prefix length, of the primary interface of the node.
+const nodePrimaryIfAddrAnnotation = "k8s.ovn.org/node-primary-ifaddr"
+
+// machineNetworks returns the machine networks of a cluster that has no
+// install-config. They are the machine networks of the HostedControlPlane on
+// HyperShift, and otherwise the subnets of the primary interfaces of the
+// nodes. It returns nil if neither has any.
+func (r *ReconcileProxyConfig) machineNetworks(ctx context.Context) (*proxyconfig.MachineNetworks, error) {
+	if hc := hypershift.NewHyperShiftConfig(); hc.Enabled && r.hcpClient != nil {
+		hcp := &unstructured.Unstructured{}
+		hcp.SetGroupVersionKind(hypershift.HostedControlPlaneGVK)
+		nsn := types.NamespacedName{Namespace: hc.Namespace, Name: hc.Name}
+		if err := r.hcpClient.Get(ctx, nsn, hcp); err != nil {
+			return nil, fmt.Errorf("failed to retrieve HostedControlPlane %s: %v", nsn, err)
+		}
+		parsed, err := hypershift.ParseHostedControlPlane(hcp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HostedControlPlane %s: %v", nsn, err)
+		}
+		if len(parsed.MachineNetworks) > 0 {
+			return &proxyconfig.MachineNetworks{Source: "hostedcontrolplane machineNetwork", CIDRs: parsed.MachineNetworks}, nil
+		}
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := r.nodeReader.List(ctx, nodes); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	subnets := sets.New[string]()
+	for _, node := range nodes.Items {
+		nodeSubnets, err := nodePrimaryIfSubnets(&node)
+		if err != nil {
+			return nil, err
+		}
+		subnets.Insert(nodeSubnets...)
+	}
+	if subnets.Len() == 0 {
+		return nil, nil
+	}
+	return &proxyconfig.MachineNetworks{Source: "node primary interface subnets", CIDRs: sets.List(subnets)}, nil
+}
+
+// nodePrimaryIfSubnets returns the subnets of the addresses of the primary
+// interface of node.
+func nodePrimaryIfSubnets(node *corev1.Node) ([]string, error) {
+	value, ok := node.Annotations[nodePrimaryIfAddrAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	ifAddr := struct {
+		IPv4 string `json:"ipv4,omitempty"`
+		IPv6 string `json:"ipv6,omitempty"`
+	}{}
+	if err := json.Unmarshal([]byte(value), &ifAddr); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s of node %s: %v", nodePrimaryIfAddrAnnotation, node.Name, err)
+	}
+	subnets := []string{}
+	for _, addr := range []string{ifAddr.IPv4, ifAddr.IPv6} {
+		if addr == "" {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q in annotation %s of node %s: %v", addr, nodePrimaryIfAddrAnnotation, node.Name, err)
+		}
+		subnets = append(subnets, subnet.String())
+	}
+	return subnets, nil
+}
+
+// enqueueProxyConfig maps an event to a reconcile of the proxy.
+func enqueueProxyConfig(_ context.Context, _ crclient.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: names.Proxy()}}
+}
+
+// nodeSubnetsPredicate passes the node events that may change the subnets of
+// the primary interfaces of the nodes.
+var nodeSubnetsPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectOld.GetAnnotations()[nodePrimaryIfAddrAnnotation] != e.ObjectNew.GetAnnotations()[nodePrimaryIfAddrAnnotation]
+	},
+	GenericFunc: func(_ event.GenericEvent) bool {
+		return false
+	},
+}
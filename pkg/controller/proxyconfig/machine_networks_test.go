@@ -0,0 +1,51 @@
+package proxyconfig
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/util/proxyconfig"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMachineNetworks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	node := func(name, ifAddr string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if ifAddr != "" {
+			node.Annotations = map[string]string{nodePrimaryIfAddrAnnotation: ifAddr}
+		}
+		return node
+	}
+
+	// Without an annotated node, there is no machine network
+	client := fake.NewFakeClient(node("worker-0", ""))
+	r := &ReconcileProxyConfig{nodeReader: client.Default().CRClient()}
+	g.Expect(r.machineNetworks(ctx)).To(BeNil())
+
+	// The subnets of the primary interfaces of the nodes are the machine networks
+	client = fake.NewFakeClient(
+		node("master-0", `{"ipv4":"10.0.0.5/24","ipv6":"fd00:10::5/64"}`),
+		node("worker-0", `{"ipv4":"10.0.0.23/24"}`),
+		node("worker-1", `{"ipv4":"10.0.1.7/24"}`),
+		node("worker-2", ""),
+	)
+	r = &ReconcileProxyConfig{nodeReader: client.Default().CRClient()}
+	g.Expect(r.machineNetworks(ctx)).To(Equal(&proxyconfig.MachineNetworks{
+		Source: "node primary interface subnets",
+		CIDRs:  []string{"10.0.0.0/24", "10.0.1.0/24", "fd00:10::/64"},
+	}))
+
+	// An invalid annotation is an error
+	client = fake.NewFakeClient(node("worker-0", `{"ipv4":"10.0.0.23"}`))
+	r = &ReconcileProxyConfig{nodeReader: client.Default().CRClient()}
+	_, err := r.machineNetworks(ctx)
+	g.Expect(err).To(HaveOccurred())
+}
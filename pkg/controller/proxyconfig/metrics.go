@@ -0,0 +1,34 @@
+package proxyconfig
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	readinessProbeDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      "network_operator",
+			Subsystem:      "proxy",
+			Name:           "readiness_probe_duration_seconds",
+			Help:           "The duration of the periodic probes of the readinessEndpoints of the cluster proxy.",
+			Buckets:        []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"endpoint", "proxy"},
+	)
+	readinessProbeFailures = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      "network_operator",
+			Subsystem:      "proxy",
+			Name:           "readiness_probe_failures_total",
+			Help:           "The number of failed periodic probes of the readinessEndpoints of the cluster proxy.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"endpoint", "proxy"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(readinessProbeDuration, readinessProbeFailures)
+}
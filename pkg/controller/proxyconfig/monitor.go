@@ -0,0 +1,136 @@
+package proxyconfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/util/validation"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// proxyHealthProbeInterval is how often the readinessEndpoints of an
+	// accepted proxy are probed.
+	proxyHealthProbeInterval = time.Minute
+	// proxyUnreachableDegradedAfter is how long the readinessEndpoints must
+	// fail before the operator is Degraded. Until then it is Progressing.
+	proxyUnreachableDegradedAfter = 5 * time.Minute
+)
+
+// proxyHealthMonitor keeps probing the readinessEndpoints of the cluster proxy
+// after the proxy controller accepted its configuration, as proxies fail much
+// more often later on than when they are configured. The probes of the proxy
+// controller only run when the configuration changes.
+type proxyHealthMonitor struct {
+	client crclient.Client
+	status *statusmanager.StatusManager
+
+	// failingSince is when the probes started failing, zero while they pass
+	failingSince time.Time
+}
+
+// Start runs the probes until ctx is done. It implements manager.Runnable.
+func (m *proxyHealthMonitor) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		m.check(ctx, time.Now())
+	}, proxyHealthProbeInterval)
+	return nil
+}
+
+// proxyAccepted tells whether the proxy controller accepted the spec of the
+// proxy, which it then copies to the status.
+func proxyAccepted(proxy *configv1.Proxy) bool {
+	return (isSpecHTTPProxySet(&proxy.Spec) || isSpecHTTPSProxySet(&proxy.Spec)) &&
+		proxy.Status.HTTPProxy == proxy.Spec.HTTPProxy && proxy.Status.HTTPSProxy == proxy.Spec.HTTPSProxy
+}
+
+// check probes each readinessEndpoint of the accepted proxy once, and updates
+// the operator status with the result.
+func (m *proxyHealthMonitor) check(ctx context.Context, now time.Time) {
+	proxy := &configv1.Proxy{}
+	if err := m.client.Get(ctx, names.Proxy(), proxy); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Printf("Failed to get proxy '%s' for its health check: %v", names.PROXY_CONFIG, err)
+			return
+		}
+		m.setHealth(nil, now)
+		return
+	}
+	if !proxyAccepted(proxy) || !isSpecReadinessEndpointsSet(&proxy.Spec) {
+		m.setHealth(nil, now)
+		return
+	}
+
+	trustBundle := &corev1.ConfigMap{}
+	if err := m.client.Get(ctx, names.TrustedCABundleConfigMap(), trustBundle); err != nil {
+		log.Printf("Failed to get trusted CA bundle configmap for the proxy health check: %v", err)
+		return
+	}
+	caBundle, _, err := validation.TrustBundleConfigMap(trustBundle, names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY)
+	if err != nil {
+		log.Printf("Failed to validate trusted CA bundle configmap for the proxy health check: %v", err)
+		return
+	}
+
+	failures := []string{}
+	for _, endpoint := range proxy.Spec.ReadinessEndpoints {
+		endpointURL, err := url.Parse(endpoint)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid readinessEndpoint '%s': %v", endpoint, err))
+			continue
+		}
+		proxyField, proxyValue := readinessEndpointProxy(&proxy.Spec, endpointURL.Scheme)
+		proxyURL, err := url.Parse(proxyValue)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid %s: %v", proxyField, err))
+			continue
+		}
+
+		start := time.Now()
+		err = runReadinessProbe(caBundle, proxyURL, endpointURL)
+		readinessProbeDuration.WithLabelValues(endpointURL.Redacted(), proxyField).Observe(time.Since(start).Seconds())
+		if err != nil {
+			readinessProbeFailures.WithLabelValues(endpointURL.Redacted(), proxyField).Inc()
+			failures = append(failures, err.Error())
+		}
+	}
+	m.setHealth(failures, now)
+}
+
+// setHealth reports failing probes as Progressing, and as Degraded once they
+// have been failing for proxyUnreachableDegradedAfter.
+func (m *proxyHealthMonitor) setHealth(failures []string, now time.Time) {
+	if len(failures) == 0 {
+		if !m.failingSince.IsZero() {
+			log.Printf("The readinessEndpoints of proxy '%s' are reachable again", names.PROXY_CONFIG)
+		}
+		m.failingSince = time.Time{}
+		m.status.UnsetProgressing(statusmanager.ProxyHealth)
+		m.status.SetNotDegraded(statusmanager.ProxyHealth)
+		return
+	}
+
+	if m.failingSince.IsZero() {
+		m.failingSince = now
+	}
+	message := fmt.Sprintf("The readinessEndpoints of proxy '%s' are unreachable since %s: %s",
+		names.PROXY_CONFIG, m.failingSince.UTC().Format(time.RFC3339), strings.Join(failures, "; "))
+	log.Print(message)
+	if now.Sub(m.failingSince) < proxyUnreachableDegradedAfter {
+		m.status.SetProgressing(statusmanager.ProxyHealth, "ProxyProbeFailing", message)
+		return
+	}
+	m.status.SetDegradedClearingProgressing(statusmanager.ProxyHealth, "ProxyUnreachable", message)
+}
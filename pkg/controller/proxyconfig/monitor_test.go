@@ -0,0 +1,93 @@
+package proxyconfig
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestProxyHealthMonitor(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	// A stand-in proxy that answers the requests it forwards itself
+	var healthy atomic.Bool
+	healthy.Store(true)
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer proxyServer.Close()
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+
+	proxy := &configv1.Proxy{
+		ObjectMeta: metav1.ObjectMeta{Name: names.PROXY_CONFIG},
+		Spec: configv1.ProxySpec{
+			HTTPProxy:          proxyServer.URL,
+			ReadinessEndpoints: []string{"http://readiness.example.com/healthz"},
+		},
+		Status: configv1.ProxyStatus{HTTPProxy: proxyServer.URL},
+	}
+	trustBundle := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS, Name: names.TRUSTED_CA_BUNDLE_CONFIGMAP},
+		Data: map[string]string{
+			names.TRUSTED_CA_BUNDLE_CONFIGMAP_KEY: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})),
+		},
+	}
+	client := fake.NewFakeClient(&operv1.Network{ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG}}, proxy, trustBundle)
+	status := statusmanager.New(client, "testing", names.StandAloneClusterName)
+	m := &proxyHealthMonitor{client: client.Default().CRClient(), status: status}
+
+	condition := func(conditionType string) *operv1.OperatorCondition {
+		oc := &operv1.Network{}
+		g.Expect(client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, oc)).To(Succeed())
+		return v1helpers.FindOperatorCondition(oc.Status.Conditions, conditionType)
+	}
+
+	now := time.Now()
+	m.check(ctx, now)
+	g.Expect(condition(operv1.OperatorStatusTypeProgressing)).To(HaveField("Status", operv1.ConditionFalse))
+	g.Expect(condition(operv1.OperatorStatusTypeDegraded)).To(HaveField("Status", operv1.ConditionFalse))
+
+	// The proxy fails after it was accepted
+	healthy.Store(false)
+	m.check(ctx, now.Add(time.Minute))
+	g.Expect(condition(operv1.OperatorStatusTypeProgressing)).To(HaveField("Reason", "ProxyProbeFailing"))
+	g.Expect(condition(operv1.OperatorStatusTypeProgressing).Message).To(ContainSubstring("statuscode '502'"))
+	g.Expect(condition(operv1.OperatorStatusTypeDegraded)).To(HaveField("Status", operv1.ConditionFalse))
+
+	m.check(ctx, now.Add(time.Minute+proxyUnreachableDegradedAfter))
+	g.Expect(condition(operv1.OperatorStatusTypeProgressing)).To(HaveField("Status", operv1.ConditionFalse))
+	g.Expect(condition(operv1.OperatorStatusTypeDegraded)).To(HaveField("Reason", "ProxyUnreachable"))
+
+	healthy.Store(true)
+	m.check(ctx, now.Add(10*time.Minute))
+	g.Expect(condition(operv1.OperatorStatusTypeDegraded)).To(HaveField("Status", operv1.ConditionFalse))
+	g.Expect(m.failingSince.IsZero()).To(BeTrue())
+
+	// A proxy that was not accepted yet is left to the proxy controller
+	healthy.Store(false)
+	proxy.Status.HTTPProxy = ""
+	g.Expect(client.Default().CRClient().Update(ctx, proxy)).To(Succeed())
+	m.check(ctx, now.Add(11*time.Minute))
+	g.Expect(condition(operv1.OperatorStatusTypeProgressing)).To(HaveField("Status", operv1.ConditionFalse))
+}
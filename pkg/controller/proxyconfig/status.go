@@ -2,31 +2,41 @@ package proxyconfig
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
 	"github.com/openshift/cluster-network-operator/pkg/util/proxyconfig"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // syncProxyStatus computes the current status of proxy and
 // updates status of any changes since last sync.
-func (r *ReconcileProxyConfig) syncProxyStatus(proxy *configv1.Proxy, infra *configv1.Infrastructure, network *configv1.Network, cluster *corev1.ConfigMap) error {
+func (r *ReconcileProxyConfig) syncProxyStatus(proxy *configv1.Proxy, infra *configv1.Infrastructure, network *configv1.Network, cluster *corev1.ConfigMap, machineNetworks *proxyconfig.MachineNetworks) error {
 	var err error
-	var noProxy string
+	explanation := &proxyconfig.NoProxyExplanation{Entries: []proxyconfig.NoProxyEntry{}}
 	updated := proxy.DeepCopy()
 
 	if isSpecNoProxySet(&proxy.Spec) || isSpecHTTPProxySet(&proxy.Spec) || isSpecHTTPSProxySet(&proxy.Spec) {
 		if proxy.Spec.NoProxy == noProxyWildcard {
-			noProxy = proxy.Spec.NoProxy
+			explanation.Entries = []proxyconfig.NoProxyEntry{{Value: noProxyWildcard, Sources: []string{"proxy noProxy"}}}
 		} else {
-			noProxy, err = proxyconfig.MergeUserSystemNoProxy(proxy, infra, network, cluster)
+			explanation, err = proxyconfig.ExplainNoProxy(proxy, infra, network, cluster, machineNetworks)
 			if err != nil {
 				return fmt.Errorf("failed to merge user/system noProxy settings: %v", err)
 			}
 		}
 	}
+	noProxy := explanation.NoProxy()
+	if err := r.syncNoProxySources(explanation); err != nil {
+		return err
+	}
 
 	updated.Status.HTTPProxy = proxy.Spec.HTTPProxy
 	updated.Status.HTTPSProxy = proxy.Spec.HTTPSProxy
@@ -41,6 +51,47 @@ func (r *ReconcileProxyConfig) syncProxyStatus(proxy *configv1.Proxy, infra *con
 	return nil
 }
 
+// syncNoProxySources records which source contributed each entry of the
+// noProxy list in a ConfigMap, as the proxy status only holds the list.
+func (r *ReconcileProxyConfig) syncNoProxySources(explanation *proxyconfig.NoProxyExplanation) error {
+	for _, warning := range explanation.Warnings {
+		log.Printf("Warning: noProxy of proxy '%s': %s", names.PROXY_CONFIG, warning)
+	}
+	data, err := json.MarshalIndent(explanation, "", "  ")
+	if err != nil {
+		return err
+	}
+	sources := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.NO_PROXY_SOURCES_CONFIGMAP,
+			Namespace: names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS,
+			Annotations: map[string]string{
+				names.OpenShiftComponent: names.ClusterNetworkOperatorJiraComponent,
+			},
+		},
+		Data: map[string]string{
+			names.NO_PROXY_SOURCES_CONFIGMAP_KEY: string(data),
+		},
+	}
+
+	current := &corev1.ConfigMap{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: sources.Namespace, Name: sources.Name}, current); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get configmap '%s/%s': %v", sources.Namespace, sources.Name, err)
+		}
+		if err := r.client.Create(context.TODO(), sources); err != nil {
+			return fmt.Errorf("failed to create configmap '%s/%s': %v", sources.Namespace, sources.Name, err)
+		}
+		return nil
+	}
+	if !configMapsEqual(names.NO_PROXY_SOURCES_CONFIGMAP_KEY, current, sources) {
+		if err := r.client.Update(context.TODO(), sources); err != nil {
+			return fmt.Errorf("failed to update configmap '%s/%s': %v", sources.Namespace, sources.Name, err)
+		}
+	}
+	return nil
+}
+
 // proxyStatusesEqual compares two ProxyStatus values. Returns true if the
 // provided values should be considered equal for the purpose of determining
 // whether an update is necessary, false otherwise.
@@ -33,6 +33,8 @@ const (
 	proxyProbeMaxRetries = 3
 	// proxyProbeWaitTime is the time to wait before retrying a failed proxy probe.
 	proxyProbeWaitTime = 1 * time.Second
+	// proxyProbeTimeout bounds a single readinessEndpoints probe.
+	proxyProbeTimeout = 30 * time.Second
 )
 
 // ValidateProxyConfig ensures that httpProxy, httpsProxy and
@@ -104,14 +106,9 @@ func (r *ReconcileProxyConfig) ValidateProxyConfig(proxyConfig *configv1.ProxySp
 			if err != nil {
 				return fmt.Errorf("failed to merge system and trustedCA trust bundles: %v", err)
 			}
-			if scheme == schemeHTTPS && isSpecHTTPSProxySet(proxyConfig) {
-				if err := validateReadinessEndpoint(trustBundle, proxyConfig.HTTPSProxy, endpoint); err != nil {
-					return fmt.Errorf("readinessEndpoint probe failed for endpoint '%s': %v", endpoint, err)
-				}
-			} else {
-				if err := validateReadinessEndpoint(trustBundle, proxyConfig.HTTPProxy, endpoint); err != nil {
-					return fmt.Errorf("readinessEndpoint probe failed for endpoint '%s': %v", endpoint, err)
-				}
+			_, proxy := readinessEndpointProxy(proxyConfig, scheme)
+			if err := validateReadinessEndpoint(trustBundle, proxy, endpoint); err != nil {
+				return fmt.Errorf("readinessEndpoint probe failed for endpoint '%s': %v", endpoint, err)
 			}
 		}
 	}
@@ -119,6 +116,15 @@ func (r *ReconcileProxyConfig) ValidateProxyConfig(proxyConfig *configv1.ProxySp
 	return nil
 }
 
+// readinessEndpointProxy returns the name of the field and the value of the
+// proxy that a readinessEndpoint with the given scheme is probed through.
+func readinessEndpointProxy(proxyConfig *configv1.ProxySpec, scheme string) (string, string) {
+	if scheme == schemeHTTPS && isSpecHTTPSProxySet(proxyConfig) {
+		return "httpsProxy", proxyConfig.HTTPSProxy
+	}
+	return "httpProxy", proxyConfig.HTTPProxy
+}
+
 // validateTrustedCA validates that trustedCA is a valid ConfigMap
 // reference and that the ConfigMap contains a valid trust bundle,
 // returning the byte slices of the certificate data from the
@@ -266,18 +272,19 @@ func runReadinessProbe(caBundle []*x509.Certificate, proxyURL, endpoint *url.URL
 
 	client := &http.Client{
 		Transport: transport,
+		Timeout:   proxyProbeTimeout,
 	}
 
 	request, err := http.NewRequest("GET", endpoint.String(), nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request for '%s' using proxy '%s': %v", endpoint.String(),
-			proxyURL.String(), err)
+			proxyURL.Redacted(), err)
 	}
 
 	resp, err := client.Do(request)
 	if err != nil {
 		return fmt.Errorf("endpoint probe failed for endpoint '%s' using proxy '%s': %v",
-			endpoint.String(), proxyURL.String(), err)
+			endpoint.String(), proxyURL.Redacted(), err)
 	}
 	defer resp.Body.Close()
 
@@ -286,5 +293,5 @@ func runReadinessProbe(caBundle []*x509.Certificate, proxyURL, endpoint *url.URL
 	}
 
 	return fmt.Errorf("endpoint probe failed with statuscode '%d' for endpoint '%s' using proxy '%s' ",
-		resp.StatusCode, endpoint.String(), proxyURL.String())
+		resp.StatusCode, endpoint.String(), proxyURL.Redacted())
 }
@@ -0,0 +1,56 @@
+package statusmanager
+
+import (
+	"context"
+	"reflect"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// SetOperatorConditions sets conditions on the operator configuration, next to
+// the ones maintained by the StatusManager, for the controllers reporting
+// conditions of their own. The transition times are only updated when a
+// condition's status changes.
+func SetOperatorConditions(ctx context.Context, client cnoclient.Client, conditions ...operv1.OperatorCondition) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+	return updateOperatorConditions(ctx, client, func(updated *[]operv1.OperatorCondition) {
+		for _, condition := range conditions {
+			v1helpers.SetOperatorCondition(updated, condition)
+		}
+	})
+}
+
+// RemoveOperatorConditions removes the given conditions from the operator
+// configuration.
+func RemoveOperatorConditions(ctx context.Context, client cnoclient.Client, conditionTypes ...string) error {
+	return updateOperatorConditions(ctx, client, func(updated *[]operv1.OperatorCondition) {
+		for _, conditionType := range conditionTypes {
+			v1helpers.RemoveOperatorCondition(updated, conditionType)
+		}
+	})
+}
+
+func updateOperatorConditions(ctx context.Context, client cnoclient.Client, update func(*[]operv1.OperatorCondition)) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		oc := &operv1.Network{}
+		if err := client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, oc); err != nil {
+			return err
+		}
+		updated := make([]operv1.OperatorCondition, len(oc.Status.Conditions))
+		copy(updated, oc.Status.Conditions)
+		update(&updated)
+		if reflect.DeepEqual(oc.Status.Conditions, updated) {
+			return nil
+		}
+		oc.Status.Conditions = updated
+		return client.Default().CRClient().Update(ctx, oc)
+	})
+}
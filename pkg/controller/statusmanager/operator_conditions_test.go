@@ -0,0 +1,62 @@
+package statusmanager
+
+import (
+	"context"
+	"testing"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestOperatorConditions(t *testing.T) {
+	ctx := context.TODO()
+	available := operv1.OperatorCondition{Type: operv1.OperatorStatusTypeAvailable, Status: operv1.ConditionTrue}
+	client := fake.NewFakeClient(&operv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG},
+		Status:     operv1.NetworkStatus{OperatorStatus: operv1.OperatorStatus{Conditions: []operv1.OperatorCondition{available}}},
+	})
+	getConditions := func() []operv1.OperatorCondition {
+		oc := &operv1.Network{}
+		if err := client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, oc); err != nil {
+			t.Fatalf("error getting network config: %v", err)
+		}
+		return oc.Status.Conditions
+	}
+
+	condition := operv1.OperatorCondition{Type: names.WhereaboutsIPReconciled, Status: operv1.ConditionTrue, Reason: "Reconciled"}
+	if err := SetOperatorConditions(ctx, client, condition); err != nil {
+		t.Fatalf("error setting conditions: %v", err)
+	}
+	conditions := getConditions()
+	if len(conditions) != 2 || !v1helpers.IsOperatorConditionTrue(conditions, operv1.OperatorStatusTypeAvailable) {
+		t.Fatalf("unexpected conditions %v", conditions)
+	}
+	set := v1helpers.FindOperatorCondition(conditions, names.WhereaboutsIPReconciled)
+	if set == nil || set.Reason != "Reconciled" || set.LastTransitionTime.IsZero() {
+		t.Fatalf("unexpected condition %v", set)
+	}
+
+	// The transition time is kept while the status does not change
+	transitionTime := set.LastTransitionTime
+	condition.Reason = "StillReconciled"
+	if err := SetOperatorConditions(ctx, client, condition); err != nil {
+		t.Fatalf("error setting conditions: %v", err)
+	}
+	set = v1helpers.FindOperatorCondition(getConditions(), names.WhereaboutsIPReconciled)
+	if set.Reason != "StillReconciled" || !set.LastTransitionTime.Equal(&transitionTime) {
+		t.Fatalf("unexpected condition %v", set)
+	}
+
+	if err := RemoveOperatorConditions(ctx, client, names.WhereaboutsIPReconciled); err != nil {
+		t.Fatalf("error removing conditions: %v", err)
+	}
+	conditions = getConditions()
+	if len(conditions) != 1 || conditions[0].Type != operv1.OperatorStatusTypeAvailable {
+		t.Fatalf("unexpected conditions %v", conditions)
+	}
+}
@@ -51,6 +51,9 @@ const (
 	CertificateSigner
 	InfrastructureConfig
 	DashboardConfig
+	DHCPDaemon
+	ProxyHealth
+	InjectorRollout
 	maxStatusLevel
 )
 
@@ -97,6 +100,7 @@ type StatusManager struct {
 	ssInformers map[string]cache.SharedIndexInformer
 	ssListers   map[string]StatefulSetLister
 
+	cluster       string
 	labelSelector labels.Selector
 
 	relatedObjects []configv1.ObjectReference
@@ -118,6 +122,7 @@ func New(client cnoclient.Client, name, cluster string) *StatusManager {
 		depListers:   map[string]DeploymentLister{},
 		ssInformers:  map[string]cache.SharedIndexInformer{},
 		ssListers:    map[string]StatefulSetLister{},
+		cluster:      cluster,
 	}
 	var err error
 	status.labelSelector, err = labels.Parse(fmt.Sprintf("%s==%s", names.GenerateStatusLabel, cluster))
@@ -127,6 +132,12 @@ func New(client cnoclient.Client, name, cluster string) *StatusManager {
 	return status
 }
 
+// Cluster returns the value of the GenerateStatusLabel of the objects whose
+// rollout is tracked by the StatusManager
+func (status *StatusManager) Cluster() string {
+	return status.cluster
+}
+
 // setClusterOperAnnotation sets an annotation on the clusterOperator network object
 func (status *StatusManager) setClusterOperAnnotation(obj *configv1.ClusterOperator) error {
 	value := []string{}
@@ -523,6 +534,17 @@ func (status *StatusManager) SetNotDegraded(statusLevel StatusLevel) {
 	status.setNotDegraded(statusLevel)
 }
 
+// SetDegradedClearingProgressing sets statusLevel Degraded after a Progressing
+// phase. A status level holds a single condition, so setting it Degraded
+// alone would replace the Progressing one without syncing the Progressing
+// status, which would then be left true.
+func (status *StatusManager) SetDegradedClearingProgressing(statusLevel StatusLevel, reason, message string) {
+	status.Lock()
+	defer status.Unlock()
+	status.unsetProgressing(statusLevel)
+	status.setDegraded(statusLevel, reason, message)
+}
+
 // syncProgressing syncs the current Progressing status
 func (status *StatusManager) syncProgressing() {
 	for _, c := range status.failing {
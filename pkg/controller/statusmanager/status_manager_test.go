@@ -343,6 +343,18 @@ func TestStatusManagerSetDegraded(t *testing.T) {
 	if !v1helpers.IsOperatorConditionFalse(oc.Status.Conditions, operv1.OperatorStatusTypeDegraded) && !conditionsEqual(oc.Status.Conditions, []operv1.OperatorCondition{condUpdate}) {
 		t.Fatalf("unexpected Status.Conditions: %#v", oc.Status.Conditions)
 	}
+
+	// A Progressing status level that becomes Degraded is no longer Progressing
+	status.SetProgressing(OperatorConfig, "Rollout", "")
+	status.SetDegradedClearingProgressing(OperatorConfig, "Operator", "")
+	oc, err = getOC(client)
+	if err != nil {
+		t.Fatalf("error getting ClusterOperator: %v", err)
+	}
+	if !v1helpers.IsOperatorConditionTrue(oc.Status.Conditions, operv1.OperatorStatusTypeDegraded) ||
+		v1helpers.IsOperatorConditionTrue(oc.Status.Conditions, operv1.OperatorStatusTypeProgressing) {
+		t.Fatalf("unexpected Status.Conditions: %#v", oc.Status.Conditions)
+	}
 }
 
 func TestStatusManagerSetFromDaemonSets(t *testing.T) {
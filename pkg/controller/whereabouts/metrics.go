@@ -0,0 +1,76 @@
+package whereabouts
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	metricsNamespace = "network_operator"
+	metricsSubsystem = "whereabouts"
+)
+
+var (
+	ippoolSize = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "ippool_size",
+			Help:           "The number of addresses in the CIDR of a whereabouts IP pool.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"range"},
+	)
+	ippoolAllocated = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "ippool_allocated",
+			Help:           "The number of addresses allocated from a whereabouts IP pool.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"range"},
+	)
+	ippoolLeaked = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "ippool_leaked",
+			Help:           "The number of addresses allocated from a whereabouts IP pool to pods that no longer exist.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"range"},
+	)
+	overlappingReservationsLeaked = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "overlapping_reservations_leaked",
+			Help:           "The number of whereabouts overlapping range reservations held by pods that no longer exist.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+	ipReconcilerLastRelease = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "ip_reconciler_last_release_timestamp_seconds",
+			Help:           "The time the ip-reconciler was last seen releasing leaked allocations, in seconds since the epoch.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(ippoolSize, ippoolAllocated, ippoolLeaked, overlappingReservationsLeaked, ipReconcilerLastRelease)
+}
+
+// resetMetrics clears all whereabouts metrics, so that pools that were deleted
+// since the last reconcile, or a whereabouts that is not in use, are not reported.
+func resetMetrics() {
+	ippoolSize.Reset()
+	ippoolAllocated.Reset()
+	ippoolLeaked.Reset()
+	overlappingReservationsLeaked.Set(0)
+	ipReconcilerLastRelease.Set(0)
+}
@@ -0,0 +1,401 @@
+package whereabouts
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// reconcilerDaemonSet is the ip-reconciler, rendered by the operator whenever an
+// additional network uses whereabouts
+const reconcilerDaemonSet = "whereabouts-reconciler"
+
+// resyncPeriod is how often IP pools are inspected, since nothing triggers a
+// reconcile when pods holding an allocation go away
+var resyncPeriod = 5 * time.Minute
+
+// maxLeaksInMessage caps the number of leaked allocations listed in the condition
+const maxLeaksInMessage = 10
+
+// lastReleaseMessage ends the message of the WhereaboutsIPReconciled condition
+// once the ip-reconciler was seen releasing leaked allocations. The time itself
+// is recorded in the IPReconcilerLastReleaseAnnotation of the DaemonSet.
+const lastReleaseMessage = "The ip-reconciler last released leaked allocations at "
+
+var (
+	ippoolGVK                 = schema.GroupVersionKind{Group: "whereabouts.cni.cncf.io", Version: "v1alpha1", Kind: "IPPoolList"}
+	overlappingReservationGVK = schema.GroupVersionKind{Group: "whereabouts.cni.cncf.io", Version: "v1alpha1", Kind: "OverlappingRangeIPReservationList"}
+)
+
+// Add creates a new whereabouts controller and adds it to the manager.
+func Add(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client) error {
+	return add(mgr, newReconciler(c))
+}
+
+func newReconciler(c cnoclient.Client) *ReconcileWhereabouts {
+	return &ReconcileWhereabouts{client: c, leaked: sets.New[string]()}
+}
+
+func add(mgr manager.Manager, r *ReconcileWhereabouts) error {
+	c, err := controller.New("whereabouts-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Reconcile when the operator configuration changes, which is when whereabouts
+	// may start or stop being used. From then on, the reconciler requeues itself.
+	return c.Watch(source.Kind(mgr.GetCache(), &operv1.Network{}), &handler.EnqueueRequestForObject{},
+		predicate.GenerationChangedPredicate{})
+}
+
+var _ reconcile.Reconciler = &ReconcileWhereabouts{}
+
+// ReconcileWhereabouts reports the usage of the whereabouts IP pools, and the
+// allocations that the ip-reconciler has not released yet.
+type ReconcileWhereabouts struct {
+	client cnoclient.Client
+
+	// leaked are the leaked allocations found at the previous reconcile. When
+	// one of them is no longer allocated, the ip-reconciler has run and
+	// released it.
+	leaked sets.Set[string]
+}
+
+// leakedAllocation is an IP address allocated to a pod that no longer exists
+type leakedAllocation struct {
+	ip     string
+	podRef string
+}
+
+// poolUsage is the usage of a single whereabouts IP pool
+type poolUsage struct {
+	cidr      string
+	size      float64
+	allocated int
+	leaked    []leakedAllocation
+}
+
+func (r *ReconcileWhereabouts) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	if request.Name != names.OPERATOR_CONFIG {
+		return reconcile.Result{}, nil
+	}
+
+	ds, err := r.reconcilerDaemonSet(ctx)
+	if err != nil {
+		klog.Errorf("Failed to determine whether whereabouts is in use: %v", err)
+		return reconcile.Result{}, err
+	}
+	if ds == nil {
+		resetMetrics()
+		r.leaked = sets.New[string]()
+		if err := statusmanager.RemoveOperatorConditions(ctx, r.client, names.WhereaboutsIPReconciled); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	pools, leakedReservations, allocations, err := r.inspect(ctx)
+	if err != nil {
+		klog.Errorf("Failed to inspect whereabouts IP pools: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	// A leaked allocation that stopped leaking may also belong to a pod
+	// recreated with the same name; it is only released once it is gone.
+	lastRelease := recordedLastRelease(ds)
+	if released := r.leaked.Difference(allocations); released.Len() > 0 {
+		klog.Infof("The whereabouts ip-reconciler released %d leaked allocations", released.Len())
+		lastRelease = time.Now().UTC().Truncate(time.Second)
+		if err := r.recordLastRelease(ctx, ds, lastRelease); err != nil {
+			klog.Errorf("Failed to record the last release of the whereabouts ip-reconciler: %v", err)
+			return reconcile.Result{}, err
+		}
+	}
+	r.leaked = leakKeys(pools, leakedReservations)
+
+	resetMetrics()
+	for _, pool := range pools {
+		ippoolSize.WithLabelValues(pool.cidr).Set(pool.size)
+		ippoolAllocated.WithLabelValues(pool.cidr).Set(float64(pool.allocated))
+		ippoolLeaked.WithLabelValues(pool.cidr).Set(float64(len(pool.leaked)))
+	}
+	overlappingReservationsLeaked.Set(float64(len(leakedReservations)))
+	if !lastRelease.IsZero() {
+		ipReconcilerLastRelease.Set(float64(lastRelease.Unix()))
+	}
+
+	if err := statusmanager.SetOperatorConditions(ctx, r.client, reconciledCondition(pools, leakedReservations, ds, lastRelease)); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// reconcilerDaemonSet returns the ip-reconciler DaemonSet, or nil if it is not
+// deployed, that is if no additional network uses whereabouts.
+func (r *ReconcileWhereabouts) reconcilerDaemonSet(ctx context.Context) (*appsv1.DaemonSet, error) {
+	ds := &appsv1.DaemonSet{}
+	err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.MULTUS_NAMESPACE, Name: reconcilerDaemonSet}, ds)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// recordedLastRelease returns the time the ip-reconciler was last seen
+// releasing leaked allocations, as recorded on its DaemonSet, or the zero time
+// if it is not recorded.
+func recordedLastRelease(ds *appsv1.DaemonSet) time.Time {
+	lastRelease, err := time.Parse(time.RFC3339, ds.Annotations[names.IPReconcilerLastReleaseAnnotation])
+	if err != nil {
+		return time.Time{}
+	}
+	return lastRelease
+}
+
+// recordLastRelease records on the ip-reconciler DaemonSet the time it was
+// seen releasing leaked allocations, so that it is kept across restarts of the
+// operator.
+func (r *ReconcileWhereabouts) recordLastRelease(ctx context.Context, ds *appsv1.DaemonSet, lastRelease time.Time) error {
+	patch := crclient.MergeFrom(ds.DeepCopy())
+	if ds.Annotations == nil {
+		ds.Annotations = map[string]string{}
+	}
+	ds.Annotations[names.IPReconcilerLastReleaseAnnotation] = lastRelease.UTC().Format(time.RFC3339)
+	return r.client.Default().CRClient().Patch(ctx, ds, patch)
+}
+
+// leakKeys identifies the leaked allocations, to tell which ones were released
+// between two reconciles. The keys match those of allocationKey.
+func leakKeys(pools []poolUsage, leakedReservations []leakedAllocation) sets.Set[string] {
+	keys := sets.New[string]()
+	for _, pool := range pools {
+		for _, l := range pool.leaked {
+			keys.Insert(allocationKey(pool.cidr, l))
+		}
+	}
+	for _, l := range leakedReservations {
+		keys.Insert(allocationKey("reservation", l))
+	}
+	return keys
+}
+
+// allocationKey identifies an allocation of a pool, or an overlapping range
+// reservation, by its address and its pod
+func allocationKey(pool string, l leakedAllocation) string {
+	return pool + "/" + l.ip + "/" + l.podRef
+}
+
+// inspect lists the whereabouts IP pools and overlapping range reservations,
+// and finds the allocations that belong to pods that no longer exist. It also
+// returns the keys of every allocation, leaked or not.
+func (r *ReconcileWhereabouts) inspect(ctx context.Context) ([]poolUsage, []leakedAllocation, sets.Set[string], error) {
+	ippools, err := r.list(ctx, ippoolGVK)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	reservations, err := r.list(ctx, overlappingReservationGVK)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	allocated := sets.New[string]()
+
+	podExists := r.podLookup(ctx)
+
+	pools := []poolUsage{}
+	for _, ippool := range ippools {
+		cidr, _, _ := uns.NestedString(ippool.Object, "spec", "range")
+		pool := poolUsage{cidr: cidr, size: rangeSize(cidr)}
+
+		allocations, _, _ := uns.NestedMap(ippool.Object, "spec", "allocations")
+		offsets := make([]string, 0, len(allocations))
+		for offset := range allocations {
+			offsets = append(offsets, offset)
+		}
+		sort.Strings(offsets)
+		for _, offset := range offsets {
+			pool.allocated++
+			allocation, ok := allocations[offset].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			podRef, _ := allocation["podref"].(string)
+			l := leakedAllocation{ip: allocatedIP(cidr, offset), podRef: podRef}
+			allocated.Insert(allocationKey(cidr, l))
+			exists, err := podExists(podRef)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if !exists {
+				pool.leaked = append(pool.leaked, l)
+			}
+		}
+		pools = append(pools, pool)
+	}
+
+	leakedReservations := []leakedAllocation{}
+	for _, reservation := range reservations {
+		podRef, _, _ := uns.NestedString(reservation.Object, "spec", "podref")
+		l := leakedAllocation{ip: reservation.GetName(), podRef: podRef}
+		allocated.Insert(allocationKey("reservation", l))
+		exists, err := podExists(podRef)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !exists {
+			leakedReservations = append(leakedReservations, l)
+		}
+	}
+
+	return pools, leakedReservations, allocated, nil
+}
+
+// list returns the whereabouts objects of the given list kind. If the
+// whereabouts CRDs are not installed, there is nothing to list.
+func (r *ReconcileWhereabouts) list(ctx context.Context, gvk schema.GroupVersionKind) ([]uns.Unstructured, error) {
+	list := &uns.UnstructuredList{}
+	list.SetGroupVersionKind(gvk)
+	err := r.client.Default().CRClient().List(ctx, list, crclient.InNamespace(names.MULTUS_NAMESPACE))
+	if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list %s: %w", gvk.Kind, err)
+	}
+	return list.Items, nil
+}
+
+// podLookup returns a function that tells whether the pod of a whereabouts
+// podref ("namespace/name") exists. Pods are listed once per namespace.
+// Allocations without a podref can't be checked and are assumed to be in use.
+func (r *ReconcileWhereabouts) podLookup(ctx context.Context) func(podRef string) (bool, error) {
+	podsByNamespace := map[string]sets.Set[string]{}
+	return func(podRef string) (bool, error) {
+		namespace, name, found := strings.Cut(podRef, "/")
+		if !found || name == "" {
+			return true, nil
+		}
+		pods, ok := podsByNamespace[namespace]
+		if !ok {
+			podList := &metav1.PartialObjectMetadataList{}
+			podList.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PodList"))
+			if err := r.client.Default().CRClient().List(ctx, podList, crclient.InNamespace(namespace)); err != nil {
+				return false, fmt.Errorf("could not list pods in namespace %s: %w", namespace, err)
+			}
+			pods = sets.New[string]()
+			for _, pod := range podList.Items {
+				pods.Insert(pod.Name)
+			}
+			podsByNamespace[namespace] = pods
+		}
+		return pods.Has(name), nil
+	}
+}
+
+// rangeSize returns the number of addresses in a CIDR
+func rangeSize(cidr string) float64 {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return 0
+	}
+	ones, bits := ipNet.Mask.Size()
+	return math.Pow(2, float64(bits-ones))
+}
+
+// allocatedIP returns the IP address at the given offset of a whereabouts range,
+// falling back to the offset itself if it can't be computed
+func allocatedIP(cidr, offset string) string {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return offset
+	}
+	var n uint64
+	if _, err := fmt.Sscanf(offset, "%d", &n); err != nil {
+		return offset
+	}
+	ip := make(net.IP, len(ipNet.IP))
+	copy(ip, ipNet.IP)
+	for i := len(ip) - 1; i >= 0 && n > 0; i-- {
+		sum := uint64(ip[i]) + n&0xff
+		ip[i] = byte(sum)
+		n = n>>8 + sum>>8
+	}
+	return ip.String()
+}
+
+// reconciledCondition builds the WhereaboutsIPReconciled condition from the
+// leaked allocations found in the pools and overlapping range reservations, and
+// from the state of the ip-reconciler DaemonSet. The condition is only True when
+// there are no leaks and the ip-reconciler runs on every node it should. The time
+// the ip-reconciler was last seen releasing leaked allocations, if ever, ends
+// the message.
+func reconciledCondition(pools []poolUsage, leakedReservations []leakedAllocation, ds *appsv1.DaemonSet, lastRelease time.Time) operv1.OperatorCondition {
+	leaks := []string{}
+	allocated := 0
+	for _, pool := range pools {
+		allocated += pool.allocated
+		for _, l := range pool.leaked {
+			leaks = append(leaks, fmt.Sprintf("%s in %s (%s)", l.ip, pool.cidr, l.podRef))
+		}
+	}
+	for _, l := range leakedReservations {
+		leaks = append(leaks, fmt.Sprintf("reservation %s (%s)", l.ip, l.podRef))
+	}
+
+	cond := operv1.OperatorCondition{
+		Type:    names.WhereaboutsIPReconciled,
+		Status:  operv1.ConditionTrue,
+		Reason:  "NoLeakedAllocations",
+		Message: fmt.Sprintf("All %d whereabouts allocations in %d IP pools belong to existing pods.", allocated, len(pools)),
+	}
+	if len(leaks) > 0 {
+		cond.Status = operv1.ConditionFalse
+		cond.Reason = "LeakedAllocations"
+		cond.Message = fmt.Sprintf("%d whereabouts allocations belong to pods that no longer exist and have not been released by the ip-reconciler: ", len(leaks))
+		if len(leaks) > maxLeaksInMessage {
+			cond.Message += strings.Join(leaks[:maxLeaksInMessage], ", ") + fmt.Sprintf(" and %d more.", len(leaks)-maxLeaksInMessage)
+		} else {
+			cond.Message += strings.Join(leaks, ", ") + "."
+		}
+	}
+	if ds.Status.DesiredNumberScheduled == 0 || ds.Status.NumberAvailable < ds.Status.DesiredNumberScheduled {
+		if cond.Status == operv1.ConditionTrue {
+			cond.Status = operv1.ConditionFalse
+			cond.Reason = "IPReconcilerUnavailable"
+		}
+		cond.Message += fmt.Sprintf(" The ip-reconciler is available on %d of %d nodes.", ds.Status.NumberAvailable, ds.Status.DesiredNumberScheduled)
+	}
+	if !lastRelease.IsZero() {
+		cond.Message += " " + lastReleaseMessage + lastRelease.UTC().Format(time.RFC3339) + "."
+	}
+	return cond
+}
@@ -0,0 +1,181 @@
+package whereabouts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestAllocatedIP(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(allocatedIP("192.168.2.0/24", "5")).To(Equal("192.168.2.5"))
+	g.Expect(allocatedIP("10.0.0.0/16", "300")).To(Equal("10.0.1.44"))
+	g.Expect(allocatedIP("fd00::/64", "258")).To(Equal("fd00::102"))
+	g.Expect(allocatedIP("invalid", "5")).To(Equal("5"))
+	g.Expect(allocatedIP("192.168.2.0/24", "x")).To(Equal("x"))
+}
+
+func TestRangeSize(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(rangeSize("192.168.2.0/24")).To(Equal(256.0))
+	g.Expect(rangeSize("fd00::/120")).To(Equal(256.0))
+	g.Expect(rangeSize("invalid")).To(Equal(0.0))
+}
+
+func TestReconciledCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cond := reconciledCondition([]poolUsage{{cidr: "192.168.2.0/24", allocated: 3}}, nil, reconcilerDS(2), time.Time{})
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Message).To(ContainSubstring("All 3 whereabouts allocations in 1 IP pools"))
+
+	cond = reconciledCondition(
+		[]poolUsage{{cidr: "192.168.2.0/24", allocated: 3, leaked: []leakedAllocation{{ip: "192.168.2.1", podRef: "ns/gone"}}}},
+		[]leakedAllocation{{ip: "192.168.2.1", podRef: "ns/gone"}}, reconcilerDS(2), time.Time{})
+	g.Expect(cond.Status).To(Equal(operv1.ConditionFalse))
+	g.Expect(cond.Reason).To(Equal("LeakedAllocations"))
+	g.Expect(cond.Message).To(ContainSubstring("2 whereabouts allocations"))
+	g.Expect(cond.Message).To(ContainSubstring("192.168.2.1 in 192.168.2.0/24 (ns/gone)"))
+	g.Expect(cond.Message).To(ContainSubstring("reservation 192.168.2.1 (ns/gone)"))
+
+	leaks := []leakedAllocation{}
+	for i := 0; i < maxLeaksInMessage+5; i++ {
+		leaks = append(leaks, leakedAllocation{ip: "192.168.2.1", podRef: "ns/gone"})
+	}
+	cond = reconciledCondition(nil, leaks, reconcilerDS(2), time.Time{})
+	g.Expect(cond.Message).To(HaveSuffix("and 5 more."))
+}
+
+func TestPodLookup(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := fake.NewFakeClient(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "running"}})
+	r := newReconciler(client)
+	podExists := r.podLookup(context.TODO())
+
+	for podRef, expected := range map[string]bool{
+		"ns/running":    true,
+		"ns/gone":       false,
+		"other/running": false,
+		"":              true,
+		"nopodref":      true,
+	} {
+		exists, err := podExists(podRef)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(exists).To(Equal(expected), podRef)
+	}
+}
+
+func reconcilerDS(available int32) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: names.MULTUS_NAMESPACE, Name: reconcilerDaemonSet},
+		Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, NumberAvailable: available},
+	}
+}
+
+func ippool(allocations map[string]interface{}) *uns.Unstructured {
+	pool := &uns.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"range":       "192.168.2.0/24",
+			"allocations": allocations,
+		},
+	}}
+	pool.SetAPIVersion("whereabouts.cni.cncf.io/v1alpha1")
+	pool.SetKind("IPPool")
+	pool.SetNamespace(names.MULTUS_NAMESPACE)
+	pool.SetName("192.168.2.0-24")
+	return pool
+}
+
+func TestReconciledConditionReconciler(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	pools := []poolUsage{{cidr: "192.168.2.0/24", allocated: 3}}
+	cond := reconciledCondition(pools, nil, reconcilerDS(1), time.Time{})
+	g.Expect(cond.Status).To(Equal(operv1.ConditionFalse))
+	g.Expect(cond.Reason).To(Equal("IPReconcilerUnavailable"))
+	g.Expect(cond.Message).To(HaveSuffix("The ip-reconciler is available on 1 of 2 nodes."))
+
+	lastRelease := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	cond = reconciledCondition(pools, nil, reconcilerDS(2), lastRelease)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Message).To(HaveSuffix(lastReleaseMessage + "2024-05-01T10:00:00Z."))
+}
+
+func TestReconcileObservesReleases(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	gone := map[string]interface{}{"1": map[string]interface{}{"podref": "ns/gone"}}
+	recreated := map[string]interface{}{"3": map[string]interface{}{"podref": "ns/recreated"}}
+	running := map[string]interface{}{"2": map[string]interface{}{"podref": "ns/running"}}
+	client := fake.NewFakeClient(
+		&operv1.Network{ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "running"}},
+		reconcilerDS(2),
+		ippool(map[string]interface{}{"1": gone["1"], "2": running["2"], "3": recreated["3"]}),
+	)
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: names.OPERATOR_CONFIG}}
+	condition := func() *operv1.OperatorCondition {
+		oc := &operv1.Network{}
+		g.Expect(client.Default().CRClient().Get(ctx, request.NamespacedName, oc)).To(Succeed())
+		return v1helpers.FindOperatorCondition(oc.Status.Conditions, names.WhereaboutsIPReconciled)
+	}
+	recorded := func() string {
+		ds := &appsv1.DaemonSet{}
+		g.Expect(client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.MULTUS_NAMESPACE, Name: reconcilerDaemonSet}, ds)).To(Succeed())
+		return ds.Annotations[names.IPReconcilerLastReleaseAnnotation]
+	}
+	updatePool := func(allocations map[string]interface{}) {
+		pool := ippool(allocations)
+		existing := ippool(nil)
+		g.Expect(client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.MULTUS_NAMESPACE, Name: pool.GetName()}, existing)).To(Succeed())
+		pool.SetResourceVersion(existing.GetResourceVersion())
+		g.Expect(client.Default().CRClient().Update(ctx, pool)).To(Succeed())
+	}
+
+	r := newReconciler(client)
+	_, err := r.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(condition().Reason).To(Equal("LeakedAllocations"))
+	g.Expect(condition().Message).NotTo(ContainSubstring(lastReleaseMessage))
+
+	// A pod recreated with the name of a leaked allocation does not release it
+	g.Expect(client.Default().CRClient().Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "recreated"}})).To(Succeed())
+	_, err = r.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(condition().Message).NotTo(ContainSubstring(lastReleaseMessage))
+	g.Expect(recorded()).To(BeEmpty())
+
+	// The ip-reconciler releases the leaked allocation
+	updatePool(map[string]interface{}{"2": running["2"], "3": recreated["3"]})
+	_, err = r.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(condition().Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(condition().Message).To(ContainSubstring(lastReleaseMessage))
+	lastRelease := recorded()
+	g.Expect(time.Parse(time.RFC3339, lastRelease)).NotTo(BeZero())
+
+	// The time of the release is kept across restarts of the operator
+	restarted := newReconciler(client)
+	_, err = restarted.Reconcile(ctx, request)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(condition().Message).To(HaveSuffix(lastReleaseMessage + lastRelease + "."))
+	g.Expect(recorded()).To(Equal(lastRelease))
+}
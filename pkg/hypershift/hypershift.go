@@ -49,6 +49,8 @@ type HostedControlPlane struct {
 	ClusterID                    string
 	ControllerAvailabilityPolicy AvailabilityPolicy
 	NodeSelector                 map[string]string
+	// MachineNetworks are the CIDRs of spec.networking.machineNetwork
+	MachineNetworks []string
 }
 
 // AvailabilityPolicy specifies a high level availability policy for components.
@@ -125,10 +127,26 @@ func ParseHostedControlPlane(hcp *unstructured.Unstructured) (*HostedControlPlan
 		return nil, fmt.Errorf("failed extract nodeSelector: %v", err)
 	}
 
+	machineNetworkEntries, _, err := unstructured.NestedSlice(hcp.UnstructuredContent(), "spec", "networking", "machineNetwork")
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract machineNetwork: %v", err)
+	}
+	var machineNetworks []string
+	for _, entry := range machineNetworkEntries {
+		entryMap, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("failed to extract machineNetwork: unexpected entry %v", entry)
+		}
+		if cidr, _, _ := unstructured.NestedString(entryMap, "cidr"); cidr != "" {
+			machineNetworks = append(machineNetworks, cidr)
+		}
+	}
+
 	return &HostedControlPlane{
 		ControllerAvailabilityPolicy: AvailabilityPolicy(controllerAvailabilityPolicy),
 		ClusterID:                    clusterID,
 		NodeSelector:                 nodeSelector,
+		MachineNetworks:              machineNetworks,
 	}, nil
 }
 
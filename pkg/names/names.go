@@ -48,6 +48,14 @@ const CreateOnlyAnnotation = "networkoperator.openshift.io/create-only"
 // tells the CNO reconciliation engine to ignore creating this object until conditions are met.
 const CreateWaitAnnotation = "networkoperator.openshift.io/create-wait"
 
+// WaitForAnnotation is an annotation on objects that tells the CNO reconciliation
+// engine to stop applying this object, and every object rendered after it, until
+// the referenced object is ready. The value has the form Kind/Namespace/Name, with
+// an empty Namespace for cluster-scoped objects. The referenced object is looked up
+// in the cluster of the annotated object, unless the value is prefixed with the
+// name of another cluster and a colon, as in management:Deployment/ns/name.
+const WaitForAnnotation = "networkoperator.openshift.io/wait-for"
+
 // NonCriticalAnnotation is an annotation on Deployments/DaemonSets to indicate
 // that they are not critical to the functioning of the pod network
 const NonCriticalAnnotation = "networkoperator.openshift.io/non-critical"
@@ -87,6 +95,10 @@ const IPsecEnableAnnotation = "networkoperator.openshift.io/ipsec-enabled"
 // (i.e. DaemonSet or Deployment) is not making progress, unset otherwise.
 const RolloutHungAnnotation = "networkoperator.openshift.io/rollout-hung"
 
+// IPReconcilerLastReleaseAnnotation is set on the whereabouts ip-reconciler DaemonSet to the
+// time, in RFC3339, at which it was last seen releasing leaked allocations.
+const IPReconcilerLastReleaseAnnotation = "networkoperator.openshift.io/ip-reconciler-last-release"
+
 // CopyFromAnnotation is an annotation that allows copying resources from specified clusters
 // value format: cluster/namespace/name
 const CopyFromAnnotation = "network.operator.openshift.io/copy-from"
@@ -119,6 +131,24 @@ const TRUSTED_CA_BUNDLE_CONFIGMAP = "trusted-ca-bundle"
 // ConfigMaps.
 const TRUSTED_CA_BUNDLE_CONFIGMAP_NS = "openshift-config-managed"
 
+// TRUSTED_CA_BUNDLE_INVENTORY_CONFIGMAP is the name of the ConfigMap in
+// TRUSTED_CA_BUNDLE_CONFIGMAP_NS that lists the certificates of the
+// TRUSTED_CA_BUNDLE_CONFIGMAP, where they come from, and where they are injected.
+const TRUSTED_CA_BUNDLE_INVENTORY_CONFIGMAP = "trusted-ca-bundle-inventory"
+
+// TRUSTED_CA_BUNDLE_INVENTORY_CONFIGMAP_KEY is the name of the data key
+// containing the JSON inventory.
+const TRUSTED_CA_BUNDLE_INVENTORY_CONFIGMAP_KEY = "inventory.json"
+
+// NO_PROXY_SOURCES_CONFIGMAP is the name of the ConfigMap in
+// TRUSTED_CA_BUNDLE_CONFIGMAP_NS that explains which source contributed each
+// entry of the noProxy list of the proxy status.
+const NO_PROXY_SOURCES_CONFIGMAP = "proxy-no-proxy-sources"
+
+// NO_PROXY_SOURCES_CONFIGMAP_KEY is the name of the data key containing the
+// JSON explanation.
+const NO_PROXY_SOURCES_CONFIGMAP_KEY = "sources.json"
+
 // TRUSTED_CA_BUNDLE_CONFIGMAP_LABEL is the name of the label that
 // determines whether or not to inject the combined ca certificate
 const TRUSTED_CA_BUNDLE_CONFIGMAP_LABEL = "config.openshift.io/inject-trusted-cabundle"
@@ -138,6 +168,42 @@ const ClusterNetworkOperatorJiraComponent = "Networking / cluster-network-operat
 // that executing network type live migration
 const NetworkTypeMigrationAnnotation = "network.openshift.io/live-migration"
 
+// NetworkTypeMigrationDryRunAnnotation is an annotation on the networks.operator.openshift.io CR to
+// request a dry run of the migration of the features selected by spec.migration.features. Its value
+// is the target network type. The result is written to the network-migration-dry-run ConfigMap.
+const NetworkTypeMigrationDryRunAnnotation = "network.openshift.io/migration-dry-run"
+
+// NetworkTypeMigrationAbortAnnotation is an annotation on the networks.config.openshift.io CR to abort
+// the network type live migration in progress. The operator rolls the cluster back to the network type
+// the migration started from, and removes the annotation.
+const NetworkTypeMigrationAbortAnnotation = "network.openshift.io/live-migration-abort"
+
+// EgressRouterProxyModeAnnotation is an annotation on an EgressRouter to run it as a proxy
+// instead of redirecting its traffic, one of HTTPProxy or DNSProxy. The proxy is configured
+// with the EgressRouterProxyAllowAnnotation and EgressRouterProxyDenyAnnotation lists.
+const EgressRouterProxyModeAnnotation = "network.operator.openshift.io/egress-router-proxy-mode"
+
+// EgressRouterProxyAllowAnnotation is an annotation on a proxy EgressRouter with the
+// destinations the proxy allows, one per line or separated by commas. The destinations
+// of an HTTPProxy router are domains, IPs or CIDRs, and the ones of a DNSProxy router
+// are "<port> <host> [<target port>]".
+const EgressRouterProxyAllowAnnotation = "network.operator.openshift.io/egress-router-proxy-allow"
+
+// EgressRouterProxyDenyAnnotation is an annotation on an HTTPProxy EgressRouter with the
+// domains, IPs or CIDRs the proxy denies, one per line or separated by commas.
+const EgressRouterProxyDenyAnnotation = "network.operator.openshift.io/egress-router-proxy-deny"
+
+// EgressRouterReplicasAnnotation is an annotation on an EgressRouter with the number of
+// replicas of the router. With more than one replica, the router runs in HA mode: the
+// replicas run on different nodes, and the one holding the leader lease of the router
+// owns the egress addresses and is labelled with EgressRouterLeaderLabel.
+const EgressRouterReplicasAnnotation = "network.operator.openshift.io/egress-router-replicas"
+
+// EgressRouterLeaderLabel is a label on the pod of an HA EgressRouter that owns the
+// egress addresses. The Services in front of an HA router select it, as the other
+// replicas do not send traffic.
+const EgressRouterLeaderLabel = "network.operator.openshift.io/egress-router-leader"
+
 // MachineConfigPoolsUpdating is the reason string NetworkTypeMigrationTargetCNIInUse and NetworkTypeMigrationMTUReady
 // conditions to indicate if MCP is updating
 const MachineConfigPoolsUpdating string = "MachineConfigPoolsUpdating"
@@ -159,8 +225,49 @@ const (
 	// NetworkTypeMigrationMTUReady is the condition type for network type live migration to indicate if the routable
 	// MTU is set
 	NetworkTypeMigrationMTUReady string = "NetworkTypeMigrationMTUReady"
+	// NetworkTypeMigrationHeld is the condition type for network type live migration to indicate that the migration
+	// holds at the last recorded step, as the other conditions point back to an earlier step
+	NetworkTypeMigrationHeld string = "NetworkTypeMigrationHeld"
 )
 
+// Status condition types of network.operator for the migration of openshift-sdn from the
+// Multitenant to the NetworkPolicy mode
+const (
+	// SDNModeMigrationInProgress is the condition type for the openshift-sdn mode migration to
+	// indicate if the migration is in progress
+	SDNModeMigrationInProgress string = "SDNModeMigrationInProgress"
+	// SDNModeMigrationPoliciesApplied is the condition type for the openshift-sdn mode migration to
+	// indicate if the NetworkPolicies generated from the NetNamespaces have been applied
+	SDNModeMigrationPoliciesApplied string = "SDNModeMigrationPoliciesApplied"
+	// SDNModeMigrationPoliciesVerified is the condition type for the openshift-sdn mode migration to
+	// indicate if the applied NetworkPolicies have been verified
+	SDNModeMigrationPoliciesVerified string = "SDNModeMigrationPoliciesVerified"
+	// SDNModeMigrationModeChanged is the condition type for the openshift-sdn mode migration to
+	// indicate if openshift-sdn runs in the NetworkPolicy mode
+	SDNModeMigrationModeChanged string = "SDNModeMigrationModeChanged"
+)
+
+// RawCNIConfigPluginsShipped is the condition type of network.operator to indicate whether every
+// plugin and IPAM type of the Raw additional networks is shipped with the cluster. It is only set
+// while some are not.
+const RawCNIConfigPluginsShipped string = "RawCNIConfigPluginsShipped"
+
+// WhereaboutsIPReconciled is the condition type of network.operator to indicate whether every
+// whereabouts IP allocation belongs to an existing pod, and the ip-reconciler is available. Its
+// message mentions the last time the ip-reconciler was seen releasing leaked allocations.
+const WhereaboutsIPReconciled string = "WhereaboutsIPReconciled"
+
+// MultiNetworkPolicyEnforced is the condition type of network.operator to indicate whether the
+// existing MultiNetworkPolicy objects are enforced: useMultiNetworkPolicy is enabled, and the
+// multi-networkpolicy rules are synced on every node. It is set before useMultiNetworkPolicy is
+// enabled or disabled, to list the namespaces whose MultiNetworkPolicy objects are affected.
+const MultiNetworkPolicyEnforced string = "MultiNetworkPolicyEnforced"
+
+// OVNKubernetesMigrationReady is the condition type of network.operator to indicate whether an
+// openshift-sdn cluster can be migrated to OVN-Kubernetes without losing configuration. The
+// findings behind it are listed in the sdn-to-ovn-migration-readiness ConfigMap.
+const OVNKubernetesMigrationReady string = "OVNKubernetesMigrationReady"
+
 // Proxy returns the namespaced name "cluster" in the
 // default namespace.
 func Proxy() types.NamespacedName {
@@ -198,6 +305,10 @@ const PolicyGroupLabelIngressValue = ""
 // value for legacy policy group label
 const PolicyGroupLabelLegacyValue = "ingress"
 
+// annotation on the host network namespace recording which ingress controllers
+// require each of its policy group labels
+const PolicyGroupSourcesAnnotation = "networkoperator.openshift.io/policy-group-sources"
+
 // default ingress controller name
 const DefaultIngressControllerName = "default"
 
@@ -0,0 +1,348 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/render"
+	"github.com/pkg/errors"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Additional network types with a typed configuration that are rendered by the
+// operator, on top of the Raw and SimpleMacvlan types of operator.openshift.io.
+// Since AdditionalNetworkDefinition has no dedicated field for them, their typed
+// configuration is given as JSON in rawCNIConfig, and is decoded strictly so
+// that misspelled or unknown fields are reported by Validate.
+const (
+	NetworkTypeIPVLAN     operv1.NetworkType = "IPVLAN"
+	NetworkTypeHostDevice operv1.NetworkType = "HostDevice"
+	NetworkTypeBridge     operv1.NetworkType = "Bridge"
+	NetworkTypeBond       operv1.NetworkType = "Bond"
+	NetworkTypeVLAN       operv1.NetworkType = "VLAN"
+)
+
+// ipvlanMode is the mode of an ipvlan interface
+type ipvlanMode string
+
+const (
+	ipvlanModeL2  ipvlanMode = "L2"
+	ipvlanModeL3  ipvlanMode = "L3"
+	ipvlanModeL3S ipvlanMode = "L3S"
+)
+
+// bondModes are the bonding modes supported by the bond CNI plugin
+var bondModes = []string{
+	"balance-rr",
+	"active-backup",
+	"balance-xor",
+	"broadcast",
+	"802.3ad",
+	"balance-tlb",
+	"balance-alb",
+}
+
+// ipvlanConfig configures an ipvlan interface in case of type:IPVLAN
+type ipvlanConfig struct {
+	// master is the host interface to create the ipvlan interface from.
+	// If this is not specified, the default route interface will be used.
+	Master string `json:"master,omitempty"`
+
+	// mode is the ipvlan mode: L2, L3 or L3S. Defaults to L2.
+	Mode ipvlanMode `json:"mode,omitempty"`
+
+	// mtu is the mtu to use for the ipvlan interface. If unset, the
+	// mtu of the master interface is used.
+	MTU uint32 `json:"mtu,omitempty"`
+
+	// ipamConfig configures the IPAM module. Defaults to DHCP.
+	IPAMConfig *operv1.IPAMConfig `json:"ipamConfig,omitempty"`
+}
+
+// hostDeviceConfig moves a host device into the pod in case of type:HostDevice.
+// Exactly one of device, hwaddr, kernelpath or pciBusID must be given.
+type hostDeviceConfig struct {
+	// device is the name of the host interface
+	Device string `json:"device,omitempty"`
+
+	// hwaddr is the MAC address of the host interface
+	HWAddr string `json:"hwaddr,omitempty"`
+
+	// kernelpath is the kernel device kobj path of the host interface
+	KernelPath string `json:"kernelpath,omitempty"`
+
+	// pciBusID is the PCI address of the host interface, e.g. 0000:00:1f.6
+	PCIBusID string `json:"pciBusID,omitempty"`
+
+	// ipamConfig configures the IPAM module. Defaults to DHCP.
+	IPAMConfig *operv1.IPAMConfig `json:"ipamConfig,omitempty"`
+}
+
+// bridgeConfig attaches the pod to a linux bridge in case of type:Bridge
+type bridgeConfig struct {
+	// bridge is the name of the bridge to use. Defaults to cni0.
+	Bridge string `json:"bridge,omitempty"`
+
+	// isGateway assigns an IP address to the bridge and uses it as gateway
+	IsGateway bool `json:"isGateway,omitempty"`
+
+	// ipMasq sets up IP masquerading for traffic leaving the pod
+	IPMasq bool `json:"ipMasq,omitempty"`
+
+	// hairpinMode lets a pod reach itself through the bridge
+	HairpinMode bool `json:"hairpinMode,omitempty"`
+
+	// vlan is the VLAN tag to assign to the pod port, 0 means untagged
+	VLAN uint32 `json:"vlan,omitempty"`
+
+	// mtu is the mtu to use for the bridge and veth interfaces
+	MTU uint32 `json:"mtu,omitempty"`
+
+	// ipamConfig configures the IPAM module. Defaults to DHCP.
+	IPAMConfig *operv1.IPAMConfig `json:"ipamConfig,omitempty"`
+}
+
+// bondConfig bonds interfaces that are attached to the pod in case of type:Bond
+type bondConfig struct {
+	// mode is the bonding mode, e.g. active-backup or 802.3ad
+	Mode string `json:"mode"`
+
+	// links are the names of the pod interfaces to enslave
+	Links []string `json:"links"`
+
+	// miimon is the link monitoring frequency in milliseconds. Defaults to 100.
+	MIIMon uint32 `json:"miimon,omitempty"`
+
+	// failOverMac is the fail_over_mac policy of the bond, from 0 to 2
+	FailOverMac uint32 `json:"failOverMac,omitempty"`
+
+	// mtu is the mtu to use for the bond interface
+	MTU uint32 `json:"mtu,omitempty"`
+
+	// ipamConfig configures the IPAM module. Defaults to DHCP.
+	IPAMConfig *operv1.IPAMConfig `json:"ipamConfig,omitempty"`
+}
+
+// vlanConfig configures a VLAN sub-interface in case of type:VLAN
+type vlanConfig struct {
+	// master is the host interface to create the VLAN interface from
+	Master string `json:"master"`
+
+	// vlanId is the VLAN tag, from 1 to 4094
+	VLANID uint32 `json:"vlanId"`
+
+	// mtu is the mtu to use for the VLAN interface
+	MTU uint32 `json:"mtu,omitempty"`
+
+	// ipamConfig configures the IPAM module. Defaults to DHCP.
+	IPAMConfig *operv1.IPAMConfig `json:"ipamConfig,omitempty"`
+}
+
+// decodeTypedConfig decodes the typed configuration of a typed additional network
+// from its rawCNIConfig. An empty rawCNIConfig leaves out unchanged.
+func decodeTypedConfig(conf *operv1.AdditionalNetworkDefinition, out interface{}) error {
+	if strings.TrimSpace(conf.RawCNIConfig) == "" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(conf.RawCNIConfig)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(out); err != nil {
+		return errors.Errorf("invalid %s configuration of additional network %s: %v", conf.Type, conf.Name, err)
+	}
+	return nil
+}
+
+// typedIPAMConfig returns the IPAMConfig of a typed configuration, if any
+func typedIPAMConfig(conf interface{}) *operv1.IPAMConfig {
+	switch c := conf.(type) {
+	case *ipvlanConfig:
+		return c.IPAMConfig
+	case *hostDeviceConfig:
+		return c.IPAMConfig
+	case *bridgeConfig:
+		return c.IPAMConfig
+	case *bondConfig:
+		return c.IPAMConfig
+	case *vlanConfig:
+		return c.IPAMConfig
+	}
+	return nil
+}
+
+// newTypedConfig returns an empty typed configuration for the given network type,
+// or nil if the type has no typed configuration
+func newTypedConfig(t operv1.NetworkType) interface{} {
+	switch t {
+	case NetworkTypeIPVLAN:
+		return &ipvlanConfig{}
+	case NetworkTypeHostDevice:
+		return &hostDeviceConfig{}
+	case NetworkTypeBridge:
+		return &bridgeConfig{}
+	case NetworkTypeBond:
+		return &bondConfig{}
+	case NetworkTypeVLAN:
+		return &vlanConfig{}
+	}
+	return nil
+}
+
+// validateTypedConfig checks the name and typed configuration of a typed
+// additional network.
+func validateTypedConfig(conf *operv1.AdditionalNetworkDefinition) []error {
+	out := []error{}
+
+	if conf.Name == "" {
+		out = append(out, errors.Errorf("Additional Network Name cannot be nil"))
+	}
+
+	typed := newTypedConfig(conf.Type)
+	if err := decodeTypedConfig(conf, typed); err != nil {
+		return append(out, err)
+	}
+
+	if ipam := typedIPAMConfig(typed); ipam != nil {
+		out = append(out, validateIPAMConfig(ipam)...)
+	}
+
+	switch c := typed.(type) {
+	case *ipvlanConfig:
+		switch c.Mode {
+		case "", ipvlanModeL2, ipvlanModeL3, ipvlanModeL3S:
+		default:
+			out = append(out, errors.Errorf("invalid IPVLAN mode: %s", c.Mode))
+		}
+	case *hostDeviceConfig:
+		set := 0
+		for _, s := range []string{c.Device, c.HWAddr, c.KernelPath, c.PCIBusID} {
+			if s != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			out = append(out, errors.Errorf("exactly one of device, hwaddr, kernelpath or pciBusID must be specified for HostDevice network %s", conf.Name))
+		}
+		if c.HWAddr != "" {
+			if _, err := net.ParseMAC(c.HWAddr); err != nil {
+				out = append(out, errors.Errorf("invalid hwaddr: %s", c.HWAddr))
+			}
+		}
+	case *bridgeConfig:
+		if c.VLAN > 4094 {
+			out = append(out, errors.Errorf("invalid bridge vlan: %d", c.VLAN))
+		}
+	case *bondConfig:
+		valid := false
+		for _, m := range bondModes {
+			if c.Mode == m {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			out = append(out, errors.Errorf("invalid Bond mode: %q, must be one of %s", c.Mode, strings.Join(bondModes, ", ")))
+		}
+		if len(c.Links) == 0 {
+			out = append(out, errors.Errorf("Bond network %s must have at least one link", conf.Name))
+		}
+		if c.FailOverMac > 2 {
+			out = append(out, errors.Errorf("invalid Bond failOverMac: %d", c.FailOverMac))
+		}
+	case *vlanConfig:
+		if c.Master == "" {
+			out = append(out, errors.Errorf("VLAN network %s must specify a master interface", conf.Name))
+		}
+		if c.VLANID < 1 || c.VLANID > 4094 {
+			out = append(out, errors.Errorf("invalid vlanId: %d", c.VLANID))
+		}
+	}
+
+	return out
+}
+
+// renderTypedConfig returns the manifests of a typed additional network
+func renderTypedConfig(conf *operv1.AdditionalNetworkDefinition, manifestDir string) ([]*uns.Unstructured, error) {
+	var err error
+
+	typed := newTypedConfig(conf.Type)
+	if typed == nil {
+		return nil, errors.Errorf("unknown or unsupported NetworkType: %s", conf.Type)
+	}
+	if err = decodeTypedConfig(conf, typed); err != nil {
+		return nil, err
+	}
+
+	data := render.MakeRenderData()
+	data.Data["AdditionalNetworkName"] = conf.Name
+	data.Data["AdditionalNetworkNamespace"] = conf.Namespace
+
+	data.Data["IPAMConfig"], err = getIPAMConfigJSON(typedIPAMConfig(typed))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render ipam config")
+	}
+
+	var dir string
+	switch c := typed.(type) {
+	case *ipvlanConfig:
+		dir = "ipvlan"
+		data.Data["Master"] = c.Master
+		if c.Mode != "" {
+			// ipvlan CNI only accepts mode in lowercase
+			data.Data["Mode"] = strings.ToLower(string(c.Mode))
+		}
+		data.Data["MTU"] = c.MTU
+	case *hostDeviceConfig:
+		dir = "hostdevice"
+		data.Data["Device"] = c.Device
+		data.Data["HWAddr"] = c.HWAddr
+		data.Data["KernelPath"] = c.KernelPath
+		data.Data["PCIBusID"] = c.PCIBusID
+	case *bridgeConfig:
+		dir = "bridge"
+		data.Data["Bridge"] = c.Bridge
+		data.Data["IsGateway"] = c.IsGateway
+		data.Data["IPMasq"] = c.IPMasq
+		data.Data["HairpinMode"] = c.HairpinMode
+		data.Data["VLAN"] = c.VLAN
+		data.Data["MTU"] = c.MTU
+	case *bondConfig:
+		dir = "bond"
+		data.Data["Mode"] = c.Mode
+		data.Data["Links"] = c.Links
+		data.Data["MIIMon"] = c.MIIMon
+		if c.MIIMon == 0 {
+			data.Data["MIIMon"] = 100
+		}
+		data.Data["FailOverMac"] = c.FailOverMac
+		data.Data["MTU"] = c.MTU
+	case *vlanConfig:
+		dir = "vlan"
+		data.Data["Master"] = c.Master
+		data.Data["VLANID"] = c.VLANID
+		data.Data["MTU"] = c.MTU
+	}
+
+	objs, err := render.RenderDir(filepath.Join(manifestDir, "network/additional-networks", dir), &data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render %s additional network", dir)
+	}
+	return objs, nil
+}
+
+// useDHCPTyped determines whether a typed additional network uses DHCP
+func useDHCPTyped(conf *operv1.AdditionalNetworkDefinition) bool {
+	typed := newTypedConfig(conf.Type)
+	if typed == nil {
+		return false
+	}
+	if err := decodeTypedConfig(conf, typed); err != nil {
+		return false
+	}
+	ipam := typedIPAMConfig(typed)
+	// no IPAMConfig means DHCP, as for SimpleMacvlan
+	return ipam == nil || ipam.Type == operv1.IPAMTypeDHCP
+}
@@ -0,0 +1,117 @@
+package network
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// netConfFromObj returns the CNI config of a rendered NetworkAttachmentDefinition
+func netConfFromObj(g *WithT, obj *uns.Unstructured) map[string]interface{} {
+	config, found, err := uns.NestedString(obj.Object, "spec", "config")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	netConf := map[string]interface{}{}
+	g.Expect(json.Unmarshal([]byte(config), &netConf)).To(Succeed())
+	return netConf
+}
+
+func TestRenderTypedConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		an       operv1.AdditionalNetworkDefinition
+		expected string
+	}{
+		{
+			an: operv1.AdditionalNetworkDefinition{Type: NetworkTypeIPVLAN, Name: "ipvlan", Namespace: "foobar",
+				RawCNIConfig: `{"master": "eth1", "mode": "L3S", "mtu": 1400}`},
+			expected: `{"cniVersion": "0.3.1", "type": "ipvlan", "master": "eth1", "mode": "l3s", "mtu": 1400, "ipam": {"type": "dhcp"}}`,
+		},
+		{
+			an: operv1.AdditionalNetworkDefinition{Type: NetworkTypeHostDevice, Name: "hostdevice",
+				RawCNIConfig: `{"pciBusID": "0000:00:1f.6", "ipamConfig": {"type": "Static"}}`},
+			expected: `{"cniVersion": "0.3.1", "type": "host-device", "pciBusID": "0000:00:1f.6", "ipam": {"type": "static", "capabilities": ["ips"]}}`,
+		},
+		{
+			an: operv1.AdditionalNetworkDefinition{Type: NetworkTypeBridge, Name: "bridge",
+				RawCNIConfig: `{"bridge": "br1", "isGateway": true, "vlan": 100}`},
+			expected: `{"cniVersion": "0.3.1", "type": "bridge", "bridge": "br1", "isGateway": true, "vlan": 100, "ipam": {"type": "dhcp"}}`,
+		},
+		{
+			an: operv1.AdditionalNetworkDefinition{Type: NetworkTypeBond, Name: "bond",
+				RawCNIConfig: `{"mode": "active-backup", "links": ["net1", "net2"], "failOverMac": 1}`},
+			expected: `{"cniVersion": "0.3.1", "type": "bond", "mode": "active-backup", "linksInContainer": true, "miimon": "100", "failOverMac": 1,
+				"links": [{"name": "net1"}, {"name": "net2"}], "ipam": {"type": "dhcp"}}`,
+		},
+		{
+			an: operv1.AdditionalNetworkDefinition{Type: NetworkTypeVLAN, Name: "vlan",
+				RawCNIConfig: `{"master": "eth0", "vlanId": 42}`},
+			expected: `{"cniVersion": "0.3.1", "type": "vlan", "master": "eth0", "vlanId": 42, "ipam": {"type": "dhcp"}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		g.Expect(validateTypedConfig(&tc.an)).To(BeEmpty())
+		objs, err := renderTypedConfig(&tc.an, manifestDir)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(objs).To(HaveLen(1))
+
+		expectedNamespace := tc.an.Namespace
+		if expectedNamespace == "" {
+			expectedNamespace = "default"
+		}
+		g.Expect(objs).To(ContainElement(HaveKubernetesID("NetworkAttachmentDefinition", expectedNamespace, tc.an.Name)))
+
+		netConf, err := json.Marshal(netConfFromObj(g, objs[0]))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(netConf).To(MatchJSON(tc.expected))
+	}
+}
+
+func TestValidateTypedConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	errExpect := func(an operv1.AdditionalNetworkDefinition, substr string) {
+		t.Helper()
+		g.Expect(validateTypedConfig(&an)).To(
+			ContainElement(MatchError(
+				ContainSubstring(substr))))
+	}
+
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeIPVLAN, RawCNIConfig: `{}`},
+		"Additional Network Name cannot be nil")
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeIPVLAN, Name: "a", RawCNIConfig: `{"mastr": "eth0"}`},
+		`unknown field "mastr"`)
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeIPVLAN, Name: "a", RawCNIConfig: `{"mode": "L4"}`},
+		"invalid IPVLAN mode: L4")
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeIPVLAN, Name: "a", RawCNIConfig: `{"ipamConfig": {"type": "foo"}}`},
+		"invalid IPAM type: foo")
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeHostDevice, Name: "a", RawCNIConfig: `{"device": "eth1", "hwaddr": "00:11:22:33:44:55"}`},
+		"exactly one of device, hwaddr, kernelpath or pciBusID")
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeHostDevice, Name: "a", RawCNIConfig: `{"hwaddr": "zz"}`},
+		"invalid hwaddr: zz")
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeBridge, Name: "a", RawCNIConfig: `{"vlan": 5000}`},
+		"invalid bridge vlan: 5000")
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeBond, Name: "a", RawCNIConfig: `{"mode": "active-passive", "links": []}`},
+		`invalid Bond mode: "active-passive"`)
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeBond, Name: "a", RawCNIConfig: `{"mode": "802.3ad", "links": []}`},
+		"must have at least one link")
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeVLAN, Name: "a", RawCNIConfig: `{"vlanId": 10}`},
+		"must specify a master interface")
+	errExpect(operv1.AdditionalNetworkDefinition{Type: NetworkTypeVLAN, Name: "a", RawCNIConfig: `{"master": "eth0", "vlanId": 0}`},
+		"invalid vlanId: 0")
+}
+
+func TestUseDHCPTyped(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(useDHCPTyped(&operv1.AdditionalNetworkDefinition{Type: NetworkTypeIPVLAN})).To(BeTrue())
+	g.Expect(useDHCPTyped(&operv1.AdditionalNetworkDefinition{Type: NetworkTypeVLAN,
+		RawCNIConfig: `{"master": "eth0", "vlanId": 42, "ipamConfig": {"type": "DHCP"}}`})).To(BeTrue())
+	g.Expect(useDHCPTyped(&operv1.AdditionalNetworkDefinition{Type: NetworkTypeVLAN,
+		RawCNIConfig: `{"master": "eth0", "vlanId": 42, "ipamConfig": {"type": "Static"}}`})).To(BeFalse())
+}
@@ -2,6 +2,7 @@ package network
 
 import (
 	"encoding/json"
+	"fmt"
 	"net"
 	"path/filepath"
 	"strings"
@@ -10,8 +11,10 @@ import (
 
 	operv1 "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-network-operator/pkg/render"
+	iputil "github.com/openshift/cluster-network-operator/pkg/util/ip"
 	"github.com/pkg/errors"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 // renderAdditionalNetworksCRD returns the manifests of the NetworkAttachmentDefinition.
@@ -62,6 +65,224 @@ func validateRaw(conf *operv1.AdditionalNetworkDefinition) []error {
 	return out
 }
 
+// supportedCNIVersions are the CNI spec versions understood by multus and the
+// shipped CNI plugins
+var supportedCNIVersions = sets.New[string]("0.1.0", "0.2.0", "0.3.0", "0.3.1", "0.4.0", "1.0.0")
+
+// shippedCNIPlugins are the CNI plugin types that are installed on every node,
+// either by multus-additional-cni-plugins or by the default network and the
+// operators that OpenShift ships for secondary networks.
+var shippedCNIPlugins = sets.New[string](
+	// containernetworking/plugins, from CNIPluginsImage
+	"bandwidth", "bridge", "dummy", "firewall", "host-device", "ipvlan",
+	"loopback", "macvlan", "portmap", "ptp", "sbr", "tap", "tuning", "vlan", "vrf",
+	// from EgressRouterImage, BondCNIPluginImage and RouteOverrideImage
+	"egress-router", "bond", "route-override",
+	// from the default network and the SR-IOV network operator
+	"ovn-k8s-cni-overlay", "openshift-sdn", "sriov", "ib-sriov",
+)
+
+// shippedIPAMPlugins are the IPAM plugin types that are installed on every node,
+// from CNIPluginsImage and WhereaboutsImage
+var shippedIPAMPlugins = sets.New[string]("dhcp", "host-local", "static", ipamTypeWhereabouts)
+
+// rawCNIPlugin is a single plugin configuration of a RawCNIConfig, either the
+// whole conf or one element of a conflist
+type rawCNIPlugin struct {
+	Type       string                 `json:"type"`
+	CNIVersion string                 `json:"cniVersion,omitempty"`
+	IPAM       map[string]interface{} `json:"ipam,omitempty"`
+}
+
+// rawCNIConfig covers both the CNI conf and conflist formats. Plugins is only
+// set in the conflist format.
+type rawCNIConfig struct {
+	rawCNIPlugin
+	Plugins []rawCNIPlugin `json:"plugins,omitempty"`
+}
+
+// validateRawCNIConfig parses the RawCNIConfig of a Raw additional network in
+// either the CNI conf or conflist format, and checks the CNI version, the
+// structure of the plugin list, and that whereabouts ranges are well formed and
+// do not overlap the cluster and service networks. Plugin types that are not
+// shipped with the cluster are not errors, as other operators install plugins
+// of their own; UnknownRawCNIPlugins reports them.
+func validateRawCNIConfig(an *operv1.AdditionalNetworkDefinition, conf *operv1.NetworkSpec) []error {
+	out := []error{}
+
+	rawConfig := rawCNIConfig{}
+	if err := json.Unmarshal([]byte(an.RawCNIConfig), &rawConfig); err != nil {
+		// already reported by validateRaw
+		return out
+	}
+
+	if rawConfig.CNIVersion == "" {
+		out = append(out, errors.Errorf("RawCNIConfig of additional network %s has no cniVersion", an.Name))
+	} else if !supportedCNIVersions.Has(rawConfig.CNIVersion) {
+		out = append(out, errors.Errorf("RawCNIConfig of additional network %s has unsupported cniVersion %q, must be one of %s",
+			an.Name, rawConfig.CNIVersion, strings.Join(sets.List(supportedCNIVersions), ", ")))
+	}
+
+	if rawConfig.Plugins != nil {
+		if rawConfig.Type != "" {
+			out = append(out, errors.Errorf("RawCNIConfig of additional network %s cannot have both type and plugins", an.Name))
+		}
+		if len(rawConfig.Plugins) == 0 {
+			out = append(out, errors.Errorf("RawCNIConfig of additional network %s has an empty plugins list", an.Name))
+		}
+		if rawConfig.CNIVersion == "0.1.0" || rawConfig.CNIVersion == "0.2.0" {
+			out = append(out, errors.Errorf("RawCNIConfig of additional network %s is a conflist, which requires cniVersion 0.3.0 or later", an.Name))
+		}
+	}
+
+	for i, plugin := range rawConfig.plugins() {
+		if plugin.Type == "" {
+			out = append(out, errors.Errorf("RawCNIConfig of additional network %s: plugin %d has no type", an.Name, i))
+		}
+		if plugin.CNIVersion != "" && plugin.CNIVersion != rawConfig.CNIVersion {
+			out = append(out, errors.Errorf("RawCNIConfig of additional network %s: plugin %d has cniVersion %q, which differs from the network's %q",
+				an.Name, i, plugin.CNIVersion, rawConfig.CNIVersion))
+		}
+		if plugin.IPAM != nil && plugin.IPAM["type"] == ipamTypeWhereabouts {
+			for _, err := range validateWhereaboutsIPAM(plugin.IPAM, conf) {
+				out = append(out, errors.Errorf("RawCNIConfig of additional network %s: %v", an.Name, err))
+			}
+		}
+	}
+
+	return out
+}
+
+// plugins returns the plugins of the conflist, or the conf itself
+func (c *rawCNIConfig) plugins() []rawCNIPlugin {
+	if c.Plugins != nil {
+		return c.Plugins
+	}
+	return []rawCNIPlugin{c.rawCNIPlugin}
+}
+
+// UnknownRawCNIPlugins lists the plugin and IPAM types of the Raw additional
+// networks that are not shipped with the cluster. They are usually typos, but
+// may be third-party plugins installed on the nodes by other operators, so
+// they are reported in the status rather than rejected.
+func UnknownRawCNIPlugins(conf *operv1.NetworkSpec) []string {
+	out := []string{}
+	for _, an := range conf.AdditionalNetworks {
+		if an.Type != operv1.NetworkTypeRaw {
+			continue
+		}
+		rawConfig := rawCNIConfig{}
+		if err := json.Unmarshal([]byte(an.RawCNIConfig), &rawConfig); err != nil {
+			continue
+		}
+		for _, plugin := range rawConfig.plugins() {
+			if plugin.Type != "" && !shippedCNIPlugins.Has(plugin.Type) {
+				out = append(out, fmt.Sprintf("plugin type %q of additional network %s", plugin.Type, an.Name))
+			}
+			if ipamType, ok := plugin.IPAM["type"].(string); ok && !shippedIPAMPlugins.Has(ipamType) {
+				out = append(out, fmt.Sprintf("IPAM type %q of additional network %s", ipamType, an.Name))
+			}
+		}
+	}
+	return out
+}
+
+// validateWhereaboutsIPAM checks the ranges and exclusions of a whereabouts IPAM
+// configuration, given either as range/exclude or as a list of ipRanges.
+func validateWhereaboutsIPAM(ipam map[string]interface{}, conf *operv1.NetworkSpec) []error {
+	out := []error{}
+
+	type ipRange struct {
+		Range      string   `json:"range"`
+		RangeStart string   `json:"range_start,omitempty"`
+		RangeEnd   string   `json:"range_end,omitempty"`
+		Exclude    []string `json:"exclude,omitempty"`
+	}
+	whereabouts := struct {
+		ipRange
+		IPRanges []ipRange `json:"ipRanges,omitempty"`
+	}{}
+	ipamBytes, err := json.Marshal(ipam)
+	if err == nil {
+		err = json.Unmarshal(ipamBytes, &whereabouts)
+	}
+	if err != nil {
+		return append(out, errors.Errorf("invalid whereabouts IPAM configuration: %v", err))
+	}
+
+	ranges := whereabouts.IPRanges
+	if whereabouts.Range != "" {
+		ranges = append(ranges, whereabouts.ipRange)
+	}
+	if len(ranges) == 0 {
+		return append(out, errors.Errorf("whereabouts IPAM configuration has no range"))
+	}
+
+	reserved := []string{}
+	for _, cn := range conf.ClusterNetwork {
+		reserved = append(reserved, cn.CIDR)
+	}
+	reserved = append(reserved, conf.ServiceNetwork...)
+
+	for _, r := range ranges {
+		rangeNet, err := parseWhereaboutsRange(r.Range)
+		if err != nil {
+			out = append(out, errors.Errorf("invalid whereabouts range %q: %v", r.Range, err))
+			continue
+		}
+
+		for _, cidr := range reserved {
+			_, reservedNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				// reported by validateIPPools
+				continue
+			}
+			if iputil.NetsOverlap(*rangeNet, *reservedNet) {
+				out = append(out, errors.Errorf("whereabouts range %s overlaps with cluster or service network %s", r.Range, cidr))
+			}
+		}
+
+		for _, bound := range []string{r.RangeStart, r.RangeEnd} {
+			if bound == "" {
+				continue
+			}
+			if ip := net.ParseIP(bound); ip == nil || !rangeNet.Contains(ip) {
+				out = append(out, errors.Errorf("whereabouts range bound %s is not an IP address within range %s", bound, r.Range))
+			}
+		}
+
+		for _, exclude := range r.Exclude {
+			_, excludeNet, err := net.ParseCIDR(exclude)
+			if err != nil {
+				out = append(out, errors.Errorf("invalid whereabouts exclusion %q: %v", exclude, err))
+				continue
+			}
+			if !iputil.NetsOverlap(*rangeNet, *excludeNet) {
+				out = append(out, errors.Errorf("whereabouts exclusion %s is outside of range %s", exclude, r.Range))
+			}
+		}
+	}
+
+	return out
+}
+
+// parseWhereaboutsRange parses a whereabouts range, either a CIDR or the short
+// form <start>-<end>/<prefix>, and returns the subnet it belongs to.
+func parseWhereaboutsRange(r string) (*net.IPNet, error) {
+	cidr := r
+	if start, end, found := strings.Cut(r, "-"); found {
+		cidr = end
+		if net.ParseIP(start) == nil {
+			return nil, errors.Errorf("invalid range start %s", start)
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	return ipNet, nil
+}
+
 // staticIPAMConfig for json generation for static IPAM
 type staticIPAMConfig struct {
 	Type         string              `json:"type"`
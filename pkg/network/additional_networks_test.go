@@ -121,6 +121,89 @@ func TestValidateRaw(t *testing.T) {
 	errExpect("Additional Network Name cannot be nil")
 }
 
+func TestValidateRawCNIConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	conf := &operv1.NetworkSpec{
+		ClusterNetwork: []operv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/14", HostPrefix: 23}},
+		ServiceNetwork: []string{"172.30.0.0/16"},
+	}
+	validate := func(rawCNIConfig string) []error {
+		t.Helper()
+		return validateRawCNIConfig(&operv1.AdditionalNetworkDefinition{
+			Type: operv1.NetworkTypeRaw, Name: "net-attach-1", RawCNIConfig: rawCNIConfig}, conf)
+	}
+	errExpect := func(rawCNIConfig, substr string) {
+		t.Helper()
+		g.Expect(validate(rawCNIConfig)).To(
+			ContainElement(MatchError(
+				ContainSubstring(substr))))
+	}
+
+	// valid conf and conflist
+	g.Expect(validate(`{"cniVersion": "0.3.1", "type": "macvlan", "master": "eth0", "ipam": {"type": "dhcp"}}`)).To(BeEmpty())
+	g.Expect(validate(`{"cniVersion": "0.4.0", "name": "chain", "plugins": [
+		{"type": "bridge", "ipam": {"type": "whereabouts", "range": "192.0.2.0/24", "exclude": ["192.0.2.0/28"]}},
+		{"type": "tuning"}]}`)).To(BeEmpty())
+	g.Expect(validate(`{"cniVersion": "1.0.0", "type": "ipvlan", "ipam": {"type": "whereabouts",
+		"ipRanges": [{"range": "192.0.2.10-192.0.2.20/24"}, {"range": "fd00::/64", "exclude": ["fd00::/120"]}]}}`)).To(BeEmpty())
+
+	// cniVersion
+	errExpect(`{"type": "macvlan"}`, "has no cniVersion")
+	errExpect(`{"cniVersion": "0.3.2", "type": "macvlan"}`, `unsupported cniVersion "0.3.2"`)
+	errExpect(`{"cniVersion": "0.2.0", "plugins": [{"type": "macvlan"}]}`, "requires cniVersion 0.3.0 or later")
+	errExpect(`{"cniVersion": "0.4.0", "plugins": [{"type": "macvlan", "cniVersion": "0.3.1"}]}`, "which differs from the network's")
+
+	// plugin types
+	errExpect(`{"cniVersion": "0.3.1"}`, "plugin 0 has no type")
+	errExpect(`{"cniVersion": "0.4.0", "plugins": []}`, "has an empty plugins list")
+	errExpect(`{"cniVersion": "0.4.0", "type": "bridge", "plugins": [{"type": "bridge"}]}`, "cannot have both type and plugins")
+
+	// whereabouts
+	errExpect(`{"cniVersion": "0.3.1", "type": "macvlan", "ipam": {"type": "whereabouts"}}`, "has no range")
+	errExpect(`{"cniVersion": "0.3.1", "type": "macvlan", "ipam": {"type": "whereabouts", "range": "192.0.2.0/33"}}`, "invalid whereabouts range")
+	errExpect(`{"cniVersion": "0.3.1", "type": "macvlan", "ipam": {"type": "whereabouts", "range": "10.130.0.0/24"}}`,
+		"overlaps with cluster or service network 10.128.0.0/14")
+	errExpect(`{"cniVersion": "0.3.1", "type": "macvlan", "ipam": {"type": "whereabouts", "range": "172.16.0.0/12"}}`,
+		"overlaps with cluster or service network 172.30.0.0/16")
+	errExpect(`{"cniVersion": "0.3.1", "type": "macvlan", "ipam": {"type": "whereabouts", "range": "192.0.2.0/24", "exclude": ["192.0.3.0/28"]}}`,
+		"whereabouts exclusion 192.0.3.0/28 is outside of range")
+	errExpect(`{"cniVersion": "0.3.1", "type": "macvlan", "ipam": {"type": "whereabouts", "range": "192.0.2.0/24", "exclude": ["foo"]}}`,
+		`invalid whereabouts exclusion "foo"`)
+	errExpect(`{"cniVersion": "0.3.1", "type": "macvlan", "ipam": {"type": "whereabouts", "range": "192.0.2.0/24", "range_start": "192.0.3.1"}}`,
+		"is not an IP address within range")
+
+	// the findings fail the validation, unlike the plugin types that are not shipped
+	g.Expect(validate(`{"cniVersion": "0.3.1", "type": "cnv-bridge", "ipam": {"type": "kube-ovn"}}`)).To(BeEmpty())
+	conf.AdditionalNetworks = []operv1.AdditionalNetworkDefinition{
+		{Type: operv1.NetworkTypeRaw, Name: "net-attach-1", RawCNIConfig: `{"type": "macvlan", "ipam": {"type": "whereabouts", "range": "10.130.0.0/24"}}`},
+	}
+	g.Expect(validateAdditionalNetworks(conf)).To(HaveLen(2))
+	g.Expect(Validate(conf)).To(MatchError(ContainSubstring("has no cniVersion")))
+}
+
+func TestUnknownRawCNIPlugins(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	conf := &operv1.NetworkSpec{
+		AdditionalNetworks: []operv1.AdditionalNetworkDefinition{
+			{Type: operv1.NetworkTypeRaw, Name: "shipped", RawCNIConfig: `{"cniVersion": "0.4.0", "plugins": [
+				{"type": "macvlan", "ipam": {"type": "dhcp"}}, {"type": "tuning"}]}`},
+			{Type: operv1.NetworkTypeRaw, Name: "typo", RawCNIConfig: `{"cniVersion": "0.3.1", "type": "macvlann", "ipam": {"type": "static"}}`},
+			{Type: operv1.NetworkTypeRaw, Name: "cnv", RawCNIConfig: `{"cniVersion": "0.3.1", "type": "cnv-bridge", "ipam": {"type": "kube-ovn"}}`},
+			// IPAM plugins are not CNI plugins of their own
+			{Type: operv1.NetworkTypeRaw, Name: "ipam", RawCNIConfig: `{"cniVersion": "0.3.1", "type": "dhcp"}`},
+			{Type: operv1.NetworkTypeRaw, Name: "malformed", RawCNIConfig: `{"type": "cnv-bridge"`},
+		},
+	}
+	g.Expect(UnknownRawCNIPlugins(conf)).To(Equal([]string{
+		`plugin type "macvlann" of additional network typo`,
+		`plugin type "cnv-bridge" of additional network cnv`,
+		`IPAM type "kube-ovn" of additional network cnv`,
+		`plugin type "dhcp" of additional network ipam`,
+	}))
+}
+
 func TestRenderSimpleMacvlanConfig(t *testing.T) {
 	g := NewGomegaWithT(t)
 
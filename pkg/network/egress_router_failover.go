@@ -0,0 +1,118 @@
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// gratuitousARPCount is how many gratuitous ARPs are sent when claiming an egress address
+	gratuitousARPCount = 3
+	// gratuitousARPInterval is the delay between the gratuitous ARPs
+	gratuitousARPInterval = 200 * time.Millisecond
+)
+
+// CheckEgressRouterCapabilities checks that the failover sidecar of an HA egress
+// router holds NET_ADMIN and NET_RAW. The sidecar runs as a non-root user, so it
+// only holds them when the container runtime passes the capabilities added to
+// the container as ambient capabilities.
+func CheckEgressRouterCapabilities() error {
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{}
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return errors.Wrap(err, "could not get the capabilities of the process")
+	}
+	for name, capability := range map[string]uint32{"NET_ADMIN": unix.CAP_NET_ADMIN, "NET_RAW": unix.CAP_NET_RAW} {
+		if data[0].Effective&(1<<capability) == 0 {
+			return errors.Errorf("the process does not hold the %s capability, which must be granted as an ambient capability to a non-root container", name)
+		}
+	}
+	return nil
+}
+
+// ReleaseEgressAddress stops the interface of an HA egress router replica from
+// answering ARP, so that the replicas that are not the leader do not claim the
+// egress address they share with it.
+func ReleaseEgressAddress(iface string) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return errors.Wrapf(err, "could not find interface %s", iface)
+	}
+	if err := netlink.LinkSetARPOff(link); err != nil {
+		return errors.Wrapf(err, "could not disable ARP on %s", iface)
+	}
+	return nil
+}
+
+// ClaimEgressAddress lets the interface of the leader of an HA egress router
+// answer ARP again, and sends gratuitous ARPs so that the neighbours of the
+// egress network update their cache with the MAC of the leader.
+func ClaimEgressAddress(iface string, ip net.IP) error {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return errors.Errorf("egress address %s is not an IPv4 address", ip)
+	}
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return errors.Wrapf(err, "could not find interface %s", iface)
+	}
+	if err := netlink.LinkSetARPOn(link); err != nil {
+		return errors.Wrapf(err, "could not enable ARP on %s", iface)
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return errors.Wrap(err, "could not open a packet socket")
+	}
+	defer unix.Close(fd)
+
+	broadcast := [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	addr := &unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_ARP),
+		Ifindex:  link.Attrs().Index,
+		Halen:    6,
+		Addr:     broadcast,
+	}
+	packet := gratuitousARP(link.Attrs().HardwareAddr, ip4)
+	for i := 0; i < gratuitousARPCount; i++ {
+		if i > 0 {
+			time.Sleep(gratuitousARPInterval)
+		}
+		if err := unix.Sendto(fd, packet, 0, addr); err != nil {
+			return errors.Wrapf(err, "could not send a gratuitous ARP on %s", iface)
+		}
+	}
+	return nil
+}
+
+// gratuitousARP builds the Ethernet frame of an ARP request for ip from ip,
+// broadcast from mac.
+func gratuitousARP(mac net.HardwareAddr, ip net.IP) []byte {
+	frame := make([]byte, 0, 42)
+	// Ethernet header
+	frame = append(frame, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	frame = append(frame, mac[:6]...)
+	frame = binary.BigEndian.AppendUint16(frame, unix.ETH_P_ARP)
+	// ARP request, Ethernet and IPv4
+	frame = binary.BigEndian.AppendUint16(frame, 1)
+	frame = binary.BigEndian.AppendUint16(frame, unix.ETH_P_IP)
+	frame = append(frame, 6, 4)
+	frame = binary.BigEndian.AppendUint16(frame, 1)
+	frame = append(frame, mac[:6]...)
+	frame = append(frame, ip...)
+	frame = append(frame, 0, 0, 0, 0, 0, 0)
+	frame = append(frame, ip...)
+	return frame
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
@@ -4,10 +4,13 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
 
 	operv1 "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
@@ -69,6 +72,13 @@ func noKubeProxyConfig(conf *operv1.NetworkSpec) bool {
 
 // validateKubeProxy checks that the kube-proxy specific configuration is basically sane.
 func validateKubeProxy(conf *operv1.NetworkSpec) []error {
+	out := validateKubeProxyConfig(conf)
+	out = append(out, validateKubeProxyNodePools(conf)...)
+	return out
+}
+
+// validateKubeProxyConfig checks the cluster-wide, or a node pool's, kube-proxy configuration.
+func validateKubeProxyConfig(conf *operv1.NetworkSpec) []error {
 	out := []error{}
 	p := conf.KubeProxyConfig
 	if p == nil {
@@ -114,9 +124,136 @@ func validateKubeProxy(conf *operv1.NetworkSpec) []error {
 		}
 	}
 
+	// Arguments that don't map to a kube-proxy configuration option would be
+	// dropped, so make sure a typo doesn't go unnoticed
+	unused, known := k8sutil.UnusedKubeProxyArguments(p.ProxyArguments)
+	for _, arg := range unused {
+		if suggestion := closestKubeProxyArgument(arg, known); suggestion != "" {
+			out = append(out, errors.Errorf("unknown kube-proxy argument --%s, did you mean --%s?", arg, suggestion))
+		} else {
+			out = append(out, errors.Errorf("unknown kube-proxy argument --%s", arg))
+		}
+	}
+
+	mode := kubeProxyMode(conf)
+	if !supportedProxyModes.Has(mode) {
+		out = append(out, errors.Errorf("invalid kube-proxy --proxy-mode %q, must be one of %s", mode, strings.Join(sets.List(supportedProxyModes), ", ")))
+	}
+
+	return out
+}
+
+// kubeProxyModeErrors returns the options of the cluster-wide, and each node
+// pool's, kube-proxy configuration that do not apply to its proxy mode.
+// kube-proxy ignores them, so they are only refused once they change, see
+// isKubeProxyChangeSafe.
+func kubeProxyModeErrors(conf *operv1.NetworkSpec) []error {
+	out := proxyModeErrors(conf)
+	pools, _ := kubeProxyNodePools(conf)
+	for _, pool := range pools {
+		for _, err := range proxyModeErrors(pool.poolConfig(conf)) {
+			out = append(out, errors.Errorf("kube-proxy node pool %q: %v", pool.Name, err))
+		}
+	}
+	return out
+}
+
+func proxyModeErrors(conf *operv1.NetworkSpec) []error {
+	out := []error{}
+	p := conf.KubeProxyConfig
+	mode := kubeProxyMode(conf)
+	if p == nil || !supportedProxyModes.Has(mode) {
+		return out
+	}
+	if p.IptablesSyncPeriod != "" && mode != "iptables" {
+		out = append(out, errors.Errorf("IptablesSyncPeriod does not apply to proxy-mode %q", mode))
+	}
+	for _, arg := range sets.List(sets.KeySet(p.ProxyArguments)) {
+		if modes, ok := proxyModeArguments[arg]; ok && !modes.Has(mode) {
+			out = append(out, errors.Errorf("kube-proxy --%s does not apply to proxy-mode %q", arg, mode))
+		}
+	}
 	return out
 }
 
+// supportedProxyModes are the proxy modes of the kube-proxy we ship
+var supportedProxyModes = sets.New[string]("iptables", "ipvs", string(k8sutil.ProxyModeNFTables))
+
+// proxyModeArguments are the kube-proxy arguments that only apply to some
+// proxy modes, and those modes
+var proxyModeArguments = map[string]sets.Set[string]{
+	"iptables-sync-period":         sets.New[string]("iptables"),
+	"iptables-min-sync-period":     sets.New[string]("iptables"),
+	"iptables-localhost-nodeports": sets.New[string]("iptables"),
+	"iptables-masquerade-bit":      sets.New[string]("iptables", "ipvs"),
+	"ipvs-sync-period":             sets.New[string]("ipvs"),
+	"ipvs-min-sync-period":         sets.New[string]("ipvs"),
+	"ipvs-scheduler":               sets.New[string]("ipvs"),
+	"ipvs-exclude-cidrs":           sets.New[string]("ipvs"),
+	"ipvs-strict-arp":              sets.New[string]("ipvs"),
+	"ipvs-tcp-timeout":             sets.New[string]("ipvs"),
+	"ipvs-tcp-fin-timeout":         sets.New[string]("ipvs"),
+	"ipvs-udp-timeout":             sets.New[string]("ipvs"),
+	"nftables-sync-period":         sets.New[string]("nftables"),
+	"nftables-min-sync-period":     sets.New[string]("nftables"),
+	"nftables-masquerade-bit":      sets.New[string]("nftables"),
+}
+
+// maxArgumentSuggestionDistance is how many edits away from an unknown
+// argument a known argument may be to be suggested instead
+const maxArgumentSuggestionDistance = 3
+
+// closestKubeProxyArgument returns the known argument closest to arg, if it is
+// close enough to be a likely typo.
+func closestKubeProxyArgument(arg string, known []string) string {
+	suggestion := ""
+	best := maxArgumentSuggestionDistance + 1
+	for _, k := range known {
+		if d := editDistance(arg, k); d < best {
+			suggestion, best = k, d
+		}
+	}
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// kubeProxyMode returns the proxy mode requested in the kube-proxy arguments,
+// which defaults to iptables.
+func kubeProxyMode(conf *operv1.NetworkSpec) string {
+	if conf.KubeProxyConfig != nil {
+		if val := conf.KubeProxyConfig.ProxyArguments["proxy-mode"]; len(val) > 0 && val[len(val)-1] != "" {
+			return val[len(val)-1]
+		}
+	}
+	return "iptables"
+}
+
 // defaultDeployKubeProxy determines if kube-proxy is deployed by default for the given
 // network type. OpenShiftSDN deploys its own kube-proxy. OVNKubernetes handles
 // services on its own. All other network providers are assumed to require a
@@ -164,20 +301,75 @@ func fillKubeProxyDefaults(conf, previous *operv1.NetworkSpec) {
 	}
 }
 
-// isKubeProxyChangeSafe is noop, but it would check if the proposed kube-proxy
-// change is safe.
+// isKubeProxyChangeSafe checks if the proposed kube-proxy change is safe. Options
+// that do not apply to the proxy mode are refused when they are set or the mode
+// changes, but the ones the applied configuration already had are kept, as
+// kube-proxy ignores them.
 func isKubeProxyChangeSafe(prev, next *operv1.NetworkSpec) []error {
-	// At present, all kube-proxy changes are safe to deploy
-	return nil
+	applied := sets.New[string]()
+	for _, err := range kubeProxyModeErrors(prev) {
+		applied.Insert(err.Error())
+	}
+	out := []error{}
+	for _, err := range kubeProxyModeErrors(next) {
+		if applied.Has(err.Error()) {
+			klog.Warningf("Ignoring the kube-proxy configuration already applied: %v", err)
+			continue
+		}
+		out = append(out, err)
+	}
+	return out
 }
 
 // renderStandaloneKubeProxy renders the standalone kube-proxy if installation was
-// requested.
+// requested. A separate kube-proxy is rendered for each of the kube-proxy node pools.
 func renderStandaloneKubeProxy(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.BootstrapResult, manifestDir string) ([]*uns.Unstructured, error) {
 	if !*conf.DeployKubeProxy {
 		return nil, nil
 	}
 
+	pools, err := kubeProxyNodePools(conf)
+	if err != nil {
+		return nil, err
+	}
+	poolLabels := make([]nodeLabel, 0, len(pools))
+	for _, pool := range pools {
+		poolLabels = append(poolLabels, pool.nodeLabel())
+	}
+
+	// The cluster-wide kube-proxy runs on the nodes that belong to no pool
+	data, err := kubeProxyRenderData(conf, bootstrapResult)
+	if err != nil {
+		return nil, err
+	}
+	data.Data["KubeProxyPoolExclusions"] = poolLabels
+	manifests, err := render.RenderDir(filepath.Join(manifestDir, "kube-proxy"), &data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render kube-proxy manifests")
+	}
+
+	// A node belongs to the first pool that selects it
+	for i, pool := range pools {
+		data, err := kubeProxyRenderData(pool.poolConfig(conf), bootstrapResult)
+		if err != nil {
+			return nil, errors.Wrapf(err, "kube-proxy node pool %q", pool.Name)
+		}
+		data.Data["KubeProxyPoolSuffix"] = "-" + pool.Name
+		data.Data["KubeProxyPoolNodeSelector"] = poolLabels[i]
+		data.Data["KubeProxyPoolExclusions"] = poolLabels[:i]
+		objs, err := render.RenderTemplate(filepath.Join(manifestDir, "kube-proxy", "kube-proxy.yaml"), &data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to render kube-proxy manifests for node pool %q", pool.Name)
+		}
+		manifests = append(manifests, objs...)
+	}
+
+	return manifests, nil
+}
+
+// kubeProxyRenderData returns the data to render a standalone kube-proxy with
+// the given configuration.
+func kubeProxyRenderData(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.BootstrapResult) (render.RenderData, error) {
 	metricsPort := "9102"
 	healthzPort := "10255"
 	if val, ok := conf.KubeProxyConfig.ProxyArguments["metrics-port"]; ok {
@@ -199,7 +391,7 @@ func renderStandaloneKubeProxy(conf *operv1.NetworkSpec, bootstrapResult *bootst
 	}
 	kpc, err := kubeProxyConfiguration(kpcDefaults, conf, kpcOverrides)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to generate kube-proxy configuration file")
+		return render.RenderData{}, errors.Wrapf(err, "failed to generate kube-proxy configuration file")
 	}
 
 	data := render.MakeRenderData()
@@ -211,6 +403,9 @@ func renderStandaloneKubeProxy(conf *operv1.NetworkSpec, bootstrapResult *bootst
 	data.Data["KubeProxyConfig"] = kpc
 	data.Data["MetricsPort"] = metricsPort
 	data.Data["HealthzPort"] = healthzPort
+	data.Data["KubeProxyPoolSuffix"] = ""
+	data.Data["KubeProxyPoolNodeSelector"] = nil
+	data.Data["KubeProxyPoolExclusions"] = nil
 	data.Data["KUBE_PROXY_NODE_SELECTOR"] = ""
 	// DPU_DEV_PREVIEW
 	if bootstrapResult.OVN.OVNKubernetesConfig != nil {
@@ -218,11 +413,5 @@ func renderStandaloneKubeProxy(conf *operv1.NetworkSpec, bootstrapResult *bootst
 			data.Data["KUBE_PROXY_NODE_SELECTOR"] = bootstrapResult.OVN.OVNKubernetesConfig.DpuModeLabel + ": ''"
 		}
 	}
-
-	manifests, err := render.RenderDir(filepath.Join(manifestDir, "kube-proxy"), &data)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to render kube-proxy manifests")
-	}
-
-	return manifests, nil
+	return data, nil
 }
@@ -0,0 +1,137 @@
+package network
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	k8sutil "github.com/openshift/cluster-network-operator/pkg/util/k8s"
+)
+
+// kubeProxyNodePool is a set of nodes, selected by a node label, on which the
+// standalone kube-proxy runs with its own arguments. There is no API for these
+// yet, so they are read from the kubeProxyNodePools field of the operator
+// configuration's unsupportedConfigOverrides:
+//
+//	unsupportedConfigOverrides:
+//	  kubeProxyNodePools:
+//	  - name: edge
+//	    nodeSelector:
+//	      node-role.kubernetes.io/edge: ""
+//	    proxyArguments:
+//	      node-port-addresses: ["192.168.100.0/24"]
+//
+// The pool's arguments are merged on top of kubeProxyConfig.proxyArguments.
+// A node belongs to the first pool that selects it; nodes that belong to no
+// pool run the cluster-wide kube-proxy.
+type kubeProxyNodePool struct {
+	Name           string                              `json:"name"`
+	NodeSelector   map[string]string                   `json:"nodeSelector"`
+	ProxyArguments map[string]operv1.ProxyArgumentList `json:"proxyArguments,omitempty"`
+}
+
+// maxKubeProxyPoolNameLength keeps the "kube-proxy-<name>" app label of the
+// pool's pods a valid label value
+const maxKubeProxyPoolNameLength = validation.LabelValueMaxLength - len("kube-proxy-")
+
+// nodeLabel is a single node label, as rendered in the kube-proxy node
+// selector and affinity. Key and Value are JSON-quoted, so they are valid YAML.
+type nodeLabel struct {
+	Key   string
+	Value string
+}
+
+// kubeProxyNodePools returns the kube-proxy node pools declared in the
+// operator configuration's unsupportedConfigOverrides.
+func kubeProxyNodePools(conf *operv1.NetworkSpec) ([]kubeProxyNodePool, error) {
+	if len(conf.UnsupportedConfigOverrides.Raw) == 0 {
+		return nil, nil
+	}
+	overrides := struct {
+		KubeProxyNodePools []kubeProxyNodePool `json:"kubeProxyNodePools"`
+	}{}
+	if err := json.Unmarshal(conf.UnsupportedConfigOverrides.Raw, &overrides); err != nil {
+		return nil, errors.Wrap(err, "failed to parse kubeProxyNodePools")
+	}
+	return overrides.KubeProxyNodePools, nil
+}
+
+// nodeLabel returns the single label that selects the nodes of the pool
+func (pool *kubeProxyNodePool) nodeLabel() nodeLabel {
+	for key, value := range pool.NodeSelector {
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(value)
+		return nodeLabel{Key: string(k), Value: string(v)}
+	}
+	return nodeLabel{}
+}
+
+// poolConfig returns the configuration the pool's kube-proxy is generated
+// from: the cluster-wide configuration with the pool's arguments merged in.
+func (pool *kubeProxyNodePool) poolConfig(conf *operv1.NetworkSpec) *operv1.NetworkSpec {
+	out := conf.DeepCopy()
+	if out.KubeProxyConfig == nil {
+		out.KubeProxyConfig = &operv1.ProxyConfig{}
+	}
+	out.KubeProxyConfig.ProxyArguments = k8sutil.MergeKubeProxyArguments(out.KubeProxyConfig.ProxyArguments, pool.ProxyArguments)
+	return out
+}
+
+// validateKubeProxyNodePools checks that the kube-proxy node pools are well
+// formed, and that the configuration of each pool is valid.
+func validateKubeProxyNodePools(conf *operv1.NetworkSpec) []error {
+	out := []error{}
+	pools, err := kubeProxyNodePools(conf)
+	if err != nil {
+		return append(out, err)
+	}
+	if len(pools) == 0 {
+		return out
+	}
+
+	deployKubeProxy := defaultDeployKubeProxy(conf)
+	if conf.DeployKubeProxy != nil {
+		deployKubeProxy = *conf.DeployKubeProxy
+	}
+	if !deployKubeProxy {
+		return append(out, errors.Errorf("kubeProxyNodePools require the standalone kube-proxy to be deployed"))
+	}
+
+	poolNames := sets.New[string]()
+	selectors := sets.New[string]()
+	for _, pool := range pools {
+		if errs := validation.IsDNS1123Label(pool.Name); len(errs) > 0 {
+			out = append(out, errors.Errorf("invalid kube-proxy node pool name %q: %v", pool.Name, errs))
+		} else if len(pool.Name) > maxKubeProxyPoolNameLength {
+			out = append(out, errors.Errorf("invalid kube-proxy node pool name %q: must be no more than %d characters", pool.Name, maxKubeProxyPoolNameLength))
+		} else if poolNames.Has(pool.Name) {
+			out = append(out, errors.Errorf("kube-proxy node pool %q is defined more than once", pool.Name))
+		}
+		poolNames.Insert(pool.Name)
+
+		if len(pool.NodeSelector) != 1 {
+			out = append(out, errors.Errorf("kube-proxy node pool %q: nodeSelector must have exactly one label", pool.Name))
+		} else {
+			for key, value := range pool.NodeSelector {
+				if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+					out = append(out, errors.Errorf("kube-proxy node pool %q: invalid nodeSelector label %q: %v", pool.Name, key, errs))
+				}
+				if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+					out = append(out, errors.Errorf("kube-proxy node pool %q: invalid nodeSelector value %q: %v", pool.Name, value, errs))
+				}
+				if selectors.Has(key + "=" + value) {
+					out = append(out, errors.Errorf("kube-proxy node pool %q: nodeSelector %s=%s is already used by another pool", pool.Name, key, value))
+				}
+				selectors.Insert(key + "=" + value)
+			}
+		}
+
+		for _, err := range validateKubeProxyConfig(pool.poolConfig(conf)) {
+			out = append(out, errors.Errorf("kube-proxy node pool %q: %v", pool.Name, err))
+		}
+	}
+	return out
+}
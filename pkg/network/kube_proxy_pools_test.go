@@ -0,0 +1,133 @@
+package network
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func kubeProxyPoolsConfig(pools string) *operv1.NetworkSpec {
+	c := &operv1.NetworkSpec{
+		ClusterNetwork: []operv1.ClusterNetworkEntry{
+			{
+				CIDR:       "192.168.0.0/14",
+				HostPrefix: 23,
+			},
+		},
+		DefaultNetwork: operv1.DefaultNetworkDefinition{Type: "Flannel"},
+		KubeProxyConfig: &operv1.ProxyConfig{
+			ProxyArguments: map[string]operv1.ProxyArgumentList{
+				"conntrack-max-per-core": {"32768"},
+			},
+		},
+	}
+	c.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(`{"kubeProxyNodePools": ` + pools + `}`)}
+	return c
+}
+
+func TestValidateKubeProxyNodePools(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := kubeProxyPoolsConfig(`[
+		{"name": "edge", "nodeSelector": {"node-role.kubernetes.io/edge": ""}, "proxyArguments": {"node-port-addresses": ["192.168.100.0/24"]}},
+		{"name": "gpu", "nodeSelector": {"example.com/gpu": "true"}}
+	]`)
+	g.Expect(validateKubeProxy(c)).To(BeEmpty())
+
+	errExpect := func(c *operv1.NetworkSpec, substr string) {
+		t.Helper()
+		g.Expect(validateKubeProxy(c)).To(
+			ContainElement(MatchError(
+				ContainSubstring(substr))))
+	}
+
+	errExpect(kubeProxyPoolsConfig(`{}`), "failed to parse kubeProxyNodePools")
+	errExpect(kubeProxyPoolsConfig(`[{"name": "Edge", "nodeSelector": {"edge": ""}}]`),
+		`invalid kube-proxy node pool name "Edge"`)
+	errExpect(kubeProxyPoolsConfig(`[{"name": "edge", "nodeSelector": {"edge": ""}}, {"name": "edge", "nodeSelector": {"other": ""}}]`),
+		`kube-proxy node pool "edge" is defined more than once`)
+	errExpect(kubeProxyPoolsConfig(`[{"name": "edge", "nodeSelector": {"edge": "", "zone": "a"}}]`),
+		"nodeSelector must have exactly one label")
+	errExpect(kubeProxyPoolsConfig(`[{"name": "edge"}]`),
+		"nodeSelector must have exactly one label")
+	errExpect(kubeProxyPoolsConfig(`[{"name": "edge", "nodeSelector": {"edge": "a b"}}]`),
+		`invalid nodeSelector value "a b"`)
+	errExpect(kubeProxyPoolsConfig(`[{"name": "a", "nodeSelector": {"edge": ""}}, {"name": "b", "nodeSelector": {"edge": ""}}]`),
+		"nodeSelector edge= is already used by another pool")
+	errExpect(kubeProxyPoolsConfig(`[{"name": "edge", "nodeSelector": {"edge": ""}, "proxyArguments": {"metrics-port": ["1234"]}}]`),
+		`kube-proxy node pool "edge": kube-proxy --metrics-port cannot be overridden`)
+
+	c = kubeProxyPoolsConfig(`[{"name": "edge", "nodeSelector": {"edge": ""}}]`)
+	c.DefaultNetwork.Type = operv1.NetworkTypeOpenShiftSDN
+	errExpect(c, "kubeProxyNodePools require the standalone kube-proxy to be deployed")
+}
+
+func TestRenderKubeProxyNodePools(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := kubeProxyPoolsConfig(`[
+		{"name": "edge", "nodeSelector": {"node-role.kubernetes.io/edge": ""}, "proxyArguments": {"node-port-addresses": ["192.168.100.0/24"]}},
+		{"name": "gpu", "nodeSelector": {"example.com/gpu": "true"}, "proxyArguments": {"conntrack-max-per-core": ["0"]}}
+	]`)
+	fillKubeProxyDefaults(c, nil)
+
+	objs, err := renderStandaloneKubeProxy(c, &FakeKubeProxyBootstrapResult, manifestDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(14))
+
+	for _, name := range []string{"openshift-kube-proxy", "openshift-kube-proxy-edge", "openshift-kube-proxy-gpu"} {
+		g.Expect(objs).To(ContainElement(HaveKubernetesID("DaemonSet", "openshift-kube-proxy", name)))
+	}
+
+	getObj := func(kind, name string) *uns.Unstructured {
+		for _, obj := range objs {
+			if obj.GetKind() == kind && obj.GetName() == name {
+				return obj
+			}
+		}
+		t.Fatalf("%s %s not rendered", kind, name)
+		return nil
+	}
+	proxyConfig := func(name string) string {
+		val, _, err := uns.NestedString(getObj("ConfigMap", name).Object, "data", "kube-proxy-config.yaml")
+		g.Expect(err).NotTo(HaveOccurred())
+		return val
+	}
+	exclusions := func(name string) []interface{} {
+		terms, _, err := uns.NestedSlice(getObj("DaemonSet", name).Object,
+			"spec", "template", "spec", "affinity", "nodeAffinity", "requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms")
+		g.Expect(err).NotTo(HaveOccurred())
+		if len(terms) == 0 {
+			return nil
+		}
+		return terms[0].(map[string]interface{})["matchExpressions"].([]interface{})
+	}
+
+	// pool arguments are merged on top of the cluster-wide arguments
+	g.Expect(proxyConfig("proxy-config")).To(ContainSubstring("maxPerCore: 32768"))
+	g.Expect(proxyConfig("proxy-config")).To(ContainSubstring("nodePortAddresses: null"))
+	g.Expect(proxyConfig("proxy-config-edge")).To(ContainSubstring("maxPerCore: 32768"))
+	g.Expect(proxyConfig("proxy-config-edge")).To(ContainSubstring("- 192.168.100.0/24"))
+	g.Expect(proxyConfig("proxy-config-gpu")).To(ContainSubstring("maxPerCore: 0"))
+
+	ds := getObj("DaemonSet", "openshift-kube-proxy-gpu")
+	nodeSelector, _, _ := uns.NestedStringMap(ds.Object, "spec", "template", "spec", "nodeSelector")
+	g.Expect(nodeSelector).To(Equal(map[string]string{"kubernetes.io/os": "linux", "example.com/gpu": "true"}))
+	labels, _, _ := uns.NestedStringMap(ds.Object, "spec", "selector", "matchLabels")
+	g.Expect(labels).To(Equal(map[string]string{"app": "kube-proxy-gpu"}))
+	volumes, _, _ := uns.NestedSlice(ds.Object, "spec", "template", "spec", "volumes")
+	g.Expect(volumes).To(ContainElement(HaveKeyWithValue("configMap", map[string]interface{}{"name": "proxy-config-gpu"})))
+
+	// a node belongs to the first pool that selects it
+	g.Expect(exclusions("openshift-kube-proxy")).To(Equal([]interface{}{
+		map[string]interface{}{"key": "node-role.kubernetes.io/edge", "operator": "NotIn", "values": []interface{}{""}},
+		map[string]interface{}{"key": "example.com/gpu", "operator": "NotIn", "values": []interface{}{"true"}},
+	}))
+	g.Expect(exclusions("openshift-kube-proxy-edge")).To(BeEmpty())
+	g.Expect(exclusions("openshift-kube-proxy-gpu")).To(Equal([]interface{}{
+		map[string]interface{}{"key": "node-role.kubernetes.io/edge", "operator": "NotIn", "values": []interface{}{""}},
+	}))
+}
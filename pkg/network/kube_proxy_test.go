@@ -22,7 +22,7 @@ var config = operv1.NetworkSpec{
 		IptablesSyncPeriod: "1m",
 		ProxyArguments: map[string]operv1.ProxyArgumentList{
 			// string
-			"proxy-mode": {"blah"},
+			"proxy-mode": {"iptables"},
 
 			// duration
 			"iptables-min-sync-period": {"2m"},
@@ -51,7 +51,7 @@ var configIPv6 = operv1.NetworkSpec{
 		IptablesSyncPeriod: "1m",
 		ProxyArguments: map[string]operv1.ProxyArgumentList{
 			// string
-			"proxy-mode": {"blah"},
+			"proxy-mode": {"iptables"},
 
 			// duration
 			"iptables-min-sync-period": {"2m"},
@@ -131,7 +131,7 @@ logging:
       infoBufferSize: "0"
   verbosity: 0
 metricsBindAddress: 1.2.3.4:999
-mode: blah
+mode: iptables
 nodePortAddresses: null
 oomScoreAdj: null
 portRange: ""
@@ -209,7 +209,7 @@ logging:
       infoBufferSize: "0"
   verbosity: 0
 metricsBindAddress: '[fd00:1234::4]:51999'
-mode: blah
+mode: iptables
 nodePortAddresses: null
 oomScoreAdj: null
 portRange: ""
@@ -260,7 +260,7 @@ func TestValidateKubeProxy(t *testing.T) {
 			BindAddress:        "1.2.3.4",
 			IptablesSyncPeriod: "30s",
 			ProxyArguments: map[string]operv1.ProxyArgumentList{
-				"foo": {"bar"},
+				"conntrack-min": {"131072"},
 			},
 		},
 	}
@@ -275,6 +275,109 @@ func TestValidateKubeProxy(t *testing.T) {
 	g.Expect(validateKubeProxy(c)).To(HaveLen(5))
 }
 
+func TestValidateKubeProxyMode(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	conf := func(args map[string]operv1.ProxyArgumentList) *operv1.NetworkSpec {
+		return &operv1.NetworkSpec{
+			DefaultNetwork:  operv1.DefaultNetworkDefinition{Type: "Flannel"},
+			KubeProxyConfig: &operv1.ProxyConfig{ProxyArguments: args},
+		}
+	}
+
+	for _, mode := range []string{"", "iptables", "ipvs", "nftables"} {
+		g.Expect(validateKubeProxy(conf(map[string]operv1.ProxyArgumentList{"proxy-mode": {mode}}))).To(BeEmpty())
+	}
+	g.Expect(validateKubeProxy(conf(map[string]operv1.ProxyArgumentList{"proxy-mode": {"userspace"}}))).To(
+		ConsistOf(MatchError(`invalid kube-proxy --proxy-mode "userspace", must be one of iptables, ipvs, nftables`)))
+
+	// Options that do not apply to the proxy mode are refused once they change
+	applied := conf(nil)
+	g.Expect(isKubeProxyChangeSafe(applied, conf(map[string]operv1.ProxyArgumentList{
+		"proxy-mode":               {"nftables"},
+		"nftables-sync-period":     {"1m"},
+		"nftables-min-sync-period": {"5s"},
+		"nftables-masquerade-bit":  {"14"},
+		"masquerade-all":           {"true"},
+	}))).To(BeEmpty())
+	g.Expect(isKubeProxyChangeSafe(applied, conf(map[string]operv1.ProxyArgumentList{
+		"proxy-mode":              {"ipvs"},
+		"iptables-masquerade-bit": {"14"},
+		"ipvs-scheduler":          {"rr"},
+	}))).To(BeEmpty())
+
+	g.Expect(isKubeProxyChangeSafe(applied, conf(map[string]operv1.ProxyArgumentList{
+		"proxy-mode":              {"nftables"},
+		"iptables-masquerade-bit": {"14"},
+		"ipvs-scheduler":          {"rr"},
+	}))).To(ConsistOf(
+		MatchError(`kube-proxy --iptables-masquerade-bit does not apply to proxy-mode "nftables"`),
+		MatchError(`kube-proxy --ipvs-scheduler does not apply to proxy-mode "nftables"`),
+	))
+	g.Expect(isKubeProxyChangeSafe(applied, conf(map[string]operv1.ProxyArgumentList{
+		"nftables-sync-period": {"1m"},
+	}))).To(ConsistOf(MatchError(`kube-proxy --nftables-sync-period does not apply to proxy-mode "iptables"`)))
+
+	c := conf(map[string]operv1.ProxyArgumentList{"proxy-mode": {"nftables"}})
+	c.KubeProxyConfig.IptablesSyncPeriod = "30s"
+	g.Expect(isKubeProxyChangeSafe(applied, c)).To(ConsistOf(MatchError(`IptablesSyncPeriod does not apply to proxy-mode "nftables"`)))
+	g.Expect(validateKubeProxy(c)).To(BeEmpty())
+
+	// An existing ipvs cluster keeps its IptablesSyncPeriod, which kube-proxy
+	// ignores, but it cannot switch an iptables cluster's to ipvs
+	ipvs := conf(map[string]operv1.ProxyArgumentList{"proxy-mode": {"ipvs"}})
+	ipvs.KubeProxyConfig.IptablesSyncPeriod = "30s"
+	g.Expect(isKubeProxyChangeSafe(ipvs, ipvs)).To(BeEmpty())
+	next := ipvs.DeepCopy()
+	next.KubeProxyConfig.ProxyArguments["ipvs-scheduler"] = []string{"rr"}
+	g.Expect(isKubeProxyChangeSafe(ipvs, next)).To(BeEmpty())
+	iptables := conf(nil)
+	iptables.KubeProxyConfig.IptablesSyncPeriod = "30s"
+	g.Expect(isKubeProxyChangeSafe(iptables, ipvs)).To(ConsistOf(MatchError(`IptablesSyncPeriod does not apply to proxy-mode "ipvs"`)))
+
+	// The options of the node pools are checked the same way
+	pools := kubeProxyPoolsConfig(`[{"name": "edge", "nodeSelector": {"edge": ""}, "proxyArguments": {"proxy-mode": ["ipvs"], "nftables-sync-period": ["1m"]}}]`)
+	g.Expect(isKubeProxyChangeSafe(applied, pools)).To(ConsistOf(
+		MatchError(`kube-proxy node pool "edge": kube-proxy --nftables-sync-period does not apply to proxy-mode "ipvs"`)))
+	g.Expect(isKubeProxyChangeSafe(pools, pools)).To(BeEmpty())
+
+	// openshift-sdn has its own service proxy, which does not support nftables
+	c = OpenShiftSDNConfig.Spec.DeepCopy()
+	c.DefaultNetwork.OpenShiftSDNConfig.EnableUnidling = new(bool)
+	c.KubeProxyConfig = &operv1.ProxyConfig{ProxyArguments: map[string]operv1.ProxyArgumentList{"proxy-mode": {"nftables"}}}
+	g.Expect(validateOpenShiftSDN(c)).To(ContainElement(MatchError(ContainSubstring(`openshift-sdn does not support proxy-mode "nftables"`))))
+}
+
+func TestValidateKubeProxyUnknownArguments(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := &operv1.NetworkSpec{
+		DefaultNetwork: operv1.DefaultNetworkDefinition{Type: "Flannel"},
+		KubeProxyConfig: &operv1.ProxyConfig{
+			ProxyArguments: map[string]operv1.ProxyArgumentList{
+				"iptable-sync-period":   {"30s"},
+				"conntrack-max-percore": {"0"},
+				"foo":                   {"bar"},
+				"conntrack-min":         {"131072"},
+			},
+		},
+	}
+	g.Expect(validateKubeProxy(c)).To(ConsistOf(
+		MatchError("unknown kube-proxy argument --conntrack-max-percore, did you mean --conntrack-max-per-core?"),
+		MatchError("unknown kube-proxy argument --foo"),
+		MatchError("unknown kube-proxy argument --iptable-sync-period, did you mean --iptables-sync-period?"),
+	))
+}
+
+func TestEditDistance(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(editDistance("", "")).To(Equal(0))
+	g.Expect(editDistance("abc", "")).To(Equal(3))
+	g.Expect(editDistance("kitten", "sitting")).To(Equal(3))
+	g.Expect(editDistance("iptable-sync-period", "iptables-sync-period")).To(Equal(1))
+}
+
 func TestFillKubeProxyDefaults(t *testing.T) {
 	g := NewGomegaWithT(t)
 
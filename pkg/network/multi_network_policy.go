@@ -28,9 +28,13 @@ func renderMultiNetworkpolicyConfig(manifestDir string) ([]*uns.Unstructured, er
 	return objs, nil
 }
 
-// isMultiNetworkpolicyChangeSafe is noop, but it would check if the proposed kube-proxy
-// change is safe.
+// isMultiNetworkpolicyChangeSafe is noop, but it would check if the proposed
+// MultiNetworkPolicy change is safe.
 func isMultiNetworkpolicyChangeSafe(prev, next *operv1.NetworkSpec) []error {
-	// At present, all multiNetworkPolicy changes are safe to deploy
+	// At present, all multiNetworkPolicy changes are safe to deploy. Enabling
+	// or disabling useMultiNetworkPolicy is not refused, but before it is
+	// rendered, the operconfig controller reports the namespaces whose
+	// MultiNetworkPolicy objects start or stop being enforced (see
+	// multinetworkpolicy.ReportChange).
 	return nil
 }
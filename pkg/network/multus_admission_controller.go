@@ -3,16 +3,20 @@ package network
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	operv1 "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
 	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
 	"github.com/openshift/cluster-network-operator/pkg/hypershift"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/openshift/cluster-network-operator/pkg/names"
 	"github.com/openshift/cluster-network-operator/pkg/render"
@@ -20,49 +24,179 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/klog/v2"
 )
 
-// ignoredNamespaces contains the comma separated namespace list that should be ignored
-// to watch by multus admission controller. This only initialized first invocation.
-var ignoredNamespaces string
+const (
+	// openshiftNamespacePrefix and openshiftNamespaceSelector match the namespaces
+	// of OpenShift components, which the multus admission controller always
+	// ignores. Not all of them are monitored, hence the prefix match.
+	openshiftNamespacePrefix   = "openshift"
+	openshiftNamespaceSelector = "openshift.io/cluster-monitoring==true"
+
+	// MultusAdmissionPolicyEnforcing makes the multus admission controller also
+	// reject pods that reference NetworkAttachmentDefinitions outside of their
+	// own namespace
+	MultusAdmissionPolicyEnforcing = "Enforcing"
+)
+
+// alwaysIgnoredNamespaces are ignored by the multus admission controller even
+// when they do not exist yet
+var alwaysIgnoredNamespaces = []string{"openshift-etcd", "openshift-console", "openshift-ingress-canary"}
+
+// multusAdmissionControllerConfig is the configuration of the multus admission
+// controller. There is no API for it yet, so it is read from the
+// multusAdmissionController field of the operator configuration's
+// unsupportedConfigOverrides:
+//
+//	unsupportedConfigOverrides:
+//	  multusAdmissionController:
+//	    ignoredNamespaceSelector: "example.com/multus-ignored=true"
+//	    policy: Enforcing
+//
+// Namespaces matched by ignoredNamespaceSelector are ignored by the admission
+// controller, in addition to the OpenShift namespaces.
+type multusAdmissionControllerConfig struct {
+	IgnoredNamespaceSelector string `json:"ignoredNamespaceSelector,omitempty"`
+	Policy                   string `json:"policy,omitempty"`
+}
+
+// getMultusAdmissionControllerConfig returns the multus admission controller
+// configuration declared in the operator configuration's unsupportedConfigOverrides.
+func getMultusAdmissionControllerConfig(conf *operv1.NetworkSpec) (*multusAdmissionControllerConfig, error) {
+	overrides := struct {
+		MultusAdmissionController multusAdmissionControllerConfig `json:"multusAdmissionController"`
+	}{}
+	if len(conf.UnsupportedConfigOverrides.Raw) == 0 {
+		return &overrides.MultusAdmissionController, nil
+	}
+	if err := json.Unmarshal(conf.UnsupportedConfigOverrides.Raw, &overrides); err != nil {
+		return nil, errors.Wrap(err, "failed to parse multusAdmissionController")
+	}
+	return &overrides.MultusAdmissionController, nil
+}
 
-// getOpenshiftNamespaces collect openshift related namespaces, as comma separate list
-func getOpenshiftNamespaces(client cnoclient.Client) (string, error) {
-	namespaces := []string{}
+// validateMultusAdmissionController checks the multus admission controller configuration
+func validateMultusAdmissionController(conf *operv1.NetworkSpec) []error {
+	mac, err := getMultusAdmissionControllerConfig(conf)
+	if err != nil {
+		return []error{err}
+	}
+	out := []error{}
+	if mac.IgnoredNamespaceSelector != "" {
+		if _, err := labels.Parse(mac.IgnoredNamespaceSelector); err != nil {
+			out = append(out, errors.Errorf("invalid multusAdmissionController ignoredNamespaceSelector %q: %v", mac.IgnoredNamespaceSelector, err))
+		}
+	}
+	if mac.Policy != "" && mac.Policy != MultusAdmissionPolicyEnforcing {
+		out = append(out, errors.Errorf("invalid multusAdmissionController policy %q, must be empty or %q", mac.Policy, MultusAdmissionPolicyEnforcing))
+	}
+	return out
+}
 
-	// get openshift specific namespaces to add them into ignoreNamespace
-	nsList, err := client.Default().Kubernetes().CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{
-		LabelSelector: "openshift.io/cluster-monitoring==true",
-	})
+// multusAdmissionControllerSelectors returns the label selectors of the
+// namespaces the multus admission controller ignores, besides
+// alwaysIgnoredNamespaces and the namespaces with the OpenShift prefix: the
+// monitored OpenShift namespaces, plus those matched by the admin-supplied
+// ignoredNamespaceSelector.
+func multusAdmissionControllerSelectors(conf *operv1.NetworkSpec) ([]labels.Selector, error) {
+	mac, err := getMultusAdmissionControllerConfig(conf)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get namespaces to render multus admission controller manifests")
+		return nil, err
+	}
+	selectors := []string{openshiftNamespaceSelector}
+	if mac.IgnoredNamespaceSelector != "" {
+		selectors = append(selectors, mac.IgnoredNamespaceSelector)
+	}
+	out := []labels.Selector{}
+	for _, selector := range selectors {
+		parsed, err := labels.Parse(selector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid namespace selector %q", selector)
+		}
+		out = append(out, parsed)
 	}
+	return out, nil
+}
 
+// MultusAdmissionControllerIgnoresNamespace returns whether the multus
+// admission controller ignores the namespace with the given name and labels.
+func MultusAdmissionControllerIgnoresNamespace(conf *operv1.NetworkSpec, name string, nsLabels map[string]string) bool {
+	selectors, err := multusAdmissionControllerSelectors(conf)
+	if err != nil {
+		return false
+	}
+	return ignoresNamespace(selectors, name, nsLabels)
+}
+
+// ignoresNamespace returns whether a namespace is always ignored, has the
+// OpenShift prefix or matches one of the selectors.
+func ignoresNamespace(selectors []labels.Selector, name string, nsLabels map[string]string) bool {
+	if strings.HasPrefix(name, openshiftNamespacePrefix) {
+		return true
+	}
+	for _, ignored := range alwaysIgnoredNamespaces {
+		if name == ignored {
+			return true
+		}
+	}
+	for _, selector := range selectors {
+		if selector.Matches(labels.Set(nsLabels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MultusAdmissionControllerIgnoredNamespaces returns the sorted list of the
+// namespaces the multus admission controller ignores: the OpenShift namespaces,
+// plus the namespaces matched by the admin-supplied ignoredNamespaceSelector.
+// The prefix of the OpenShift namespaces cannot be matched by a selector, so all
+// the namespaces are listed and matched as MultusAdmissionControllerIgnoresNamespace
+// does.
+func MultusAdmissionControllerIgnoredNamespaces(ctx context.Context, client cnoclient.Client, conf *operv1.NetworkSpec) ([]string, error) {
+	selectors, err := multusAdmissionControllerSelectors(conf)
+	if err != nil {
+		return nil, err
+	}
+	nsList, err := client.Default().Kubernetes().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list namespaces")
+	}
+	namespaces := sets.New[string](alwaysIgnoredNamespaces...)
 	for _, ns := range nsList.Items {
-		namespaces = append(namespaces, ns.Name)
+		if ignoresNamespace(selectors, ns.Name, ns.Labels) {
+			namespaces.Insert(ns.Name)
+		}
 	}
-	return strings.Join(namespaces, ","), nil
+	return sets.List(namespaces), nil
 }
 
 // renderMultusAdmissonControllerConfig returns the manifests of Multus Admisson Controller
-func renderMultusAdmissonControllerConfig(manifestDir string, externalControlPlane bool, bootstrapResult *bootstrap.BootstrapResult, client cnoclient.Client) ([]*uns.Unstructured, error) {
+func renderMultusAdmissonControllerConfig(conf *operv1.NetworkSpec, manifestDir string, externalControlPlane bool, bootstrapResult *bootstrap.BootstrapResult, client cnoclient.Client) ([]*uns.Unstructured, error) {
 	objs := []*uns.Unstructured{}
-	var err error
 
 	replicas := getMultusAdmissionControllerReplicas(bootstrapResult)
-	if ignoredNamespaces == "" {
-		ignoredNamespaces, err = getOpenshiftNamespaces(client)
-		if err != nil {
-			klog.Warningf("failed to get openshift namespaces: %+v", err)
-		}
+	mac, err := getMultusAdmissionControllerConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	// The list is recomputed on every render, so that namespaces created or
+	// relabeled since the last render are picked up. It is sorted, so the
+	// rendered objects only change when the list does. A partial list would
+	// put OpenShift namespaces under the fail-closed /isolate webhook, so the
+	// render fails instead, and the objects applied last are kept.
+	ignoredNamespaces, err := MultusAdmissionControllerIgnoredNamespaces(context.TODO(), client, conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the namespaces ignored by the multus admission controller")
 	}
 
 	// render the manifests on disk
 	data := render.MakeRenderData()
 	data.Data["ReleaseVersion"] = os.Getenv("RELEASE_VERSION")
 	data.Data["MultusAdmissionControllerImage"] = os.Getenv("MULTUS_ADMISSION_CONTROLLER_IMAGE")
-	data.Data["IgnoredNamespace"] = ignoredNamespaces
+	data.Data["IgnoredNamespace"] = strings.Join(ignoredNamespaces, ",")
+	data.Data["IgnoredNamespaces"] = ignoredNamespaces
+	data.Data["MultusAdmissionEnforcing"] = mac.Policy == MultusAdmissionPolicyEnforcing
 	data.Data["MultusValidatingWebhookName"] = names.MULTUS_VALIDATING_WEBHOOK
 	data.Data["KubeRBACProxyImage"] = os.Getenv("KUBE_RBAC_PROXY_IMAGE")
 	data.Data["ExternalControlPlane"] = externalControlPlane
@@ -1,6 +1,8 @@
 package network
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -10,6 +12,10 @@ import (
 	cnofake "github.com/openshift/cluster-network-operator/pkg/client/fake"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 var MultusAdmissionControllerConfig = operv1.Network{
@@ -84,8 +90,8 @@ func TestRenderMultusAdmissionController(t *testing.T) {
 	g.Expect(objs).To(ContainElement(HaveKubernetesID("Deployment", "openshift-multus", "multus-admission-controller")))
 }
 
-// TestRenderMultusAdmissionControllerGetNamespace tests getOpenshiftNamespaces()
-func TestRenderMultusAdmissionControllerGetNamespace(t *testing.T) {
+// TestMultusAdmissionControllerIgnoredNamespaces tests MultusAdmissionControllerIgnoredNamespaces()
+func TestMultusAdmissionControllerIgnoredNamespaces(t *testing.T) {
 	g := NewGomegaWithT(t)
 
 	fakeClient := cnofake.NewFakeClient(
@@ -108,8 +114,130 @@ func TestRenderMultusAdmissionControllerGetNamespace(t *testing.T) {
 				"openshift.io/cluster-monitoring": "true",
 			},
 		},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name: "openshift-unmonitored",
+		},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name: "test4-admin-ignored",
+			Labels: map[string]string{
+				"example.com/multus": "ignored",
+			},
+		},
 		})
-	namespaces, err := getOpenshiftNamespaces(fakeClient)
+
+	config := MultusAdmissionControllerConfig.Spec.DeepCopy()
+	namespaces, err := MultusAdmissionControllerIgnoredNamespaces(context.TODO(), fakeClient, config)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(namespaces).To(Equal([]string{"openshift-console", "openshift-etcd", "openshift-ingress-canary", "openshift-unmonitored", "test1-ignored", "test3-ignored"}))
+
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multusAdmissionController":{"ignoredNamespaceSelector":"example.com/multus=ignored"}}`)
+	namespaces, err = MultusAdmissionControllerIgnoredNamespaces(context.TODO(), fakeClient, config)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(namespaces).To(Equal([]string{"openshift-console", "openshift-etcd", "openshift-ingress-canary", "openshift-unmonitored", "test1-ignored", "test3-ignored", "test4-admin-ignored"}))
+
+	// namespaces created after the first render are picked up
+	_, err = fakeClient.Default().Kubernetes().CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "test5-ignored",
+		Labels: map[string]string{"openshift.io/cluster-monitoring": "true"},
+	}}, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	namespaces, err = MultusAdmissionControllerIgnoredNamespaces(context.TODO(), fakeClient, config)
 	g.Expect(err).NotTo(HaveOccurred())
-	g.Expect(namespaces).To(Equal("test1-ignored,test3-ignored"))
+	g.Expect(namespaces).To(ContainElement("test5-ignored"))
+
+	// a single namespace is matched as the list does
+	g.Expect(MultusAdmissionControllerIgnoresNamespace(config, "openshift-etcd", nil)).To(BeTrue())
+	g.Expect(MultusAdmissionControllerIgnoresNamespace(config, "openshift-unmonitored", nil)).To(BeTrue())
+	g.Expect(MultusAdmissionControllerIgnoresNamespace(config, "test4-admin-ignored", map[string]string{"example.com/multus": "ignored"})).To(BeTrue())
+	g.Expect(MultusAdmissionControllerIgnoresNamespace(config, "test2-not-ignored", nil)).To(BeFalse())
+}
+
+func TestValidateMultusAdmissionController(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	config := MultusAdmissionControllerConfig.Spec.DeepCopy()
+	g.Expect(validateMultusAdmissionController(config)).To(BeEmpty())
+
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multusAdmissionController":{"ignoredNamespaceSelector":"example.com/multus=ignored","policy":"Enforcing"}}`)
+	g.Expect(validateMultusAdmissionController(config)).To(BeEmpty())
+
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multusAdmissionController":{"ignoredNamespaceSelector":"a b c","policy":"Strict"}}`)
+	errs := validateMultusAdmissionController(config)
+	g.Expect(errs).To(HaveLen(2))
+	g.Expect(errs[0]).To(MatchError(ContainSubstring("invalid multusAdmissionController ignoredNamespaceSelector")))
+	g.Expect(errs[1]).To(MatchError(ContainSubstring(`invalid multusAdmissionController policy "Strict"`)))
+}
+
+// TestRenderMultusAdmissionControllerEnforcing checks that the pod isolating
+// webhook is only rendered in enforcing mode, and skips the ignored namespaces
+func TestRenderMultusAdmissionControllerEnforcing(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := MultusAdmissionControllerConfig.DeepCopy()
+	config := &crd.Spec
+	fillDefaults(config, nil)
+
+	fakeClient := cnofake.NewFakeClient(
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test1-ignored",
+				Labels: map[string]string{
+					"openshift.io/cluster-monitoring": "true",
+				},
+			},
+		})
+	bootstrap := fakeBootstrapResult()
+
+	webhooks := func() []interface{} {
+		objs, err := renderMultusAdmissionController(config, manifestDir, false, bootstrap, fakeClient)
+		g.Expect(err).NotTo(HaveOccurred())
+		for _, obj := range objs {
+			if obj.GetKind() == "ValidatingWebhookConfiguration" {
+				webhooks, _, err := uns.NestedSlice(obj.Object, "webhooks")
+				g.Expect(err).NotTo(HaveOccurred())
+				return webhooks
+			}
+			if obj.GetKind() == "Deployment" {
+				containers, _, err := uns.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(fmt.Sprint(containers)).To(ContainSubstring("-ignore-namespaces=openshift-console,openshift-etcd,openshift-ingress-canary,test1-ignored"))
+			}
+		}
+		return nil
+	}
+
+	g.Expect(webhooks()).To(HaveLen(1))
+
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multusAdmissionController":{"policy":"Enforcing"}}`)
+	hooks := webhooks()
+	g.Expect(hooks).To(HaveLen(2))
+	isolating := hooks[1].(map[string]interface{})
+	g.Expect(isolating["name"]).To(Equal("multus-isolating-config.k8s.io"))
+	expressions, _, err := uns.NestedSlice(isolating, "namespaceSelector", "matchExpressions")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expressions).To(HaveLen(1))
+	values, _, err := uns.NestedStringSlice(expressions[0].(map[string]interface{}), "values")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(values).To(Equal([]string{"openshift-console", "openshift-etcd", "openshift-ingress-canary", "test1-ignored"}))
+}
+
+// TestRenderMultusAdmissionControllerListError checks that the render fails
+// rather than shrinking the list of ignored namespaces
+func TestRenderMultusAdmissionControllerListError(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := MultusAdmissionControllerConfig.DeepCopy()
+	config := &crd.Spec
+	fillDefaults(config, nil)
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multusAdmissionController":{"policy":"Enforcing"}}`)
+
+	fakeClient := cnofake.NewFakeClient()
+	fakeClient.Default().Kubernetes().(*k8sfake.Clientset).PrependReactor("list", "namespaces",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("apiserver unavailable")
+		})
+	_, err := renderMultusAdmissionController(config, manifestDir, false, fakeBootstrapResult(), fakeClient)
+	g.Expect(err).To(MatchError(ContainSubstring("apiserver unavailable")))
 }
@@ -94,6 +94,40 @@ func useDHCPSimpleMacvlan(conf *operv1.SimpleMacvlanConfig) bool {
 	return false
 }
 
+// useDHCP determines if an additional network needs the DHCP CNI daemon.
+func useDHCP(addnet *operv1.AdditionalNetworkDefinition) bool {
+	switch addnet.Type {
+	case operv1.NetworkTypeRaw:
+		return detectIPAMTypeRaw(ipamTypeDHCP, addnet)
+	case operv1.NetworkTypeSimpleMacvlan:
+		return useDHCPSimpleMacvlan(addnet.SimpleMacvlanConfig)
+	case NetworkTypeIPVLAN, NetworkTypeHostDevice, NetworkTypeBridge, NetworkTypeBond, NetworkTypeVLAN:
+		// Typed networks only support static and DHCP, as SimpleMacvlan.
+		return useDHCPTyped(addnet)
+	}
+	return false
+}
+
+// DHCPNetworks returns the additional networks, as "namespace/name", that
+// need the DHCP CNI daemon.
+func DHCPNetworks(conf *operv1.NetworkSpec) []string {
+	networks := []string{}
+	if conf.DisableMultiNetwork != nil && *conf.DisableMultiNetwork {
+		return networks
+	}
+	for _, addnet := range conf.AdditionalNetworks {
+		if !useDHCP(&addnet) {
+			continue
+		}
+		namespace := addnet.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		networks = append(networks, namespace+"/"+addnet.Name)
+	}
+	return networks
+}
+
 // detectAuxiliaryIPAM detects if an auxiliary ipam is used.
 func detectAuxiliaryIPAM(conf *operv1.NetworkSpec) (bool, bool) {
 	renderdhcp := false
@@ -107,13 +141,10 @@ func detectAuxiliaryIPAM(conf *operv1.NetworkSpec) (bool, bool) {
 	// Look and see if we have an AdditionalNetworks
 	if conf.AdditionalNetworks != nil {
 		for _, addnet := range conf.AdditionalNetworks {
-			switch addnet.Type {
-			case operv1.NetworkTypeRaw:
-				renderdhcp = renderdhcp || detectIPAMTypeRaw(ipamTypeDHCP, &addnet)
+			renderdhcp = renderdhcp || useDHCP(&addnet)
+			if addnet.Type == operv1.NetworkTypeRaw {
+				// SimpleMacvlan and typed networks only support static and DHCP. So we don't detect whereabouts.
 				renderwhereabouts = renderwhereabouts || detectIPAMTypeRaw(ipamTypeWhereabouts, &addnet)
-			case operv1.NetworkTypeSimpleMacvlan:
-				// SimpleMacvlan only supports static and DHCP. So we don't detect whereabouts.
-				renderdhcp = renderdhcp || useDHCPSimpleMacvlan(addnet.SimpleMacvlanConfig)
 			}
 
 			if renderdhcp && renderwhereabouts {
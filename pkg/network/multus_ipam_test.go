@@ -305,3 +305,25 @@ func TestRenderNoDHCPSimpleMacvlan(t *testing.T) {
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(objs).NotTo(ContainElement(HaveKubernetesID("DaemonSet", "openshift-multus", "dhcp-daemon")))
 }
+
+// TestDHCPNetworks tests the detection of the additional networks that need the DHCP daemon.
+func TestDHCPNetworks(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := DHCPConfig.DeepCopy()
+	config := &crd.Spec
+	config.AdditionalNetworks = append(config.AdditionalNetworks, NoIPAMConfig.Spec.AdditionalNetworks...)
+	config.AdditionalNetworks = append(config.AdditionalNetworks, DHCPConfigSimpleMacvlan.Spec.AdditionalNetworks...)
+	config.AdditionalNetworks[0].Namespace = "foobar"
+	fillDefaults(config, nil)
+
+	expected := []string{"foobar/net-attach-dhcp"}
+	for _, addnet := range DHCPConfigSimpleMacvlan.Spec.AdditionalNetworks {
+		expected = append(expected, "default/"+addnet.Name)
+	}
+	g.Expect(DHCPNetworks(config)).To(Equal(expected))
+
+	disabled := true
+	config.DisableMultiNetwork = &disabled
+	g.Expect(DHCPNetworks(config)).To(BeEmpty())
+}
@@ -14,14 +14,12 @@ import (
 	"github.com/openshift/cluster-network-operator/pkg/hypershift"
 	"github.com/openshift/cluster-network-operator/pkg/names"
 	"github.com/openshift/cluster-network-operator/pkg/render"
-	"github.com/openshift/cluster-network-operator/pkg/util/k8s"
 	"github.com/openshift/cluster-network-operator/pkg/util/validation"
 	"github.com/pkg/errors"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 	utilnet "k8s.io/utils/net"
@@ -75,7 +73,9 @@ func renderNetworkNodeIdentity(conf *operv1.NetworkSpec, bootstrapResult *bootst
 		data.Data["ConfigureNodeAdmissionWebhook"] = true
 	}
 
-	webhookReady := false
+	// webhookWaitFor references the workload serving the webhook, which must be
+	// ready before the webhook is applied
+	webhookWaitFor := ""
 	// HyperShift specific
 	if hcpCfg := hypershift.NewHyperShiftConfig(); hcpCfg.Enabled {
 		webhookCAClient = client.ClientFor(names.ManagementClusterName)
@@ -97,22 +97,8 @@ func renderNetworkNodeIdentity(conf *operv1.NetworkSpec, bootstrapResult *bootst
 		localAPIServer := bootstrapResult.Infra.APIServers[bootstrap.APIServerDefaultLocal]
 		data.Data["K8S_LOCAL_APISERVER"] = "https://" + net.JoinHostPort(localAPIServer.Host, localAPIServer.Port)
 
-		webhookDeployment := &appsv1.Deployment{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "Deployment",
-				APIVersion: appsv1.SchemeGroupVersion.String(),
-			},
-		}
-		nsn := types.NamespacedName{Namespace: hcpCfg.Namespace, Name: "network-node-identity"}
-		if err := client.ClientFor(names.ManagementClusterName).CRClient().Get(context.TODO(), nsn, webhookDeployment); err != nil {
-			if !apierrors.IsNotFound(err) {
-				return nil, fmt.Errorf("failed to retrieve existing network-node-identity deployment: %w", err)
-			} else {
-				klog.Infof("network-node-identity deployment does not exist")
-			}
-		} else {
-			webhookReady = !deploymentProgressing(webhookDeployment)
-		}
+		// The webhook is in the hosted cluster, its pods in the management cluster
+		webhookWaitFor = fmt.Sprintf("%s:Deployment/%s/network-node-identity", names.ManagementClusterName, hcpCfg.Namespace)
 
 		manifestDirs = append(manifestDirs, filepath.Join(manifestDir, "network/node-identity/managed"))
 	} else {
@@ -142,22 +128,7 @@ func renderNetworkNodeIdentity(conf *operv1.NetworkSpec, bootstrapResult *bootst
 			return nil, err
 		}
 
-		webhookDaemonSet := &appsv1.DaemonSet{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "DaemonSet",
-				APIVersion: appsv1.SchemeGroupVersion.String(),
-			},
-		}
-		nsn := types.NamespacedName{Namespace: NetworkNodeIdentityNamespace, Name: "network-node-identity"}
-		if err := client.Default().CRClient().Get(context.TODO(), nsn, webhookDaemonSet); err != nil {
-			if !apierrors.IsNotFound(err) {
-				return nil, fmt.Errorf("failed to retrieve existing network-node-identity daemonset: %w", err)
-			} else {
-				klog.Infof("network-node-identity daemonset does not exist")
-			}
-		} else {
-			webhookReady = !daemonSetProgressing(webhookDaemonSet, false)
-		}
+		webhookWaitFor = fmt.Sprintf("DaemonSet/%s/network-node-identity", NetworkNodeIdentityNamespace)
 
 		manifestDirs = append(manifestDirs, filepath.Join(manifestDir, "network/node-identity/self-hosted"))
 	}
@@ -191,23 +162,32 @@ func renderNetworkNodeIdentity(conf *operv1.NetworkSpec, bootstrapResult *bootst
 		klog.Infof("network-node-identity webhook will not be applied, CA bundle not found")
 	}
 
-	// This is useful only when upgrading from a version that didn't enable the webhook
-	// because marking an existing webhook config with CreateWaitAnnotation won't remove it
-	if !webhookReady {
-		applyWebhook = false
-		klog.Infof("network-node-identity webhook will not be applied, the deployment/daemonset is not ready")
-	}
-
-	if !applyWebhook {
-		klog.Infof("network-node-identity webhook will not be applied, if it already exists it won't be removed")
-		k8s.UpdateObjByGroupKindName(manifests, "admissionregistration.k8s.io", "ValidatingWebhookConfiguration", "", "network-node-identity.openshift.io", func(o *uns.Unstructured) {
-			anno := o.GetAnnotations()
+	// The webhook is only applied once the workload serving it is ready, so
+	// that it does not reject the node and pod updates while the workload is
+	// missing or rolling out. It is moved after the workload, as wait-for also
+	// postpones the objects rendered after it. An existing webhook is kept, with
+	// create-wait, while it cannot be rendered yet, as it would otherwise be
+	// deleted for not being rendered.
+	out := make([]*uns.Unstructured, 0, len(manifests))
+	webhooks := []*uns.Unstructured{}
+	for _, obj := range manifests {
+		if obj.GroupVersionKind().GroupKind() == (schema.GroupKind{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}) &&
+			obj.GetName() == "network-node-identity.openshift.io" {
+			anno := obj.GetAnnotations()
 			if anno == nil {
 				anno = map[string]string{}
 			}
-			anno[names.CreateWaitAnnotation] = "true"
-			o.SetAnnotations(anno)
-		})
+			if applyWebhook {
+				anno[names.WaitForAnnotation] = webhookWaitFor
+			} else {
+				klog.Infof("network-node-identity webhook will not be applied, if it already exists it won't be removed")
+				anno[names.CreateWaitAnnotation] = "true"
+			}
+			obj.SetAnnotations(anno)
+			webhooks = append(webhooks, obj)
+			continue
+		}
+		out = append(out, obj)
 	}
-	return manifests, nil
+	return append(out, webhooks...), nil
 }
@@ -0,0 +1,77 @@
+package network
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	cnofake "github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testCABundle returns the PEM of a self-signed CA certificate
+func testCABundle(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "network-node-identity"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// TestRenderNetworkNodeIdentityWebhook checks that the webhook is rendered last,
+// waiting for the DaemonSet that serves it, and only once it can be rendered
+func TestRenderNetworkNodeIdentityWebhook(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := OVNKubernetesConfig.DeepCopy()
+	config := &crd.Spec
+	fillDefaults(config, nil)
+	bootstrapResult := fakeBootstrapResult()
+	bootstrapResult.Infra.NetworkNodeIdentityEnabled = true
+
+	bootstrapConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: CLUSTER_CONFIG_NAMESPACE, Name: "bootstrap"},
+		Data:       map[string]string{"status": "complete"},
+	}
+	ca := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: NetworkNodeIdentityNamespace, Name: "network-node-identity-ca"},
+		Data:       map[string]string{"ca-bundle.crt": testCABundle(t)},
+	}
+
+	objs, err := renderNetworkNodeIdentity(config, bootstrapResult, manifestDir, cnofake.NewFakeClient(bootstrapConfig, ca))
+	g.Expect(err).NotTo(HaveOccurred())
+	webhook := objs[len(objs)-1]
+	g.Expect(webhook).To(HaveKubernetesID("ValidatingWebhookConfiguration", "", "network-node-identity.openshift.io"))
+	g.Expect(webhook.GetAnnotations()).To(HaveKeyWithValue(names.WaitForAnnotation, "DaemonSet/openshift-network-node-identity/network-node-identity"))
+	g.Expect(webhook.GetAnnotations()).NotTo(HaveKey(names.CreateWaitAnnotation))
+	g.Expect(objs).To(ContainElement(HaveKubernetesID("DaemonSet", NetworkNodeIdentityNamespace, "network-node-identity")))
+
+	// Without its CA, the webhook is not applied, but an existing one is kept
+	objs, err = renderNetworkNodeIdentity(config, bootstrapResult, manifestDir, cnofake.NewFakeClient(bootstrapConfig))
+	g.Expect(err).NotTo(HaveOccurred())
+	webhook = objs[len(objs)-1]
+	g.Expect(webhook).To(HaveKubernetesID("ValidatingWebhookConfiguration", "", "network-node-identity.openshift.io"))
+	g.Expect(webhook.GetAnnotations()).To(HaveKey(names.CreateWaitAnnotation))
+}
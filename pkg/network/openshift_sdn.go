@@ -19,6 +19,7 @@ import (
 	netv1 "github.com/openshift/api/network/v1"
 	operv1 "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-network-operator/pkg/render"
+	k8sutil "github.com/openshift/cluster-network-operator/pkg/util/k8s"
 )
 
 // renderOpenShiftSDN returns the manifests for the openshift-sdn.
@@ -165,6 +166,11 @@ func validateOpenShiftSDN(conf *operv1.NetworkSpec) []error {
 		}
 	}
 
+	// the service proxy embedded in openshift-sdn has no nftables support
+	if kubeProxyMode(conf) == string(k8sutil.ProxyModeNFTables) {
+		out = append(out, errors.Errorf("invalid proxy-mode - openshift-sdn does not support proxy-mode \"nftables\""))
+	}
+
 	if conf.DeployKubeProxy != nil && *conf.DeployKubeProxy {
 		// We allow deploying an external kube-proxy with openshift-sdn in very
 		// limited circumstances, for testing purposes. The error here
@@ -191,8 +197,15 @@ func isOpenShiftSDNChangeSafe(prev, next *operv1.NetworkSpec) []error {
 		return errs
 	}
 
+	// The only mode change supported is the migration from Multitenant to
+	// NetworkPolicy, which the operator drives by first generating equivalent
+	// NetworkPolicies.
 	if pn.Mode != nn.Mode {
-		errs = append(errs, errors.Errorf("cannot change openshift-sdn mode"))
+		if pn.Mode != operv1.SDNModeMultitenant || nn.Mode != operv1.SDNModeNetworkPolicy {
+			errs = append(errs, errors.Errorf("cannot change openshift-sdn mode"))
+		} else if next.Migration != nil && next.Migration.NetworkType != "" {
+			errs = append(errs, errors.Errorf("cannot change openshift-sdn mode during a network type migration"))
+		}
 	}
 
 	// deepequal is nil-safe
@@ -317,6 +317,18 @@ func TestOpenShiftSDNIsSafe(t *testing.T) {
 	g.Expect(errs[1]).To(MatchError("cannot change openshift-sdn vxlanPort"))
 	g.Expect(errs[2]).To(MatchError("cannot change openshift-sdn mtu without migration"))
 
+	// migrating from Multitenant to NetworkPolicy is allowed, but not during
+	// a network type migration
+	prev.DefaultNetwork.OpenShiftSDNConfig.Mode = operv1.SDNModeMultitenant
+	next = prev.DeepCopy()
+	next.DefaultNetwork.OpenShiftSDNConfig.Mode = operv1.SDNModeNetworkPolicy
+	errs = isOpenShiftSDNChangeSafe(prev, next)
+	g.Expect(errs).To(BeEmpty())
+	next.Migration = &operv1.NetworkMigration{NetworkType: string(operv1.NetworkTypeOVNKubernetes)}
+	errs = isOpenShiftSDNChangeSafe(prev, next)
+	g.Expect(errs).To(ContainElement(MatchError("cannot change openshift-sdn mode during a network type migration")))
+	prev.DefaultNetwork.OpenShiftSDNConfig.Mode = operv1.SDNModeNetworkPolicy
+
 	next = prev.DeepCopy()
 	// mtu migration
 
@@ -117,7 +117,8 @@ func Render(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.BootstrapResult
 	}
 	objs = append(objs, o...)
 
-	// render
+	// render network node identity last, as its webhook waits for the workload
+	// serving it, and so postpones every object rendered after it
 	o, err = renderNetworkNodeIdentity(conf, bootstrapResult, manifestDir, client)
 	if err != nil {
 		return nil, progressing, err
@@ -546,7 +547,8 @@ func validateMultus(conf *operv1.NetworkSpec) []error {
 	if !deployMultus && len(conf.AdditionalNetworks) > 0 {
 		return []error{errors.Errorf("additional networks cannot be specified without deploying Multus")}
 	}
-	return []error{}
+	errs := validateAdditionalNetworks(conf)
+	return append(errs, validateMultusAdmissionController(conf)...)
 }
 
 // validateDefaultNetwork validates whichever network is specified
@@ -687,10 +689,17 @@ func validateAdditionalNetworks(conf *operv1.NetworkSpec) []error {
 			if errs := validateRaw(&an); len(errs) > 0 {
 				out = append(out, errs...)
 			}
+			if errs := validateRawCNIConfig(&an, conf); len(errs) > 0 {
+				out = append(out, errs...)
+			}
 		case operv1.NetworkTypeSimpleMacvlan:
 			if errs := validateSimpleMacvlanConfig(&an); len(errs) > 0 {
 				out = append(out, errs...)
 			}
+		case NetworkTypeIPVLAN, NetworkTypeHostDevice, NetworkTypeBridge, NetworkTypeBond, NetworkTypeVLAN:
+			if errs := validateTypedConfig(&an); len(errs) > 0 {
+				out = append(out, errs...)
+			}
 		default:
 			out = append(out, errors.Errorf("unknown or unsupported NetworkType: %s", an.Type))
 		}
@@ -727,6 +736,12 @@ func renderAdditionalNetworks(conf *operv1.NetworkSpec, manifestDir string) ([]*
 				return nil, err
 			}
 			out = append(out, objs...)
+		case NetworkTypeIPVLAN, NetworkTypeHostDevice, NetworkTypeBridge, NetworkTypeBond, NetworkTypeVLAN:
+			objs, err := renderTypedConfig(&an, manifestDir)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, objs...)
 		default:
 			return nil, errors.Errorf("unknown or unsupported NetworkType: %s", an.Type)
 		}
@@ -757,7 +772,7 @@ func renderMultusAdmissionController(conf *operv1.NetworkSpec, manifestDir strin
 	var err error
 	out := []*uns.Unstructured{}
 
-	objs, err := renderMultusAdmissonControllerConfig(manifestDir, externalControlPlane,
+	objs, err := renderMultusAdmissonControllerConfig(conf, manifestDir, externalControlPlane,
 		bootstrapResult, client)
 	if err != nil {
 		return nil, err
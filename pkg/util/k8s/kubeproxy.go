@@ -12,10 +12,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/sets"
 	cliflag "k8s.io/component-base/cli/flag"
 	kubeproxyconfig "k8s.io/kube-proxy/config/v1alpha1"
 )
 
+// ProxyModeNFTables is the nftables proxy mode, which the vendored
+// kubeproxyconfig does not know about yet.
+const ProxyModeNFTables kubeproxyconfig.ProxyMode = "nftables"
+
 // MergeKubeProxyArguments merges a set of default kube-proxy command-line arguments with
 // a set of overrides, keeping only the last-specified copy of each argument.
 func MergeKubeProxyArguments(defaults, overrides map[string]operv1.ProxyArgumentList) map[string]operv1.ProxyArgumentList {
@@ -33,16 +38,57 @@ func MergeKubeProxyArguments(defaults, overrides map[string]operv1.ProxyArgument
 	return args
 }
 
+// kubeProxyConfiguration is the KubeProxyConfiguration of the vendored
+// kube-proxy, plus the nftables settings of the kube-proxy we ship, which
+// supports the nftables proxy mode.
+type kubeProxyConfiguration struct {
+	kubeproxyconfig.KubeProxyConfiguration `json:",inline"`
+
+	NFTables *kubeProxyNFTablesConfiguration `json:"nftables,omitempty"`
+}
+
+// kubeProxyNFTablesConfiguration contains the nftables-related configuration
+// of kube-proxy.
+type kubeProxyNFTablesConfiguration struct {
+	MasqueradeBit *int32          `json:"masqueradeBit"`
+	MasqueradeAll bool            `json:"masqueradeAll"`
+	SyncPeriod    metav1.Duration `json:"syncPeriod"`
+	MinSyncPeriod metav1.Duration `json:"minSyncPeriod"`
+}
+
 // GenerateKubeProxyConfiguration takes a set of defaults and a set of overrides in the
 // form of kube-proxy command-line arguments, and returns a YAML kube-proxy config file.
+//
+// Arguments that do not map to a configuration option are reported in an
+// error; see UnusedKubeProxyArguments.
 func GenerateKubeProxyConfiguration(args map[string]operv1.ProxyArgumentList) (string, error) {
 	// We use MergeKubeProxyArguments here to force a copy
-	ka := &kpcArgs{args: MergeKubeProxyArguments(args, nil)}
+	ka := newKpcArgs(MergeKubeProxyArguments(args, nil))
+	kpc := ka.build()
+	if err := ka.getError(); err != nil {
+		return "", err
+	}
 
-	kpc := &kubeproxyconfig.KubeProxyConfiguration{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: "kubeproxy.config.k8s.io/v1alpha1",
-			Kind:       "KubeProxyConfiguration",
+	buf, err := yaml.Marshal(kpc)
+	return string(buf), err
+}
+
+// UnusedKubeProxyArguments returns the arguments that GenerateKubeProxyConfiguration
+// would not consume, and the arguments it knows about, both sorted.
+func UnusedKubeProxyArguments(args map[string]operv1.ProxyArgumentList) (unused, known []string) {
+	ka := newKpcArgs(MergeKubeProxyArguments(args, nil))
+	ka.build()
+	return sets.List(sets.KeySet(ka.args)), sets.List(ka.known)
+}
+
+// build builds the KubeProxyConfiguration, consuming the arguments
+func (ka *kpcArgs) build() *kubeProxyConfiguration {
+	kpc := &kubeProxyConfiguration{
+		KubeProxyConfiguration: kubeproxyconfig.KubeProxyConfiguration{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "kubeproxy.config.k8s.io/v1alpha1",
+				Kind:       "KubeProxyConfiguration",
+			},
 		},
 	}
 
@@ -72,7 +118,17 @@ func GenerateKubeProxyConfiguration(args map[string]operv1.ProxyArgumentList) (s
 	kpc.IPVS.TCPFinTimeout.Duration = ka.getDuration("ipvs-tcp-fin-timeout")
 	kpc.IPVS.UDPTimeout.Duration = ka.getDuration("ipvs-udp-timeout")
 
+	nft := &kubeProxyNFTablesConfiguration{
+		MasqueradeBit: ka.getOptInt32("nftables-masquerade-bit"),
+		MasqueradeAll: kpc.IPTables.MasqueradeAll,
+	}
+	nft.SyncPeriod.Duration = ka.getDuration("nftables-sync-period")
+	nft.MinSyncPeriod.Duration = ka.getDuration("nftables-min-sync-period")
+
 	kpc.Mode = kubeproxyconfig.ProxyMode(ka.getString("proxy-mode"))
+	if kpc.Mode == ProxyModeNFTables || nft.MasqueradeBit != nil || nft.SyncPeriod.Duration != 0 || nft.MinSyncPeriod.Duration != 0 {
+		kpc.NFTables = nft
+	}
 
 	kpc.PortRange = ka.getPortRange("proxy-port-range")
 
@@ -98,12 +154,7 @@ func GenerateKubeProxyConfiguration(args map[string]operv1.ProxyArgumentList) (s
 	kpc.DetectLocal.BridgeInterface = ka.getString("pod-bridge-interface")
 	kpc.DetectLocal.InterfaceNamePrefix = ka.getString("pod-interface-name-prefix")
 
-	if err := ka.getError(); err != nil {
-		return "", err
-	}
-
-	buf, err := yaml.Marshal(kpc)
-	return string(buf), err
+	return kpc
 }
 
 // kpcArgs is a helper to build the KubeProxyConfiguration. In particular, it
@@ -111,26 +162,26 @@ func GenerateKubeProxyConfiguration(args map[string]operv1.ProxyArgumentList) (s
 type kpcArgs struct {
 	args map[string]operv1.ProxyArgumentList
 	errs []error
+	// known are the names of all the arguments that were looked up
+	known sets.Set[string]
+}
+
+func newKpcArgs(args map[string]operv1.ProxyArgumentList) *kpcArgs {
+	return &kpcArgs{args: args, known: sets.New[string]()}
 }
 
 func (ka *kpcArgs) getError() error {
 	if len(ka.errs) != 0 {
 		return utilerrors.NewAggregate(ka.errs)
 	} else if len(ka.args) != 0 {
-		unused := ""
-		for key := range ka.args {
-			if len(unused) > 0 {
-				unused += ", "
-			}
-			unused += key
-		}
-		return fmt.Errorf("unused arguments: %s", unused)
+		return fmt.Errorf("unused arguments: %s", strings.Join(sets.List(sets.KeySet(ka.args)), ", "))
 	} else {
 		return nil
 	}
 }
 
 func (ka *kpcArgs) get(argName string) string {
+	ka.known.Insert(argName)
 	val := ka.args[argName]
 	if len(val) == 0 {
 		return ""
@@ -289,6 +340,7 @@ func (ka *kpcArgs) getPortRange(key string) string {
 
 // getFeatureGates parses feature-gates and returns a map[string]bool
 func (ka *kpcArgs) getFeatureGates(key string) map[string]bool {
+	ka.known.Insert(key)
 	val := ka.args[key]
 	if len(val) == 0 {
 		return nil
@@ -346,6 +346,80 @@ nodePortAddresses: null
 oomScoreAdj: null
 portRange: 1000+10
 showHiddenMetricsForVersion: ""
+winkernel:
+  enableDSR: false
+  forwardHealthCheckVip: false
+  networkName: ""
+  rootHnsEndpointName: ""
+  sourceVip: ""
+`,
+		},
+		{
+			description: "nftables",
+			overrides: map[string]operv1.ProxyArgumentList{
+				"proxy-mode":               {"nftables"},
+				"masquerade-all":           {"true"},
+				"nftables-masquerade-bit":  {"14"},
+				"nftables-sync-period":     {"1m"},
+				"nftables-min-sync-period": {"5s"},
+			},
+			output: `
+apiVersion: kubeproxy.config.k8s.io/v1alpha1
+bindAddress: 0.0.0.0
+bindAddressHardFail: false
+clientConnection:
+  acceptContentTypes: ""
+  burst: 0
+  contentType: ""
+  kubeconfig: ""
+  qps: 0
+clusterCIDR: ""
+configSyncPeriod: 0s
+conntrack:
+  maxPerCore: null
+  min: null
+  tcpCloseWaitTimeout: null
+  tcpEstablishedTimeout: null
+detectLocal:
+  bridgeInterface: ""
+  interfaceNamePrefix: ""
+detectLocalMode: ""
+enableProfiling: false
+healthzBindAddress: ""
+hostnameOverride: ""
+iptables:
+  localhostNodePorts: null
+  masqueradeAll: true
+  masqueradeBit: 0
+  minSyncPeriod: 0s
+  syncPeriod: 0s
+ipvs:
+  excludeCIDRs: null
+  minSyncPeriod: 0s
+  scheduler: ""
+  strictARP: false
+  syncPeriod: 0s
+  tcpFinTimeout: 0s
+  tcpTimeout: 0s
+  udpTimeout: 0s
+kind: KubeProxyConfiguration
+logging:
+  flushFrequency: 0
+  options:
+    json:
+      infoBufferSize: "0"
+  verbosity: 0
+metricsBindAddress: 0.0.0.0:9102
+mode: nftables
+nftables:
+  masqueradeAll: true
+  masqueradeBit: 14
+  minSyncPeriod: 5s
+  syncPeriod: 1m0s
+nodePortAddresses: null
+oomScoreAdj: null
+portRange: ""
+showHiddenMetricsForVersion: ""
 winkernel:
   enableDSR: false
   forwardHealthCheckVip: false
@@ -456,3 +530,24 @@ winkernel:
 		}
 	}
 }
+
+func TestUnusedKubeProxyArguments(t *testing.T) {
+	unused, known := UnusedKubeProxyArguments(map[string]operv1.ProxyArgumentList{
+		"iptables-sync-period": {"30s"},
+		"iptable-sync-period":  {"30s"},
+		"foo":                  {"bar"},
+		"empty":                {},
+	})
+	if strings.Join(unused, ",") != "foo,iptable-sync-period" {
+		t.Fatalf("unexpected unused arguments: %v", unused)
+	}
+	for _, arg := range []string{"iptables-sync-period", "feature-gates", "proxy-mode", "nftables-sync-period"} {
+		found := false
+		for _, k := range known {
+			found = found || k == arg
+		}
+		if !found {
+			t.Fatalf("expected %q to be a known argument, got: %v", arg, known)
+		}
+	}
+}
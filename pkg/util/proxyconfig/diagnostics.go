@@ -0,0 +1,285 @@
+package proxyconfig
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+// proxyAuthCheckTimeout bounds the connection to the proxy that checks its credentials
+const proxyAuthCheckTimeout = 10 * time.Second
+
+// ProxyDiagnosis explains how a request to a destination goes through the cluster proxy
+type ProxyDiagnosis struct {
+	// Destination is the URL of the request
+	Destination string `json:"destination"`
+	// NoProxy is the merged noProxy list of the cluster
+	NoProxy []string `json:"noProxy"`
+	// BypassedBy is the reason the request does not use a proxy: the noProxy entry
+	// that matches the destination, or why no proxy applies. It is empty when the
+	// request goes through a proxy.
+	BypassedBy string `json:"bypassedBy,omitempty"`
+	// ProxyField is the field of the Proxy config, httpProxy or httpsProxy, that
+	// the request goes through
+	ProxyField string `json:"proxyField,omitempty"`
+	// Proxy is the URL of that proxy, without its password
+	Proxy string `json:"proxy,omitempty"`
+	// Auth is the result of a request through the proxy
+	Auth *ProxyAuthResult `json:"auth,omitempty"`
+}
+
+// ProxyAuthResult is the answer of a proxy to a request through it
+type ProxyAuthResult struct {
+	// Credentials tells whether the proxy URL holds credentials
+	Credentials bool `json:"credentials"`
+	// Status is the status line of the answer of the proxy
+	Status string `json:"status,omitempty"`
+	// Succeeded tells whether the proxy accepted the credentials, or did not ask for any
+	Succeeded bool `json:"succeeded"`
+	// Error is why the proxy could not be asked
+	Error string `json:"error,omitempty"`
+}
+
+// parseDestination accepts a URL or a host, which is then reached with https
+func parseDestination(destination string) (*url.URL, error) {
+	if !strings.Contains(destination, "://") {
+		destination = "https://" + destination
+	}
+	u, err := url.Parse(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination %q: %v", destination, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid destination %q, the scheme must be http or https", destination)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid destination %q, it has no host", destination)
+	}
+	return u, nil
+}
+
+// destinationPort returns the port of a destination, or the default one of its scheme
+func destinationPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if u.Scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
+
+// MatchNoProxy returns the entry of a noProxy list that makes the requests to a
+// destination bypass the proxy, or "" if none does. It follows the rules of the Go
+// HTTP clients of the cluster components: "*" matches everything, a CIDR matches the
+// IPs it contains, a domain matches itself and its subdomains, a domain with a leading
+// "." only matches its subdomains, and an IP or a domain with a port only matches
+// that port. The destinations are not resolved, so a CIDR never matches a name.
+func MatchNoProxy(noProxy []string, destination *url.URL) string {
+	host := strings.ToLower(destination.Hostname())
+	port := destinationPort(destination)
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return entry
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return entry
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && ip.Equal(entryIP) {
+				return entry
+			}
+			continue
+		}
+		if strings.HasPrefix(entryHost, "*.") {
+			entryHost = entryHost[1:]
+		}
+		if strings.HasPrefix(entryHost, ".") {
+			if strings.HasSuffix(host, entryHost) {
+				return entry
+			}
+			continue
+		}
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return entry
+		}
+	}
+	return ""
+}
+
+// DiagnoseProxy explains whether a request to a destination, a URL or a host, bypasses
+// the proxy, and which proxy it goes through otherwise. When it goes through a proxy
+// and checkAuth is set, a request is sent through the proxy to check that the proxy
+// accepts the credentials of its URL. tlsConfig is used to connect to an https proxy.
+func DiagnoseProxy(ctx context.Context, proxy *configv1.ProxySpec, noProxy string, destination string, checkAuth bool, tlsConfig *tls.Config) (*ProxyDiagnosis, error) {
+	u, err := parseDestination(destination)
+	if err != nil {
+		return nil, err
+	}
+	diagnosis := &ProxyDiagnosis{Destination: u.String(), NoProxy: []string{}}
+	for _, entry := range strings.Split(noProxy, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			diagnosis.NoProxy = append(diagnosis.NoProxy, entry)
+		}
+	}
+
+	// The Go HTTP clients never proxy the requests to the local host
+	if host := u.Hostname(); host == "localhost" || net.ParseIP(host).IsLoopback() {
+		diagnosis.BypassedBy = "the local host is never proxied"
+		return diagnosis, nil
+	}
+	if entry := MatchNoProxy(diagnosis.NoProxy, u); entry != "" {
+		diagnosis.BypassedBy = fmt.Sprintf("noProxy entry %q", entry)
+		return diagnosis, nil
+	}
+
+	proxyValue := proxy.HTTPProxy
+	diagnosis.ProxyField = "httpProxy"
+	if u.Scheme == "https" {
+		proxyValue = proxy.HTTPSProxy
+		diagnosis.ProxyField = "httpsProxy"
+	}
+	if proxyValue == "" {
+		diagnosis.BypassedBy = fmt.Sprintf("%s is not set", diagnosis.ProxyField)
+		diagnosis.ProxyField = ""
+		return diagnosis, nil
+	}
+	proxyURL, err := url.Parse(proxyValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", diagnosis.ProxyField, err)
+	}
+	diagnosis.Proxy = proxyURL.Redacted()
+
+	if checkAuth {
+		diagnosis.Auth = checkProxyAuth(ctx, proxyURL, u, tlsConfig)
+	}
+	return diagnosis, nil
+}
+
+// checkProxyAuth asks the proxy to CONNECT to an https destination, or sends a HEAD
+// request for an http destination through it, with the credentials of its URL. A
+// proxy that rejects the credentials answers "407 Proxy Authentication Required".
+func checkProxyAuth(ctx context.Context, proxyURL, destination *url.URL, tlsConfig *tls.Config) *ProxyAuthResult {
+	result := &ProxyAuthResult{Credentials: proxyURL.User != nil}
+	fail := func(err error) *ProxyAuthResult {
+		result.Error = err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, proxyAuthCheckTimeout)
+	defer cancel()
+	proxyAddress := net.JoinHostPort(proxyURL.Hostname(), destinationPort(proxyURL))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return fail(fmt.Errorf("could not connect to the proxy: %v", err))
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if proxyURL.Scheme == "https" {
+		config := &tls.Config{}
+		if tlsConfig != nil {
+			config = tlsConfig.Clone()
+		}
+		config.ServerName = proxyURL.Hostname()
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fail(fmt.Errorf("TLS handshake with the proxy failed: %v", err))
+		}
+		conn = tlsConn
+	}
+
+	var req *http.Request
+	if destination.Scheme == "https" {
+		hostPort := net.JoinHostPort(destination.Hostname(), destinationPort(destination))
+		req = &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: hostPort},
+			Host:   hostPort,
+			Header: http.Header{},
+		}
+	} else {
+		req, err = http.NewRequest(http.MethodHead, destination.String(), nil)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if destination.Scheme == "https" {
+		err = req.Write(conn)
+	} else {
+		err = req.WriteProxy(conn)
+	}
+	if err != nil {
+		return fail(fmt.Errorf("could not send the request to the proxy: %v", err))
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return fail(fmt.Errorf("could not read the answer of the proxy: %v", err))
+	}
+	resp.Body.Close()
+	result.Status = resp.Status
+	result.Succeeded = resp.StatusCode != http.StatusProxyAuthRequired
+	return result
+}
+
+// String renders a diagnosis for a person
+func (d *ProxyDiagnosis) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Destination: %s\n", d.Destination)
+	fmt.Fprintf(&b, "noProxy: %s\n", strings.Join(d.NoProxy, ","))
+	if d.BypassedBy != "" {
+		fmt.Fprintf(&b, "Proxy: none, bypassed by %s\n", d.BypassedBy)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Proxy: %s (%s)\n", d.Proxy, d.ProxyField)
+	if d.Auth == nil {
+		return b.String()
+	}
+	switch {
+	case d.Auth.Error != "":
+		fmt.Fprintf(&b, "Authentication: unknown, %s\n", d.Auth.Error)
+	case !d.Auth.Succeeded && d.Auth.Credentials:
+		fmt.Fprintf(&b, "Authentication: rejected, the proxy answered %q\n", d.Auth.Status)
+	case !d.Auth.Succeeded:
+		fmt.Fprintf(&b, "Authentication: missing, the proxy requires credentials in the %s URL\n", d.ProxyField)
+	case d.Auth.Credentials:
+		fmt.Fprintf(&b, "Authentication: accepted, the proxy answered %q\n", d.Auth.Status)
+	default:
+		fmt.Fprintf(&b, "Authentication: not required, the proxy answered %q\n", d.Auth.Status)
+	}
+	return b.String()
+}
@@ -16,6 +16,93 @@ import (
 
 const defaultCIDR = "0.0.0.0/0"
 
+// platformMetadataEndpoints are the metadata services of the platforms, which
+// the nodes reach over the network. The other platforms have none, or serve the
+// metadata from a config drive.
+var platformMetadataEndpoints = map[configv1.PlatformType][]string{
+	configv1.AWSPlatformType:   {"169.254.169.254"},
+	configv1.AzurePlatformType: {"169.254.169.254"},
+	// From https://cloud.google.com/vpc/docs/special-configurations add GCP metadata.
+	// "metadata.google.internal." added due to https://bugzilla.redhat.com/show_bug.cgi?id=1754049
+	configv1.GCPPlatformType:          {"169.254.169.254", "metadata", "metadata.google.internal", "metadata.google.internal."},
+	configv1.OpenStackPlatformType:    {"169.254.169.254"},
+	configv1.IBMCloudPlatformType:     {"169.254.169.254", "api.metadata.cloud.ibm.com"},
+	configv1.AlibabaCloudPlatformType: {"100.100.100.200"},
+	configv1.EquinixMetalPlatformType: {"metadata.platformequinix.com"},
+}
+
+// NoProxyEntry is an entry of the merged noProxy list, with the sources that
+// contributed it.
+type NoProxyEntry struct {
+	Value   string   `json:"value"`
+	Sources []string `json:"sources"`
+}
+
+// NoProxyExplanation explains where each entry of the merged noProxy list
+// comes from.
+type NoProxyExplanation struct {
+	Entries []NoProxyEntry `json:"entries"`
+	// Warnings are the conflicts found while merging, such as user entries that
+	// the cluster already adds.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// NoProxy returns the merged, comma-separated noProxy list
+func (e *NoProxyExplanation) NoProxy() string {
+	values := make([]string, len(e.Entries))
+	for i, entry := range e.Entries {
+		values[i] = entry.Value
+	}
+	return strings.Join(values, ",")
+}
+
+// AcceptedNoProxy returns the merged noProxy list of the status of the proxy,
+// and whether the proxy controller accepted the spec of the proxy into it. The
+// explanation is the one the controller recorded along with the status, and
+// tells which user entries the status was merged from.
+func AcceptedNoProxy(proxy *configv1.Proxy, explanation *NoProxyExplanation) (string, bool) {
+	if explanation == nil || proxy.Status.HTTPProxy != proxy.Spec.HTTPProxy || proxy.Status.HTTPSProxy != proxy.Spec.HTTPSProxy {
+		return "", false
+	}
+	userValues := sets.New[string]()
+	for _, userValue := range strings.Split(proxy.Spec.NoProxy, ",") {
+		if userValue != "" {
+			userValues.Insert(userValue)
+		}
+	}
+	acceptedValues := sets.New[string]()
+	for _, entry := range explanation.Entries {
+		for _, source := range entry.Sources {
+			if source == "proxy noProxy" {
+				acceptedValues.Insert(entry.Value)
+			}
+		}
+	}
+	if !userValues.Equal(acceptedValues) || explanation.NoProxy() != proxy.Status.NoProxy {
+		return "", false
+	}
+	return proxy.Status.NoProxy, true
+}
+
+// noProxySet collects the noProxy entries with their sources
+type noProxySet map[string][]string
+
+func (s noProxySet) insert(source string, values ...string) {
+	for _, value := range values {
+		if value != "" {
+			s[value] = append(s[value], source)
+		}
+	}
+}
+
+func (s noProxySet) explanation(warnings []string) *NoProxyExplanation {
+	e := &NoProxyExplanation{Entries: []NoProxyEntry{}, Warnings: warnings}
+	for _, value := range sets.StringKeySet(s).List() {
+		e.Entries = append(e.Entries, NoProxyEntry{Value: value, Sources: s[value]})
+	}
+	return e
+}
+
 // MergeUserSystemNoProxy merges user supplied noProxy settings from proxy
 // with cluster-wide noProxy settings. It returns a merged, comma-separated
 // string of noProxy settings. If no user supplied noProxy settings are
@@ -26,55 +113,172 @@ func MergeUserSystemNoProxy(proxy *configv1.Proxy, infra *configv1.Infrastructur
 }
 
 func mergeUserSystemNoProxy(proxy *configv1.Proxy, infra *configv1.Infrastructure, network *configv1.Network, cluster *corev1.ConfigMap, getEnv func(string) string) (string, error) {
-	// TODO: This will be flexible when master machine management is more dynamic.
+	explanation, err := explainNoProxy(proxy, infra, network, cluster, nil, getEnv)
+	if err != nil {
+		return "", err
+	}
+	return explanation.NoProxy(), nil
+}
+
+// MachineNetworks are the machine networks of a cluster that has no
+// install-config, and the source they were read from.
+type MachineNetworks struct {
+	Source string
+	CIDRs  []string
+}
+
+// ExplainNoProxy merges the noProxy settings like MergeUserSystemNoProxy, and
+// explains which source contributed each entry. cluster is the install-config
+// ConfigMap, which may be nil on clusters that were not installed with one.
+// machineNetworks are then used instead of the machine networks of the
+// install-config, and may be nil if they could not be found either.
+func ExplainNoProxy(proxy *configv1.Proxy, infra *configv1.Infrastructure, network *configv1.Network, cluster *corev1.ConfigMap, machineNetworks *MachineNetworks) (*NoProxyExplanation, error) {
+	return explainNoProxy(proxy, infra, network, cluster, machineNetworks, os.Getenv)
+}
+
+// installConfigMachineNetworks returns the machine networks of the install-config
+// in cluster, and false if there is no install-config.
+func installConfigMachineNetworks(cluster *corev1.ConfigMap) (noProxySet, bool, error) {
 	type machineNetworkEntry struct {
 		// CIDR is the IP block address pool for machines within the cluster.
 		CIDR string `json:"cidr"`
 	}
 	type installConfig struct {
-		ControlPlane struct {
-			Replicas string `json:"replicas"`
-		} `json:"controlPlane"`
 		Networking struct {
 			MachineCIDR    string                `json:"machineCIDR"`
 			MachineNetwork []machineNetworkEntry `json:"machineNetwork,omitempty"`
 		} `json:"networking"`
 	}
 
-	var ic installConfig
+	if cluster == nil {
+		return nil, false, nil
+	}
 	data, ok := cluster.Data["install-config"]
 	if !ok {
-		return "", fmt.Errorf("missing install-config in configmap")
+		return nil, false, nil
 	}
+	var ic installConfig
 	if err := yaml.Unmarshal([]byte(data), &ic); err != nil {
-		return "", fmt.Errorf("invalid install-config: %v\njson:\n%s", err, data)
-	}
-
-	set := sets.NewString(
-		"127.0.0.1",
-		"localhost",
-		".svc",
-		".cluster.local",
-	)
-	if hcpCfg := hypershift.NewHyperShiftConfig(); hcpCfg.Enabled {
-		set.Insert(".hypershift.local")
+		return nil, false, fmt.Errorf("invalid install-config: %v\njson:\n%s", err, data)
 	}
 
+	set := noProxySet{}
 	if ic.Networking.MachineCIDR != "" && ic.Networking.MachineCIDR != defaultCIDR {
 		if _, _, err := net.ParseCIDR(ic.Networking.MachineCIDR); err != nil {
-			return "", fmt.Errorf("MachineCIDR has an invalid CIDR: %s", ic.Networking.MachineCIDR)
+			return nil, false, fmt.Errorf("MachineCIDR has an invalid CIDR: %s", ic.Networking.MachineCIDR)
 		}
-		set.Insert(ic.Networking.MachineCIDR)
+		set.insert("install-config machineCIDR", ic.Networking.MachineCIDR)
 	}
-
 	for _, mc := range ic.Networking.MachineNetwork {
 		if mc.CIDR == defaultCIDR {
 			continue
 		}
 		if _, _, err := net.ParseCIDR(mc.CIDR); err != nil {
-			return "", fmt.Errorf("MachineNetwork has an invalid CIDR: %s", mc.CIDR)
+			return nil, false, fmt.Errorf("MachineNetwork has an invalid CIDR: %s", mc.CIDR)
+		}
+		set.insert("install-config machineNetwork", mc.CIDR)
+	}
+	return set, true, nil
+}
+
+// fallbackMachineNetworkSet returns the machine networks found without an
+// install-config.
+func fallbackMachineNetworkSet(machineNetworks *MachineNetworks) (noProxySet, error) {
+	set := noProxySet{}
+	if machineNetworks == nil {
+		return set, nil
+	}
+	for _, cidr := range machineNetworks.CIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("%s has an invalid CIDR: %s", machineNetworks.Source, cidr)
+		}
+		if len(set[cidr]) == 0 {
+			set.insert(machineNetworks.Source, cidr)
+		}
+	}
+	return set, nil
+}
+
+// withSingular appends the deprecated singular field of a VIP to the list of VIPs
+func withSingular(vips []string, vip string) []string {
+	return append(append([]string{}, vips...), vip)
+}
+
+// infrastructureVIPs returns the internal API and ingress VIPs of the platforms
+// that have them. The VIPs are in the machine network, which the Infrastructure
+// does not describe otherwise.
+func infrastructureVIPs(infra *configv1.Infrastructure) noProxySet {
+	set := noProxySet{}
+	ps := infra.Status.PlatformStatus
+	if ps == nil {
+		return set
+	}
+	var apiVIPs, ingressVIPs []string
+	switch {
+	case ps.BareMetal != nil:
+		apiVIPs = withSingular(ps.BareMetal.APIServerInternalIPs, ps.BareMetal.APIServerInternalIP)
+		ingressVIPs = withSingular(ps.BareMetal.IngressIPs, ps.BareMetal.IngressIP)
+	case ps.OpenStack != nil:
+		apiVIPs = withSingular(ps.OpenStack.APIServerInternalIPs, ps.OpenStack.APIServerInternalIP)
+		ingressVIPs = withSingular(ps.OpenStack.IngressIPs, ps.OpenStack.IngressIP)
+	case ps.Ovirt != nil:
+		apiVIPs = withSingular(ps.Ovirt.APIServerInternalIPs, ps.Ovirt.APIServerInternalIP)
+		ingressVIPs = withSingular(ps.Ovirt.IngressIPs, ps.Ovirt.IngressIP)
+	case ps.VSphere != nil:
+		apiVIPs = withSingular(ps.VSphere.APIServerInternalIPs, ps.VSphere.APIServerInternalIP)
+		ingressVIPs = withSingular(ps.VSphere.IngressIPs, ps.VSphere.IngressIP)
+	case ps.Nutanix != nil:
+		apiVIPs = withSingular(ps.Nutanix.APIServerInternalIPs, ps.Nutanix.APIServerInternalIP)
+		ingressVIPs = withSingular(ps.Nutanix.IngressIPs, ps.Nutanix.IngressIP)
+	case ps.Kubevirt != nil:
+		apiVIPs = []string{ps.Kubevirt.APIServerInternalIP}
+		ingressVIPs = []string{ps.Kubevirt.IngressIP}
+	case ps.EquinixMetal != nil:
+		apiVIPs = []string{ps.EquinixMetal.APIServerInternalIP}
+		ingressVIPs = []string{ps.EquinixMetal.IngressIP}
+	}
+	for _, vip := range apiVIPs {
+		// the deprecated singular field repeats the first VIP of the list
+		if vip != "" && len(set[vip]) == 0 {
+			set.insert("infrastructure apiServerInternalIPs", vip)
+		}
+	}
+	for _, vip := range ingressVIPs {
+		if vip != "" && len(set[vip]) == 0 {
+			set.insert("infrastructure ingressIPs", vip)
+		}
+	}
+	return set
+}
+
+func explainNoProxy(proxy *configv1.Proxy, infra *configv1.Infrastructure, network *configv1.Network, cluster *corev1.ConfigMap, fallbackMachineNetworks *MachineNetworks, getEnv func(string) string) (*NoProxyExplanation, error) {
+	set := noProxySet{}
+	warnings := []string{}
+	set.insert("default", "127.0.0.1", "localhost", ".svc", ".cluster.local")
+	if hcpCfg := hypershift.NewHyperShiftConfig(); hcpCfg.Enabled {
+		set.insert("hypershift", ".hypershift.local")
+	}
+
+	// The machine networks come from the install-config, or else from the
+	// source the caller found them in. The VIPs of the platform are only
+	// added when neither has them, as they cover none of the node addresses.
+	machineNetworks, hasInstallConfig, err := installConfigMachineNetworks(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if !hasInstallConfig {
+		machineNetworks, err = fallbackMachineNetworkSet(fallbackMachineNetworks)
+		if err != nil {
+			return nil, err
+		}
+		if len(machineNetworks) == 0 {
+			machineNetworks = infrastructureVIPs(infra)
+		}
+	}
+	for value, sources := range machineNetworks {
+		for _, source := range sources {
+			set.insert(source, value)
 		}
-		set.Insert(mc.CIDR)
 	}
 
 	// Hypershift does in many but not all cases not actually have an internal apiserver address and just
@@ -85,69 +289,66 @@ func mergeUserSystemNoProxy(proxy *configv1.Proxy, infra *configv1.Infrastructur
 		if len(infra.Status.APIServerInternalURL) > 0 {
 			internalAPIServer, err := url.Parse(infra.Status.APIServerInternalURL)
 			if err != nil {
-				return "", fmt.Errorf("failed to parse internal api server internal url")
+				return nil, fmt.Errorf("failed to parse internal api server internal url")
 			}
-			set.Insert(internalAPIServer.Hostname())
+			set.insert("infrastructure apiServerInternalURL", internalAPIServer.Hostname())
 		} else {
-			return "", fmt.Errorf("internal api server url missing from infrastructure config '%s'", infra.Name)
+			return nil, fmt.Errorf("internal api server url missing from infrastructure config '%s'", infra.Name)
 		}
 	}
 
 	if len(network.Status.ServiceNetwork) > 0 {
-		for _, nss := range network.Status.ServiceNetwork {
-			set.Insert(nss)
-		}
+		set.insert("network serviceNetwork", network.Status.ServiceNetwork...)
 	} else {
-		return "", fmt.Errorf("serviceNetwork missing from network '%s' status", network.Name)
+		return nil, fmt.Errorf("serviceNetwork missing from network '%s' status", network.Name)
 	}
 
 	if infra.Status.PlatformStatus != nil {
-		switch infra.Status.PlatformStatus.Type {
-		case configv1.AWSPlatformType, configv1.GCPPlatformType, configv1.AzurePlatformType, configv1.OpenStackPlatformType:
-			set.Insert("169.254.169.254")
-		}
+		platform := infra.Status.PlatformStatus.Type
+		set.insert(fmt.Sprintf("platform %s metadata endpoint", platform), platformMetadataEndpoints[platform]...)
 
 		// Construct the node sub domain.
 		// TODO: Add support for additional cloud providers.
-		switch infra.Status.PlatformStatus.Type {
+		switch platform {
 		case configv1.AWSPlatformType:
 			region := infra.Status.PlatformStatus.AWS.Region
 			if region == "us-east-1" {
-				set.Insert(".ec2.internal")
+				set.insert("platform AWS node domain", ".ec2.internal")
 			} else {
-				set.Insert(fmt.Sprintf(".%s.compute.internal", region))
+				set.insert("platform AWS node domain", fmt.Sprintf(".%s.compute.internal", region))
 			}
 		case configv1.AzurePlatformType:
 			if cloudName := infra.Status.PlatformStatus.Azure.CloudName; cloudName != configv1.AzurePublicCloud {
 				// https://learn.microsoft.com/en-us/azure/virtual-network/what-is-ip-address-168-63-129-16
-				set.Insert("168.63.129.16")
+				set.insert("platform Azure host endpoint", "168.63.129.16")
 				// https://bugzilla.redhat.com/show_bug.cgi?id=2104997
 				if cloudName == configv1.AzureStackCloud {
-					set.Insert(infra.Status.PlatformStatus.Azure.ARMEndpoint)
+					set.insert("platform Azure armEndpoint", infra.Status.PlatformStatus.Azure.ARMEndpoint)
 				}
 			}
-		case configv1.GCPPlatformType:
-			// From https://cloud.google.com/vpc/docs/special-configurations add GCP metadata.
-			// "metadata.google.internal." added due to https://bugzilla.redhat.com/show_bug.cgi?id=1754049
-			set.Insert("metadata", "metadata.google.internal", "metadata.google.internal.")
 		}
 	}
 
 	if len(network.Status.ClusterNetwork) > 0 {
 		for _, clusterNetwork := range network.Status.ClusterNetwork {
-			set.Insert(clusterNetwork.CIDR)
+			set.insert("network clusterNetwork", clusterNetwork.CIDR)
 		}
 	} else {
-		return "", fmt.Errorf("clusterNetwork missing from network `%s` status", network.Name)
+		return nil, fmt.Errorf("clusterNetwork missing from network `%s` status", network.Name)
 	}
 
 	if len(proxy.Spec.NoProxy) > 0 {
 		for _, userValue := range strings.Split(proxy.Spec.NoProxy, ",") {
-			if userValue != "" {
-				set.Insert(userValue)
+			if userValue == "" {
+				continue
+			}
+			if sources, ok := set[userValue]; ok && sources[len(sources)-1] != "proxy noProxy" {
+				warnings = append(warnings, fmt.Sprintf("noProxy entry %q of the proxy is already added from %s",
+					userValue, strings.Join(sources, ", ")))
 			}
+			set.insert("proxy noProxy", userValue)
 		}
 	}
 
-	return strings.Join(set.List(), ","), nil
+	return set.explanation(warnings), nil
 }
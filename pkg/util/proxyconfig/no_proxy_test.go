@@ -1,7 +1,10 @@
 package proxyconfig
 
 import (
+	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
 	configv1 "github.com/openshift/api/config/v1"
@@ -305,3 +308,171 @@ func TestMergeUserSystemNoProxy(t *testing.T) {
 		})
 	}
 }
+
+func TestExplainNoProxy(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	network := netConfig("10.128.0.0/14", []string{"172.30.0.0/16"})
+
+	explanation, err := explainNoProxy(proxyConfigWithNoProxy("10.0.0.0/16,.corp.example.com"),
+		infraConfig(configv1.AWSPlatformType, "test.cluster.com", "us-west-2"),
+		network, cfgMapWithInstallConfig(cfgMapKey, cfgMapData), nil, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string]string{}
+	for _, entry := range explanation.Entries {
+		sources[entry.Value] = strings.Join(entry.Sources, ", ")
+	}
+	want := map[string]string{
+		".cluster.local":              "default",
+		".corp.example.com":           "proxy noProxy",
+		".svc":                        "default",
+		".us-west-2.compute.internal": "platform AWS node domain",
+		"10.0.0.0/16":                 "install-config machineCIDR, proxy noProxy",
+		"10.128.0.0/14":               "network clusterNetwork",
+		"127.0.0.1":                   "default",
+		"169.254.169.254":             "platform AWS metadata endpoint",
+		"172.30.0.0/16":               "network serviceNetwork",
+		"api-int.test.cluster.com":    "infrastructure apiServerInternalURL",
+		"localhost":                   "default",
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("ExplainNoProxy() sources = %v, want %v", sources, want)
+	}
+	wantWarnings := []string{`noProxy entry "10.0.0.0/16" of the proxy is already added from install-config machineCIDR`}
+	if !reflect.DeepEqual(explanation.Warnings, wantWarnings) {
+		t.Errorf("ExplainNoProxy() warnings = %v, want %v", explanation.Warnings, wantWarnings)
+	}
+
+	// Without an install-config, the machine networks come from the source found
+	// by the caller, or else from the VIPs of the platform
+	infra := infraConfig(configv1.BareMetalPlatformType, "test.cluster.com", "")
+	infra.Status.PlatformStatus = &configv1.PlatformStatus{
+		Type: configv1.BareMetalPlatformType,
+		BareMetal: &configv1.BareMetalPlatformStatus{
+			APIServerInternalIP:  "192.168.111.5",
+			APIServerInternalIPs: []string{"192.168.111.5", "fd2e:6f44:5dd8::5"},
+			IngressIP:            "192.168.111.4",
+			IngressIPs:           []string{"192.168.111.4"},
+		},
+	}
+	explanation, err = explainNoProxy(proxyConfig(), infra, network, cfgMap(), nil, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := explanation.NoProxy(), ".cluster.local,.svc,10.128.0.0/14,127.0.0.1,172.30.0.0/16,"+
+		"192.168.111.4,192.168.111.5,api-int.test.cluster.com,fd2e:6f44:5dd8::5,localhost"; got != want {
+		t.Errorf("ExplainNoProxy() got = %v, want %v", got, want)
+	}
+
+	machineNetworks := &MachineNetworks{
+		Source: "node primary interface subnets",
+		CIDRs:  []string{"192.168.111.0/24", "fd2e:6f44:5dd8::/64", "192.168.111.0/24"},
+	}
+	explanation, err = explainNoProxy(proxyConfig(), infra, network, cfgMap(), machineNetworks, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := explanation.NoProxy(), ".cluster.local,.svc,10.128.0.0/14,127.0.0.1,172.30.0.0/16,"+
+		"192.168.111.0/24,api-int.test.cluster.com,fd2e:6f44:5dd8::/64,localhost"; got != want {
+		t.Errorf("ExplainNoProxy() got = %v, want %v", got, want)
+	}
+	for _, entry := range explanation.Entries {
+		if entry.Value == "192.168.111.0/24" && !reflect.DeepEqual(entry.Sources, []string{machineNetworks.Source}) {
+			t.Errorf("ExplainNoProxy() sources of %s = %v, want %v", entry.Value, entry.Sources, []string{machineNetworks.Source})
+		}
+	}
+
+	// The install-config takes precedence over the other sources
+	explanation, err = explainNoProxy(proxyConfig(), infra, network, cfgMapWithInstallConfig(cfgMapKey, cfgMapData), machineNetworks, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(explanation.NoProxy(), "192.168.111.0/24") {
+		t.Errorf("ExplainNoProxy() got = %v, want the machine networks of the install-config", explanation.NoProxy())
+	}
+
+	machineNetworks.CIDRs = []string{"192.168.111.0"}
+	if _, err := explainNoProxy(proxyConfig(), infra, network, cfgMap(), machineNetworks, noEnv); err == nil {
+		t.Errorf("ExplainNoProxy() accepted an invalid machine network")
+	}
+}
+
+func TestAcceptedNoProxy(t *testing.T) {
+	network := netConfig("10.128.0.0/14", []string{"172.30.0.0/16"})
+	infra := infraConfig(configv1.AWSPlatformType, "test.cluster.com", "us-west-2")
+	accept := func(proxy *configv1.Proxy) *NoProxyExplanation {
+		explanation, err := explainNoProxy(proxy, infra, network, cfgMapWithInstallConfig(cfgMapKey, cfgMapData), nil, func(string) string { return "" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		proxy.Status = configv1.ProxyStatus{
+			HTTPProxy:  proxy.Spec.HTTPProxy,
+			HTTPSProxy: proxy.Spec.HTTPSProxy,
+			NoProxy:    explanation.NoProxy(),
+		}
+		return explanation
+	}
+
+	proxy := proxyConfigWithNoProxy("example.com,10.0.0.0/16")
+	explanation := accept(proxy)
+	if noProxy, ok := AcceptedNoProxy(proxy, explanation); !ok || noProxy != proxy.Status.NoProxy {
+		t.Errorf("AcceptedNoProxy() = %q, %v, want %q, true", noProxy, ok, proxy.Status.NoProxy)
+	}
+
+	// A noProxy entry that is removed from the spec is not accepted yet
+	proxy.Spec.NoProxy = "example.com"
+	if _, ok := AcceptedNoProxy(proxy, explanation); ok {
+		t.Errorf("AcceptedNoProxy() accepted a spec with a removed noProxy entry")
+	}
+	explanation = accept(proxy)
+	if _, ok := AcceptedNoProxy(proxy, explanation); !ok {
+		t.Errorf("AcceptedNoProxy() did not accept the spec once merged")
+	}
+
+	// Neither is a new noProxy entry or proxy
+	proxy.Spec.NoProxy = "example.com,example.org"
+	if _, ok := AcceptedNoProxy(proxy, explanation); ok {
+		t.Errorf("AcceptedNoProxy() accepted a spec with an added noProxy entry")
+	}
+	proxy.Spec.NoProxy = "example.com"
+	proxy.Spec.HTTPSProxy = "https://test.secure-proxy.com:5678"
+	if _, ok := AcceptedNoProxy(proxy, explanation); ok {
+		t.Errorf("AcceptedNoProxy() accepted a spec with a new httpsProxy")
+	}
+
+	// The status must be the one the explanation was recorded with
+	explanation = accept(proxy)
+	proxy.Status.NoProxy = "example.com"
+	if _, ok := AcceptedNoProxy(proxy, explanation); ok {
+		t.Errorf("AcceptedNoProxy() accepted a status that differs from the explanation")
+	}
+	if _, ok := AcceptedNoProxy(proxy, nil); ok {
+		t.Errorf("AcceptedNoProxy() accepted a spec without an explanation")
+	}
+}
+
+func TestPlatformMetadataEndpoints(t *testing.T) {
+	for platform, want := range map[configv1.PlatformType]string{
+		configv1.IBMCloudPlatformType:     "169.254.169.254,api.metadata.cloud.ibm.com",
+		configv1.AlibabaCloudPlatformType: "100.100.100.200",
+		configv1.VSpherePlatformType:      "",
+	} {
+		infra := infraConfig(platform, "test.cluster.com", "")
+		infra.Status.PlatformStatus = &configv1.PlatformStatus{Type: platform}
+		explanation, err := explainNoProxy(proxyConfig(), infra, netConfig("10.128.0.0/14", []string{"172.30.0.0/16"}),
+			cfgMapWithInstallConfig(cfgMapKey, cfgMapData), nil, func(string) string { return "" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		endpoints := []string{}
+		for _, entry := range explanation.Entries {
+			if entry.Sources[0] == fmt.Sprintf("platform %s metadata endpoint", platform) {
+				endpoints = append(endpoints, entry.Value)
+			}
+		}
+		if got := strings.Join(endpoints, ","); got != want {
+			t.Errorf("metadata endpoints of %s = %v, want %v", platform, got, want)
+		}
+	}
+}